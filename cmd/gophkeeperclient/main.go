@@ -1,8 +1,12 @@
 package main
 
 import (
+	"crypto/tls"
 	"log"
+	"os"
+	"strings"
 
+	"yudinsv/gophkeeper/internal/gophkeeperclient/cli"
 	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
 	"yudinsv/gophkeeper/internal/gophkeeperclient/window"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
@@ -24,14 +28,22 @@ func version() {
 	log.Printf("Commit Author: %s\n", commitAuthor)
 
 }
+
+// main runs the command given in the args non-interactively, without args it starts the interactive window.
 func main() {
-	version()
-	var client service.Clienter
 	var cfg models.Config
-	client = &service.MyClient{}
-	if err := env.Parse(&cfg); err != nil {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Println(err)
+	}
+	if err = env.Parse(&cfg); err != nil {
 		log.Fatalln("error config read", err)
 	}
+	tlsConfig, err := service.ClientTLSConfig(cfg.TLSCAFile, cfg.TLSClientCertFile, cfg.TLSClientKeyFile)
+	if err != nil {
+		log.Fatalln("error loading the certificates", err)
+	}
+	client := service.NewMyClient(hostname, tlsConfig)
 	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
 	if err != nil {
 		log.Fatalln(err)
@@ -39,11 +51,35 @@ func main() {
 	authorizationer := service.NewAuthorizationer(client, cfg.Address)
 	registrationer := service.NewRegistrationer(client, cfg.Address)
 	syncer := service.NewSyncer(keeperStorage, client, cfg.Address)
+	if cfg.GRPCAddress != "" {
+		grpcTLS := tlsConfig
+		if grpcTLS == nil && strings.HasPrefix(cfg.Address, "https://") {
+			grpcTLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		conn, err := service.DialGRPC(cfg.GRPCAddress, grpcTLS)
+		if err != nil {
+			log.Fatalln("error connecting to the gRPC API", err)
+		}
+		defer conn.Close()
+		syncer = service.NewGRPCSyncer(keeperStorage, client, conn, cfg.Address)
+	}
+	vaulter := service.NewVaulter(client, cfg.Address)
 	serviceClient := service.ClientService{
 		AuthService:     authorizationer,
 		RegistryService: registrationer,
 		SyncService:     syncer,
+		VaultService:    vaulter,
+		SessionService:  service.NewSessioner(client, cfg.Address),
+		BlobService:     service.NewBlober(client, cfg.Address),
 	}
+	if len(os.Args) > 1 {
+		code := cli.New(serviceClient, keeperStorage, client, cfg).Run(os.Args[1:])
+		if err = keeperStorage.Close(); err != nil {
+			log.Println(err)
+		}
+		os.Exit(code)
+	}
+	version()
 	err = serviceClient.AuthService.Ping()
 	if err != nil {
 		log.Fatalln(err)
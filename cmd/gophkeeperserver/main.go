@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"net"
 
+	"yudinsv/gophkeeper/internal/blobstore"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/api/handlers"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/api/server"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/keyset"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
 	"yudinsv/gophkeeper/internal/keeperstorage"
 
 	"github.com/caarlos0/env/v6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -26,13 +32,47 @@ func version() {
 	log.Printf("Commit Author: %s\n", commitAuthor)
 
 }
+
+// startBlobGC collects the payloads of the blob store no longer referenced by the secrets or the file chunks.
+func startBlobGC(cfg models.Config, keeperStorage keeperstorage.KeeperStorage) error {
+	store, err := blobstore.NewBlobStore(cfg)
+	if err != nil || store == nil {
+		return err
+	}
+	// Only the Postgres storages keep their payloads in the store, without them nothing is referenced.
+	var referencers []blobstore.Referencer
+	for _, storage := range []interface{}{keeperStorage, container.GetBlobStorage()} {
+		if referencer, ok := storage.(blobstore.Referencer); ok {
+			referencers = append(referencers, referencer)
+		}
+	}
+	if len(referencers) == 0 {
+		return nil
+	}
+	go blobstore.RunGC(context.Background(), store, cfg.BlobGCInterval, blobstore.GCGrace, referencers...)
+	return nil
+}
+
 func main() {
 	version()
 	var cfg models.Config
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatalln("error config read", err)
 	}
+	flag.BoolVar(&cfg.DevMode, "dev", cfg.DevMode, "allow insecure settings for local development")
+	rotateKey := flag.Bool("rotate-jwt-key", false, "write a new signing key to JWT_KEYS_DIR and exit")
 	flag.Parse()
+	if *rotateKey {
+		id, err := keyset.GenerateEd25519(cfg.JWTKeysDir)
+		if err != nil {
+			log.Fatalln("error generating the signing key", err)
+		}
+		log.Printf("signing key %s generated\n", id)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalln(err)
+	}
 	userStorage, err := userstorage.NewUserStorage(cfg)
 	if err != nil {
 		log.Fatalln(err)
@@ -44,6 +84,42 @@ func main() {
 	if err := container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
 		log.Fatalln("error starting container", err)
 	}
-	r := handlers.Router()
-	server.NewServer(r, cfg.Address)
+	// The HMAC secret has nothing to reload
+	if cfg.JWTKeysDir != "" && cfg.JWTKeysReload > 0 {
+		go container.GetKeySet().Watch(context.Background(), cfg.JWTKeysReload)
+	}
+	if err = startBlobGC(cfg, keeperStorage); err != nil {
+		log.Fatalln("error opening the blob store", err)
+	}
+	r, err := handlers.Router(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalln("error configuring the trusted proxies", err)
+	}
+	if cfg.TLSSelfSigned {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err = server.GenerateSelfSigned(cfg.TLSCertFile, cfg.TLSKeyFile, []string{host, "localhost", "127.0.0.1"}); err != nil {
+			log.Fatalln("error generating the certificate", err)
+		}
+	}
+	var certs *server.CertReloader
+	var grpcOptions []grpc.ServerOption
+	if cfg.TLSCertFile != "" {
+		if certs, err = server.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile); err != nil {
+			log.Fatalln("error loading the certificates", err)
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(certs.TLSConfig())))
+	}
+	if cfg.GRPCAddress != "" {
+		if err = server.StartGRPC(handlers.GRPCServer(grpcOptions...), cfg.GRPCAddress); err != nil {
+			log.Fatalln("error starting the gRPC server", err)
+		}
+	}
+	if certs == nil {
+		server.NewServer(r, cfg.Address)
+		return
+	}
+	server.NewServerTLS(r, cfg.Address, certs)
 }
@@ -0,0 +1,198 @@
+package fsstore
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// tempPrefix marks the files being written, they are not listed.
+const tempPrefix = ".tmp-"
+
+// quarantinePrefix marks the quarantined payloads, a payload "ab/abcd..." is quarantined as "ab/.quarantine-abcd...".
+const quarantinePrefix = ".quarantine-"
+
+// FsStore keeps the payloads in files under a directory, a payload with the hash "abcd..." is in "ab/abcd...".
+// A file is written under a temporary name and renamed, so a payload is never read half written.
+// A payload is quarantined by renaming, so a Put refreshing the payload before the rename only refreshes
+// the quarantined file, and a Put after the rename writes the payload again.
+type FsStore struct {
+	dir string
+}
+
+// New creates the directory if it does not exist.
+func New(dir string) (*FsStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FsStore{dir: dir}, nil
+}
+
+// Put writes the payload, a stored payload only gets a new modification time.
+func (FS *FsStore) Put(_ context.Context, hash string, data []byte) error {
+	path, err := FS.path(hash)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = os.Chtimes(path, now, now); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), tempPrefix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.Remove(temp.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println(err)
+		}
+	}()
+	if _, err = temp.Write(data); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Get reads the payload, a quarantined payload is read from its quarantined file.
+func (FS *FsStore) Get(_ context.Context, hash string) ([]byte, error) {
+	path, err := FS.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = os.ReadFile(quarantinePath(path))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, constants.ErrContentNotFound
+	}
+	return data, err
+}
+
+// Quarantine renames the payload to its quarantined file. The modification time is set first,
+// so the quarantined file is dated by the quarantine.
+func (FS *FsStore) Quarantine(_ context.Context, hash string) error {
+	path, err := FS.path(hash)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = os.Chtimes(path, now, now); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return os.Rename(path, quarantinePath(path))
+}
+
+// Restore renames the quarantined file back, or removes it if the payload was written again.
+func (FS *FsStore) Restore(_ context.Context, hash string) error {
+	path, err := FS.path(hash)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path); err == nil {
+		return FS.remove(quarantinePath(path))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = os.Rename(quarantinePath(path), path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Purge removes the quarantined file.
+func (FS *FsStore) Purge(_ context.Context, hash string) error {
+	path, err := FS.path(hash)
+	if err != nil {
+		return err
+	}
+	return FS.remove(quarantinePath(path))
+}
+
+// List walks the directory.
+func (FS *FsStore) List(ctx context.Context) ([]models.BlobObject, error) {
+	return FS.walk(ctx, "")
+}
+
+// Quarantined walks the directory for the quarantined files.
+func (FS *FsStore) Quarantined(ctx context.Context) ([]models.BlobObject, error) {
+	return FS.walk(ctx, quarantinePrefix)
+}
+
+// walk lists the files named by the prefix and a hash.
+func (FS *FsStore) walk(ctx context.Context, prefix string) ([]models.BlobObject, error) {
+	var objects []models.BlobObject
+	err := filepath.WalkDir(FS.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		hash := strings.TrimPrefix(entry.Name(), prefix)
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || validHash(hash) != nil {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, models.BlobObject{Hash: hash, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// remove removes the file, a missing file is not an error.
+func (FS *FsStore) remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file of the payload. The hash is checked, so it cannot point outside the directory.
+func (FS *FsStore) path(hash string) (string, error) {
+	if err := validHash(hash); err != nil {
+		return "", err
+	}
+	return filepath.Join(FS.dir, hash[:2], hash), nil
+}
+
+// quarantinePath returns the quarantined file of the payload file.
+func quarantinePath(path string) string {
+	return filepath.Join(filepath.Dir(path), quarantinePrefix+filepath.Base(path))
+}
+
+// validHash checks that the hash is a lowercase hex SHA-256 digest.
+func validHash(hash string) error {
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 || strings.ToLower(hash) != hash {
+		return fmt.Errorf("invalid blob hash %q", hash)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package fsstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte("payload"))
+	hash := hex.EncodeToString(sum[:])
+	if err = store.Put(ctx, hash, []byte("payload")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, hash[:2], hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	assert.NoError(t, store.Put(ctx, hash, []byte("payload")), "a stored payload is put again")
+
+	data, err := store.Get(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []byte("payload"), data)
+	objects, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, objects, 1) {
+		assert.Equal(t, hash, objects[0].Hash)
+		assert.Equal(t, int64(len("payload")), objects[0].Size)
+	}
+
+	assert.Error(t, store.Put(ctx, "../../etc/passwd", []byte("payload")), "the hash cannot leave the directory")
+
+	// A quarantined payload is not listed but read, and restored
+	assert.NoError(t, store.Quarantine(ctx, hash))
+	objects, err = store.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+	quarantined, err := store.Quarantined(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, quarantined, 1)
+	data, err = store.Get(ctx, hash)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("payload"), data)
+	assert.NoError(t, store.Restore(ctx, hash))
+	objects, err = store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	// A payload put again after the quarantine is kept when the quarantined file is purged
+	assert.NoError(t, store.Quarantine(ctx, hash))
+	assert.NoError(t, store.Put(ctx, hash, []byte("payload")))
+	assert.NoError(t, store.Purge(ctx, hash))
+	_, err = store.Get(ctx, hash)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Quarantine(ctx, hash))
+	assert.NoError(t, store.Purge(ctx, hash))
+	_, err = store.Get(ctx, hash)
+	assert.ErrorIs(t, err, constants.ErrContentNotFound)
+	assert.NoError(t, store.Quarantine(ctx, hash), "an unknown hash is ignored")
+	assert.NoError(t, store.Purge(ctx, hash))
+}
@@ -0,0 +1,83 @@
+package blobstore
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// GCGrace is the age under which a payload is never collected. A payload is put before the row referring
+// to it is committed, the grace period keeps it until the commit.
+const GCGrace = time.Hour
+
+// Collect deletes the payloads that none of the storages refers to in two phases and returns their number.
+// A payload older than grace without a reference is quarantined. A payload quarantined longer than grace ago
+// is deleted if it is still without a reference, otherwise it is restored: a Put of the payload
+// racing the quarantine belongs to a row committed within grace, and Get reads the payload meanwhile.
+// The payloads are listed before the references are read, so a payload put meanwhile is not seen.
+func Collect(ctx context.Context, store BlobStore, grace time.Duration, referencers ...Referencer) (int, error) {
+	quarantined, err := store.Quarantined(ctx)
+	if err != nil {
+		return 0, err
+	}
+	objects, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, referencer := range referencers {
+		hashes, err := referencer.Referenced(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for hash := range hashes {
+			referenced[hash] = true
+		}
+	}
+	deleted := 0
+	cutoff := time.Now().Add(-grace)
+	for _, object := range quarantined {
+		if referenced[object.Hash] {
+			if err = store.Restore(ctx, object.Hash); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+		if object.Modified.After(cutoff) {
+			continue
+		}
+		if err = store.Purge(ctx, object.Hash); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	for _, object := range objects {
+		if referenced[object.Hash] || object.Modified.After(cutoff) {
+			continue
+		}
+		if err = store.Quarantine(ctx, object.Hash); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// RunGC collects the unreferenced payloads every interval until the context is done.
+func RunGC(ctx context.Context, store BlobStore, interval time.Duration, grace time.Duration, referencers ...Referencer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := Collect(ctx, store, grace, referencers...)
+			if err != nil {
+				log.Println("error collecting the unreferenced blobs", err)
+			}
+			if deleted != 0 {
+				log.Printf("%d unreferenced blobs deleted\n", deleted)
+			}
+		}
+	}
+}
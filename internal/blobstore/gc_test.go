@@ -0,0 +1,148 @@
+package blobstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/blobstore/fsstore"
+	"yudinsv/gophkeeper/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type references map[string]bool
+
+func (r references) Referenced(context.Context) (map[string]bool, error) {
+	return r, nil
+}
+
+func TestOffloadLoad(t *testing.T) {
+	store, err := fsstore.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	inline, hash, err := Offload(ctx, store, 4, []byte("tiny"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", hash)
+	assert.Equal(t, []byte("tiny"), inline)
+
+	inline, hash, err = Offload(ctx, store, 4, []byte("large payload"))
+	assert.NoError(t, err)
+	assert.Equal(t, Hash([]byte("large payload")), hash)
+	assert.Empty(t, inline)
+	data, err := Load(ctx, store, inline, hash)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("large payload"), data)
+
+	inline, hash, err = Offload(ctx, nil, 4, []byte("large payload"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", hash, "without a store the payload stays inline")
+	assert.Equal(t, []byte("large payload"), inline)
+
+	assert.NoError(t, store.Put(ctx, Hash([]byte("original")), []byte("tampered")))
+	_, err = Load(ctx, store, nil, Hash([]byte("original")))
+	assert.ErrorIs(t, err, constants.ErrContentCorrupted)
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	store, err := fsstore.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	old := time.Now().Add(-2 * GCGrace)
+	for _, payload := range []string{"referenced", "versioned", "orphan", "fresh orphan"} {
+		hash := Hash([]byte(payload))
+		if err = store.Put(ctx, hash, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+		if payload != "fresh orphan" {
+			if err = os.Chtimes(filepath.Join(dir, hash[:2], hash), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	referencers := []Referencer{
+		references{Hash([]byte("referenced")): true},
+		references{Hash([]byte("versioned")): true},
+	}
+	deleted, err := Collect(ctx, store, GCGrace, referencers...)
+	assert.NoError(t, err)
+	assert.Zero(t, deleted, "the orphan is only quarantined")
+	quarantined, err := store.Quarantined(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, quarantined, 1) {
+		assert.Equal(t, Hash([]byte("orphan")), quarantined[0].Hash)
+	}
+	_, err = store.Get(ctx, Hash([]byte("orphan")))
+	assert.NoError(t, err, "a quarantined payload is still read")
+
+	// The quarantine is younger than the grace period
+	deleted, err = Collect(ctx, store, GCGrace, referencers...)
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	deleted, err = Collect(ctx, store, 0, referencers...)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = store.Get(ctx, Hash([]byte("orphan")))
+	assert.ErrorIs(t, err, constants.ErrContentNotFound)
+	for _, payload := range []string{"referenced", "versioned", "fresh orphan"} {
+		_, err = store.Get(ctx, Hash([]byte(payload)))
+		assert.NoError(t, err, payload)
+	}
+}
+
+// racingPut puts the payload again right before it is quarantined, as a secret referring to it is written.
+type racingPut struct {
+	BlobStore
+}
+
+func (r racingPut) Quarantine(ctx context.Context, hash string) error {
+	if err := r.Put(ctx, hash, []byte("orphan")); err != nil {
+		return err
+	}
+	return r.BlobStore.Quarantine(ctx, hash)
+}
+
+func TestCollect_PutDuringQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	store, err := fsstore.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	old := time.Now().Add(-2 * GCGrace)
+	hash := Hash([]byte("orphan"))
+	if err = store.Put(ctx, hash, []byte("orphan")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(filepath.Join(dir, hash[:2], hash), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// The Put refreshes the payload after the listing and succeeds, the payload is quarantined anyway
+	deleted, err := Collect(ctx, racingPut{BlobStore: store}, GCGrace, references{})
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+	data, err := store.Get(ctx, hash)
+	assert.NoError(t, err, "the row committed meanwhile can read the payload")
+	assert.Equal(t, []byte("orphan"), data)
+
+	// The row is committed, the next collection after the grace period restores the payload
+	deleted, err = Collect(ctx, store, 0, references{hash: true})
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+	quarantined, err := store.Quarantined(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, quarantined)
+	objects, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	_, err = store.Get(ctx, hash)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,48 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"yudinsv/gophkeeper/internal/constants"
+)
+
+// Offload puts the data into the store if it is larger than threshold bytes and returns its hash,
+// the caller keeps an empty value in its row. Otherwise, or without a store, the data is returned
+// to be kept inline and the hash is empty.
+func Offload(ctx context.Context, store BlobStore, threshold int, data []byte) ([]byte, string, error) {
+	if store == nil || len(data) <= threshold {
+		return data, "", nil
+	}
+	hash := Hash(data)
+	if err := store.Put(ctx, hash, data); err != nil {
+		return nil, "", err
+	}
+	return []byte{}, hash, nil
+}
+
+// Load returns the inline data, or if the hash is set, the payload from the store verified against the hash.
+func Load(ctx context.Context, store BlobStore, inline []byte, hash string) ([]byte, error) {
+	if hash == "" {
+		return inline, nil
+	}
+	if store == nil {
+		return nil, errors.New("the payload is in a blob store, but no blob store is configured")
+	}
+	data, err := store.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if Hash(data) != hash {
+		return nil, constants.ErrContentCorrupted
+	}
+	return data, nil
+}
+
+// Hash returns the name of the payload in the store.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,344 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// emptyPayloadHash is the SHA-256 digest of an empty body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// quarantinePrefix is the prefix of the quarantined objects, an object "abcd..." is quarantined as "quarantine/abcd...".
+const quarantinePrefix = "quarantine/"
+
+// S3Store keeps the payloads as the objects of a bucket of an S3-compatible service, such as MinIO.
+// The objects are addressed path-style, endpoint/bucket/hash, and the requests are signed with AWS Signature V4.
+// S3 cannot rename, an object is quarantined by copying it and deleting the original. A payload uploaded
+// in between is deleted with the original, the quarantined copy keeps serving it until it is restored.
+type S3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    http.Client
+	now       func() time.Time
+}
+
+// New creates the store of the bucket at the endpoint, for example "http://localhost:9000".
+func New(endpoint string, bucket string, region string, accessKey string, secretKey string) (*S3Store, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	return &S3Store{
+		endpoint:  u,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads the payload. An upload replaces the object, so a stored payload gets a new modification time.
+func (SS *S3Store) Put(ctx context.Context, hash string, data []byte) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	resp, err := SS.do(ctx, http.MethodPut, "/"+hash, nil, nil, data)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return responseError("put", resp)
+	}
+	return nil
+}
+
+// Get downloads the payload, a quarantined payload is downloaded from its quarantined copy.
+func (SS *S3Store) Get(ctx context.Context, hash string) ([]byte, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+	data, err := SS.get(ctx, hash)
+	if errors.Is(err, constants.ErrContentNotFound) {
+		return SS.get(ctx, quarantinePrefix+hash)
+	}
+	return data, err
+}
+
+// Quarantine copies the object to its quarantined copy and deletes it, the copy is dated by the quarantine.
+func (SS *S3Store) Quarantine(ctx context.Context, hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	if err := SS.copy(ctx, hash, quarantinePrefix+hash); errors.Is(err, constants.ErrContentNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return SS.delete(ctx, hash)
+}
+
+// Restore copies the quarantined copy back and deletes it.
+func (SS *S3Store) Restore(ctx context.Context, hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	if err := SS.copy(ctx, quarantinePrefix+hash, hash); errors.Is(err, constants.ErrContentNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return SS.delete(ctx, quarantinePrefix+hash)
+}
+
+// Purge deletes the quarantined copy.
+func (SS *S3Store) Purge(ctx context.Context, hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	return SS.delete(ctx, quarantinePrefix+hash)
+}
+
+// get downloads the object.
+func (SS *S3Store) get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := SS.do(ctx, http.MethodGet, "/"+key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, constants.ErrContentNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("get", resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// copy copies the object within the bucket with CopyObject.
+func (SS *S3Store) copy(ctx context.Context, from string, to string) error {
+	header := http.Header{"X-Amz-Copy-Source": {"/" + SS.bucket + "/" + from}}
+	resp, err := SS.do(ctx, http.MethodPut, "/"+to, nil, header, nil)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return constants.ErrContentNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError("copy", resp)
+	}
+	return nil
+}
+
+// delete deletes the object, deleting a missing object succeeds in S3.
+func (SS *S3Store) delete(ctx context.Context, key string) error {
+	resp, err := SS.do(ctx, http.MethodDelete, "/"+key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError("delete", resp)
+	}
+	return nil
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List lists the objects of the bucket page by page. The bucket may be shared,
+// so only the objects named by a hash are listed, see validHash.
+func (SS *S3Store) List(ctx context.Context) ([]models.BlobObject, error) {
+	return SS.list(ctx, "")
+}
+
+// Quarantined lists the quarantined copies.
+func (SS *S3Store) Quarantined(ctx context.Context) ([]models.BlobObject, error) {
+	return SS.list(ctx, quarantinePrefix)
+}
+
+// list lists the objects named by the prefix and a hash.
+func (SS *S3Store) list(ctx context.Context, prefix string) ([]models.BlobObject, error) {
+	var objects []models.BlobObject
+	query := url.Values{"list-type": {"2"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	for {
+		page, err := SS.listPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			hash := strings.TrimPrefix(object.Key, prefix)
+			if !strings.HasPrefix(object.Key, prefix) || validHash(hash) != nil {
+				continue
+			}
+			objects = append(objects, models.BlobObject{Hash: hash, Size: object.Size, Modified: object.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// listPage requests one page of ListObjectsV2.
+func (SS *S3Store) listPage(ctx context.Context, query url.Values) (listBucketResult, error) {
+	resp, err := SS.do(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return listBucketResult{}, err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return listBucketResult{}, responseError("list", resp)
+	}
+	var page listBucketResult
+	if err = xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return listBucketResult{}, err
+	}
+	return page, nil
+}
+
+// validHash checks that the hash is a lowercase hex SHA-256 digest.
+func validHash(hash string) error {
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 || strings.ToLower(hash) != hash {
+		return fmt.Errorf("invalid blob hash %q", hash)
+	}
+	return nil
+}
+
+// do sends the signed request for the key of the bucket, an empty key addresses the bucket itself.
+func (SS *S3Store) do(ctx context.Context, method string, key string, query url.Values, header http.Header, payload []byte) (*http.Response, error) {
+	u := *SS.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + SS.bucket + key
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayloadHash
+	if len(payload) != 0 {
+		sum := sha256.Sum256(payload)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	SS.sign(req, payloadHash, SS.now())
+	return SS.client.Do(req)
+}
+
+// sign sets the AWS Signature V4 Authorization header, the host and all headers of the request are signed.
+func (SS *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + SS.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := hmacSHA256([]byte("AWS4"+SS.secretKey), date)
+	key = hmacSHA256(key, SS.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+SS.accessKey+"/"+scope+
+		",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+// canonicalQuery encodes the query sorted by name with the URI encoding of Signature V4.
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters.
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError reads the error of the service from the response.
+func responseError(operation string, resp *http.Response) error {
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("S3 %s failed %s %s", operation, resp.Status, all)
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,47 @@
+// Package blobstore keeps the large payloads, the secret values and file chunks, outside the database.
+// The rows of the storages keep only the hash and size of a payload stored here.
+package blobstore
+
+import (
+	"context"
+
+	"yudinsv/gophkeeper/internal/blobstore/fsstore"
+	"yudinsv/gophkeeper/internal/blobstore/s3store"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// BlobStore keeps payloads named by their hash, the hex SHA-256 digest of the content,
+// so equal payloads are stored once. Put stores the payload or refreshes the modification time
+// of the stored one, so a payload referenced again is not collected. Get returns constants.ErrContentNotFound
+// for an unknown hash. List returns all stored payloads, other files or objects are skipped.
+//
+// A payload is deleted in two phases, see Collect. Quarantine moves the payload aside: List no longer returns it,
+// Get still reads it and Put stores it again. Quarantined lists the quarantined payloads dated by the quarantine.
+// Restore moves a quarantined payload back, Purge deletes it. The three ignore an unknown hash.
+type BlobStore interface {
+	Put(ctx context.Context, hash string, data []byte) error
+	Get(ctx context.Context, hash string) ([]byte, error)
+	List(ctx context.Context) ([]models.BlobObject, error)
+	Quarantine(ctx context.Context, hash string) error
+	Quarantined(ctx context.Context) ([]models.BlobObject, error)
+	Restore(ctx context.Context, hash string) error
+	Purge(ctx context.Context, hash string) error
+}
+
+// Referencer is a storage keeping its payloads in a BlobStore, Referenced returns the hashes its rows refer to.
+type Referencer interface {
+	Referenced(ctx context.Context) (map[string]bool, error)
+}
+
+// NewBlobStore creates the store configured by BlobStoreDir or S3Endpoint.
+// It returns nil if neither is set, the payloads are kept in the database then.
+func NewBlobStore(cfg servermodels.Config) (BlobStore, error) {
+	if cfg.BlobStoreDir != "" {
+		return fsstore.New(cfg.BlobStoreDir)
+	}
+	if cfg.S3Endpoint != "" {
+		return s3store.New(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+	}
+	return nil, nil
+}
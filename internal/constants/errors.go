@@ -4,3 +4,36 @@ import "errors"
 
 // ErrSecretNotFound secret not found in storage.
 var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrRevisionConflict the secret was changed after the base revision of the update.
+var ErrRevisionConflict = errors.New("revision conflict")
+
+// ErrVersionNotFound the revision is not in the history of the secret.
+var ErrVersionNotFound = errors.New("version not found")
+
+// ErrKDFParamsNotFound vault key derivation params not found in storage.
+var ErrKDFParamsNotFound = errors.New("kdf params not found")
+
+// ErrMFARequired the login has to be completed with the second factor.
+var ErrMFARequired = errors.New("second factor required")
+
+// ErrSessionNotFound session not found in storage.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrBlobNotFound blob of a file secret not found in storage.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrChunkNotFound chunk of a blob not found in storage.
+var ErrChunkNotFound = errors.New("chunk not found")
+
+// ErrBlobIncomplete some chunks of the blob are not uploaded yet.
+var ErrBlobIncomplete = errors.New("blob incomplete")
+
+// ErrBlobComplete the blob is completed and cannot be changed.
+var ErrBlobComplete = errors.New("blob already completed")
+
+// ErrContentNotFound payload not found in the blob store.
+var ErrContentNotFound = errors.New("blob content not found")
+
+// ErrContentCorrupted payload in the blob store does not match its digest.
+var ErrContentCorrupted = errors.New("blob content corrupted")
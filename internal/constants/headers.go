@@ -0,0 +1,9 @@
+package constants
+
+// DeviceNameHeader is the request header with the name of the device the client runs on.
+// The server records it in the session created on login.
+const DeviceNameHeader = "X-Device-Name"
+
+// ChunkHashHeader is the header with the hex SHA-256 digest of the blob chunk in the body.
+// The server rejects a chunk not matching it and sends it back with the chunk, so both sides verify the transfer.
+const ChunkHashHeader = "X-Chunk-SHA256"
@@ -0,0 +1,5 @@
+package constants
+
+// DefaultSecretVersions is the number of the last revisions kept in the history of a secret
+// when the storage is created without a limit.
+const DefaultSecretVersions = 10
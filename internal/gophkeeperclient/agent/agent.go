@@ -0,0 +1,231 @@
+// Package agent keeps the vault of the client unlocked between the short-lived commands.
+// The agent holds the vault key and the tokens of the user after one unlock and serves the seal and open
+// requests of the commands on a Unix socket readable only by the user, so the key never leaves the agent.
+// The client only talks to a socket owned by the user, and both ends check the user of the peer where the system tells it.
+// The agent locks itself after the idle timeout: the key and the tokens are wiped.
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrLocked the agent holds no key.
+var ErrLocked = errors.New("agent is locked")
+
+// The operations of the agent protocol.
+const (
+	opStatus    = "status"
+	opUnlock    = "unlock"
+	opLock      = "lock"
+	opTokens    = "tokens"
+	opSetTokens = "set_tokens"
+	opSeal      = "seal"
+	opOpen      = "open"
+	opSealChunk = "seal_chunk"
+	opOpenChunk = "open_chunk"
+	opMAC       = "mac"
+)
+
+// requestTimeout limits reading a request and writing its response.
+const requestTimeout = time.Minute
+
+// request is a request to the agent, a connection carries one request and its response as JSON.
+type request struct {
+	Op     string               `json:"op"`
+	Login  string               `json:"login,omitempty"`
+	Key    []byte               `json:"key,omitempty"`
+	Tokens *models.Tokens       `json:"tokens,omitempty"`
+	Secret *models.Secret       `json:"secret,omitempty"`
+	Plain  *crypter.PlainSecret `json:"plain,omitempty"`
+	BlobID uuid.UUID            `json:"blob_id,omitempty"`
+	Index  int                  `json:"index,omitempty"`
+	Count  int                  `json:"count,omitempty"`
+	Data   []byte               `json:"data,omitempty"`
+}
+
+// response is the response of the agent, Error is set if the request failed.
+type response struct {
+	Error  string               `json:"error,omitempty"`
+	Locked bool                 `json:"locked,omitempty"`
+	Login  string               `json:"login,omitempty"`
+	Tokens *models.Tokens       `json:"tokens,omitempty"`
+	Secret *models.Secret       `json:"secret,omitempty"`
+	Plain  *crypter.PlainSecret `json:"plain,omitempty"`
+	Data   []byte               `json:"data,omitempty"`
+}
+
+// Agent holds the unlocked vault of one user. Every request using the key or the tokens
+// postpones the lock by the idle timeout, a timeout of zero never locks.
+type Agent struct {
+	mu     sync.Mutex
+	idle   time.Duration
+	login  string
+	key    crypter.Key
+	tokens models.Tokens
+	timer  *time.Timer
+}
+
+// New creates a locked agent.
+func New(idle time.Duration) *Agent {
+	return &Agent{idle: idle}
+}
+
+// Serve accepts the connections of the listener until it is closed, the agent is locked then.
+func (a *Agent) Serve(listener net.Listener) error {
+	defer a.Lock()
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go a.serveConn(conn)
+	}
+}
+
+// serveConn reads the request of the connection and writes the response.
+// Connections of the processes of other users are closed unanswered.
+func (a *Agent) serveConn(conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+	if err := checkPeer(conn); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		log.Println(err)
+		return
+	}
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := json.NewEncoder(conn).Encode(a.handle(req)); err != nil {
+		log.Println(err)
+	}
+}
+
+// handle serves the request.
+func (a *Agent) handle(req request) response {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch req.Op {
+	case opStatus:
+		return response{Locked: a.key == nil, Login: a.login}
+	case opUnlock:
+		if req.Login == "" || len(req.Key) == 0 {
+			return response{Error: "login and key are required"}
+		}
+		a.lock()
+		a.login, a.key = req.Login, req.Key
+		if req.Tokens != nil {
+			a.tokens = *req.Tokens
+		}
+		a.touch()
+		return response{Login: a.login}
+	case opLock:
+		a.lock()
+		return response{Locked: true}
+	}
+	if a.key == nil {
+		return response{Error: ErrLocked.Error(), Locked: true}
+	}
+	a.touch()
+	resp, err := a.keyed(req)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	return resp
+}
+
+// keyed serves the requests of the unlocked agent.
+func (a *Agent) keyed(req request) (response, error) {
+	switch req.Op {
+	case opTokens:
+		tokens := a.tokens
+		return response{Login: a.login, Tokens: &tokens}, nil
+	case opSetTokens:
+		if req.Tokens == nil {
+			return response{}, errors.New("tokens are required")
+		}
+		a.tokens = *req.Tokens
+		return response{}, nil
+	case opSeal:
+		if req.Secret == nil || req.Plain == nil {
+			return response{}, errors.New("secret and plain are required")
+		}
+		secret, err := a.key.Seal(*req.Secret, *req.Plain)
+		return response{Secret: &secret}, err
+	case opOpen:
+		if req.Secret == nil {
+			return response{}, errors.New("secret is required")
+		}
+		plain, err := a.key.Open(*req.Secret)
+		return response{Plain: &plain}, err
+	case opSealChunk:
+		data, err := a.key.SealChunk(req.BlobID, req.Index, req.Count, req.Data)
+		return response{Data: data}, err
+	case opOpenChunk:
+		data, err := a.key.OpenChunk(req.BlobID, req.Index, req.Count, req.Data)
+		return response{Data: data}, err
+	case opMAC:
+		data, err := a.key.MAC(req.Data)
+		return response{Data: data}, err
+	}
+	return response{}, errors.New("unknown operation " + req.Op)
+}
+
+// Lock wipes the key and the tokens.
+func (a *Agent) Lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lock()
+}
+
+// lock wipes the key and the tokens, the caller holds mu.
+func (a *Agent) lock() {
+	for i := range a.key {
+		a.key[i] = 0
+	}
+	a.login, a.key, a.tokens = "", nil, models.Tokens{}
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+}
+
+// touch postpones the lock by the idle timeout, the caller holds mu.
+func (a *Agent) touch() {
+	if a.idle <= 0 {
+		return
+	}
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(a.idle, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		// a timer replaced by a later request does not lock
+		if a.timer == timer {
+			a.lock()
+		}
+	})
+	a.timer = timer
+}
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSocket returns a socket path short enough for the limit of the Unix socket addresses.
+func testSocket(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "gk")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "run", "agent.sock")
+}
+
+func TestAgent(t *testing.T) {
+	path := testSocket(t)
+	listener, err := Listen(path)
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- New(time.Hour).Serve(listener) }()
+	defer func() {
+		require.NoError(t, listener.Close())
+		assert.NoError(t, <-done)
+	}()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	_, err = Listen(path)
+	assert.Error(t, err, "the socket of a running agent is not replaced")
+
+	client := NewClient(path)
+	_, locked, err := client.Status()
+	require.NoError(t, err)
+	assert.True(t, locked)
+	secret := models.Secret{ID: uuid.New(), OwnerID: "user", Ver: time.Now().UTC()}
+	plain := crypter.PlainSecret{Type: "text", Description: "note", Value: []byte("value")}
+	_, err = client.Seal(secret, plain)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	key := make(crypter.Key, 32)
+	key[0] = 1
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+	require.NoError(t, client.Unlock("user", key, tokens))
+	login, locked, err := client.Status()
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.Equal(t, "user", login)
+	got, err := client.Tokens()
+	require.NoError(t, err)
+	assert.Equal(t, tokens, got)
+	tokens.AccessToken = "refreshed"
+	require.NoError(t, client.SetTokens(tokens))
+	got, err = client.Tokens()
+	require.NoError(t, err)
+	assert.Equal(t, tokens, got)
+
+	// The agent seals with the key, the sealed secret opens with the key and back
+	sealed, err := client.Seal(secret, plain)
+	require.NoError(t, err)
+	opened, err := key.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+	sealed, err = key.Seal(secret, plain)
+	require.NoError(t, err)
+	opened, err = client.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+	chunk, err := client.SealChunk(secret.ID, 1, 2, []byte("chunk"))
+	require.NoError(t, err)
+	data, err := key.OpenChunk(secret.ID, 1, 2, chunk)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("chunk"), data)
+	_, err = client.OpenChunk(secret.ID, 0, 2, chunk)
+	assert.Error(t, err, "the chunk is bound to its index")
+	mac, err := client.MAC([]byte("data"))
+	require.NoError(t, err)
+	expected, err := key.MAC([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, mac)
+
+	require.NoError(t, client.Lock())
+	_, locked, err = client.Status()
+	require.NoError(t, err)
+	assert.True(t, locked)
+	_, err = client.Tokens()
+	assert.ErrorIs(t, err, ErrLocked, "the tokens are wiped with the key")
+}
+
+func TestAgent_IdleLock(t *testing.T) {
+	path := testSocket(t)
+	listener, err := Listen(path)
+	require.NoError(t, err)
+	defer listener.Close()
+	go New(200 * time.Millisecond).Serve(listener)
+
+	client := NewClient(path)
+	require.NoError(t, client.Unlock("user", make([]byte, 32), models.Tokens{}))
+	for i := 0; i < 3; i++ {
+		time.Sleep(50 * time.Millisecond)
+		_, err = client.MAC([]byte("keep alive"))
+		require.NoError(t, err, "every request postpones the lock")
+	}
+	assert.Eventually(t, func() bool {
+		_, locked, err := client.Status()
+		return err == nil && locked
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := testSocket(t)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	listener, err := Listen(path)
+	require.NoError(t, err, "a socket left by a stopped agent is replaced")
+	require.NoError(t, listener.Close())
+
+	require.NoError(t, os.Chmod(filepath.Dir(path), 0o755))
+	_, err = Listen(path)
+	assert.Error(t, err, "the directory of the socket must be private")
+}
+
+func TestClient_SocketOwner(t *testing.T) {
+	path := testSocket(t)
+	listener, err := Listen(path)
+	require.NoError(t, err)
+	defer listener.Close()
+	go New(time.Hour).Serve(listener)
+
+	// A link to the socket of the agent is not followed
+	link := filepath.Join(filepath.Dir(path), "link.sock")
+	require.NoError(t, os.Symlink(path, link))
+	_, _, err = NewClient(link).Status()
+	assert.Error(t, err, "the socket must not be a symbolic link")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	if uid, ok, err := peerUID(conn); ok {
+		require.NoError(t, err)
+		assert.Equal(t, os.Getuid(), uid)
+	}
+	assert.NoError(t, checkPeer(conn))
+
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of the socket requires root")
+	}
+	require.NoError(t, os.Chown(path, 65534, 65534))
+	_, _, err = NewClient(path).Status()
+	assert.Error(t, err, "the socket of another user is refused")
+	require.NoError(t, os.Chown(path, 0, 0))
+	require.NoError(t, os.Chown(filepath.Dir(path), 65534, 65534))
+	_, _, err = NewClient(path).Status()
+	assert.Error(t, err, "the directory of another user is refused")
+	_, err = Listen(filepath.Join(filepath.Dir(path), "other.sock"))
+	assert.Error(t, err, "the agent does not listen in the directory of another user")
+}
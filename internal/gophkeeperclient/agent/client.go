@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// dialTimeout limits connecting to the agent.
+const dialTimeout = time.Second
+
+// DefaultSocket returns the socket of the agent of the user: gophkeeper/agent.sock in XDG_RUNTIME_DIR,
+// or in a directory of the user in the temporary directory.
+func DefaultSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gophkeeper", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gophkeeper-%d", os.Getuid()), "agent.sock")
+}
+
+// Listen listens on the socket at the path. The directory of the socket must be owned by the user
+// and accessible only by them, it is created so, and the socket is readable and writable only by the user.
+// A socket left by an agent that is no longer running is replaced, the socket of a running agent is refused.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		if err = conn.Close(); err != nil {
+			log.Println(err)
+		}
+		return nil, fmt.Errorf("an agent is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, 0o600); err != nil {
+		if err := listener.Close(); err != nil {
+			log.Println(err)
+		}
+		return nil, err
+	}
+	return listener, nil
+}
+
+// checkSocketDir checks that the directory of the socket is owned by the user and accessible only by them,
+// so no other user can place a socket of their own in it.
+func checkSocketDir(dir string) error {
+	info, err := checkOwned(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by other users", dir)
+	}
+	return nil
+}
+
+// checkSocket checks the socket of the agent before a request is sent, the key and the tokens
+// are only passed to an agent of the user.
+func checkSocket(path string) error {
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	info, err := checkOwned(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s is not a socket", path)
+	}
+	return nil
+}
+
+// checkOwned checks that the file at the path is not a symbolic link and is owned by the user.
+func checkOwned(path string) (fs.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%s is a symbolic link", path)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return nil, fmt.Errorf("%s is owned by another user", path)
+	}
+	return info, nil
+}
+
+// checkPeer checks that the process on the other end of the connection runs as the user.
+func checkPeer(conn net.Conn) error {
+	uid, ok, err := peerUID(conn)
+	if err != nil {
+		return err
+	}
+	if ok && uid != os.Getuid() {
+		return fmt.Errorf("the peer of the agent socket runs as user %d", uid)
+	}
+	return nil
+}
+
+// Client sends the requests of the commands to the agent listening on the socket.
+// It implements crypter.Keyring with the key held by the agent.
+type Client struct {
+	path string
+}
+
+// NewClient creates a client of the agent listening on the socket at the path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Status returns the user of the unlocked agent, or reports that the agent is locked.
+// It fails if no agent is running.
+func (c *Client) Status() (string, bool, error) {
+	resp, err := c.do(request{Op: opStatus})
+	return resp.Login, resp.Locked, err
+}
+
+// Unlock passes the key and the tokens of the user to the agent.
+func (c *Client) Unlock(login string, key []byte, tokens models.Tokens) error {
+	_, err := c.do(request{Op: opUnlock, Login: login, Key: key, Tokens: &tokens})
+	return err
+}
+
+// Lock wipes the key and the tokens held by the agent.
+func (c *Client) Lock() error {
+	_, err := c.do(request{Op: opLock})
+	return err
+}
+
+// Tokens returns the tokens held by the agent.
+func (c *Client) Tokens() (models.Tokens, error) {
+	resp, err := c.do(request{Op: opTokens})
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	if resp.Tokens == nil {
+		return models.Tokens{}, errors.New("agent returned no tokens")
+	}
+	return *resp.Tokens, nil
+}
+
+// SetTokens replaces the tokens held by the agent, for example after they were refreshed.
+func (c *Client) SetTokens(tokens models.Tokens) error {
+	_, err := c.do(request{Op: opSetTokens, Tokens: &tokens})
+	return err
+}
+
+// Seal seals the secret with the key of the agent.
+func (c *Client) Seal(secret models.Secret, plain crypter.PlainSecret) (models.Secret, error) {
+	resp, err := c.do(request{Op: opSeal, Secret: &secret, Plain: &plain})
+	if err != nil {
+		return models.Secret{}, err
+	}
+	if resp.Secret == nil {
+		return models.Secret{}, errors.New("agent returned no secret")
+	}
+	return *resp.Secret, nil
+}
+
+// Open opens the secret with the key of the agent.
+func (c *Client) Open(secret models.Secret) (crypter.PlainSecret, error) {
+	resp, err := c.do(request{Op: opOpen, Secret: &secret})
+	if err != nil {
+		return crypter.PlainSecret{}, err
+	}
+	if resp.Plain == nil {
+		return crypter.PlainSecret{}, errors.New("agent returned no plain secret")
+	}
+	return *resp.Plain, nil
+}
+
+// SealChunk seals the chunk of a file with the key of the agent.
+func (c *Client) SealChunk(blobID uuid.UUID, index int, count int, data []byte) ([]byte, error) {
+	resp, err := c.do(request{Op: opSealChunk, BlobID: blobID, Index: index, Count: count, Data: data})
+	return resp.Data, err
+}
+
+// OpenChunk opens the chunk of a file with the key of the agent.
+func (c *Client) OpenChunk(blobID uuid.UUID, index int, count int, envelope []byte) ([]byte, error) {
+	resp, err := c.do(request{Op: opOpenChunk, BlobID: blobID, Index: index, Count: count, Data: envelope})
+	return resp.Data, err
+}
+
+// MAC authenticates the data with the key of the agent.
+func (c *Client) MAC(data []byte) ([]byte, error) {
+	resp, err := c.do(request{Op: opMAC, Data: data})
+	return resp.Data, err
+}
+
+// do sends the request on a new connection and reads the response.
+// The socket and the agent must belong to the user, see checkSocket and checkPeer.
+// The refusal of a locked agent is reported as ErrLocked.
+func (c *Client) do(req request) (response, error) {
+	if err := checkSocket(c.path); err != nil {
+		return response{}, err
+	}
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return response{}, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+	if err = checkPeer(conn); err != nil {
+		return response{}, err
+	}
+	if err = conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return response{}, err
+	}
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+	var resp response
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, err
+	}
+	if resp.Error != "" {
+		if resp.Locked {
+			return resp, ErrLocked
+		}
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "io/fs"
+
+// fileOwner reports that the owner of the file is unknown on this system.
+func fileOwner(fs.FileInfo) (int, bool) {
+	return 0, false
+}
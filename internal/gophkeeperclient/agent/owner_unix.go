@@ -0,0 +1,17 @@
+//go:build unix
+
+package agent
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the user owning the file.
+func fileOwner(info fs.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
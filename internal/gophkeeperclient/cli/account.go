@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// login logs the user in, unlocks the vault and stores the session for the following commands.
+// The password is read from the standard input with --password-stdin or asked on the terminal,
+// the code of the second factor is taken from --code or asked on the terminal when the server requires it.
+func (c *CLI) login(args []string) error {
+	fs := c.flags("login")
+	user := fs.String("user", "", "the login of the user")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the standard input")
+	code := fs.String("code", "", "the code of the authenticator app or a recovery code")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	if *user == "" {
+		return usageError("--user is required")
+	}
+	var password string
+	var err error
+	if *passwordStdin {
+		password, err = c.readLine()
+	} else {
+		password, err = c.ReadPassword("Password")
+	}
+	if err != nil {
+		return err
+	}
+	err = c.Service.AuthService.Authorization(models.User{Login: *user, Password: password})
+	if errors.Is(err, constants.ErrMFARequired) {
+		if *code == "" {
+			if *code, err = c.ReadPassword("Code"); err != nil {
+				return err
+			}
+		}
+		err = c.Service.AuthService.VerifyTOTP(*code)
+	}
+	if err != nil {
+		return err
+	}
+	masterPassword, err := c.masterPassword()
+	if err != nil {
+		return err
+	}
+	key, params, err := service.UnlockVault(c.Service.VaultService, masterPassword)
+	if err != nil {
+		return err
+	}
+	if err = c.reencryptLegacy(*user, key); err != nil {
+		return err
+	}
+	tokens := c.Client.Tokens()
+	if err = saveSession(c.SessionFile, session{Login: *user, Tokens: tokens, KDFParams: params}); err != nil {
+		return err
+	}
+	c.unlockAgent(*user, key, tokens)
+	return c.print(map[string]string{"login": *user}, "Logged in as "+*user)
+}
+
+// reencryptLegacy seals the local secrets stored before the vault key with the vault key,
+// the private key they are encrypted with is asked on the terminal.
+func (c *CLI) reencryptLegacy(login string, key []byte) error {
+	ctx := context.Background()
+	legacy, corrupted, err := service.LegacySecrets(ctx, c.Storage, login, key)
+	if err != nil {
+		return err
+	}
+	for _, secret := range corrupted {
+		c.warn("secret %s is corrupted, it cannot be decrypted with the master password", secret.ID)
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	c.warn("%d secrets are encrypted with the private key, they are encrypted again with the master password", len(legacy))
+	privateKey, err := c.ReadPassword("Private key")
+	if err != nil {
+		return err
+	}
+	return service.ReencryptLegacySecrets(ctx, c.Storage, legacy, key, privateKey)
+}
+
+// logout ends the session on the server, locks the agent and deletes the session file.
+// The session file is deleted even if the server cannot be reached.
+func (c *CLI) logout(args []string) error {
+	fs := c.flags("logout")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	s, err := loadSession(c.SessionFile)
+	if err != nil {
+		return err
+	}
+	c.Client.SetTokens(s.Tokens)
+	if err = c.Service.SessionService.Logout(); err != nil {
+		c.warn("ending the session on the server: %s", err)
+	}
+	if c.Agent != nil {
+		if _, _, err = c.Agent.Status(); err == nil {
+			if err = c.Agent.Lock(); err != nil {
+				c.warn("locking the agent: %s", err)
+			}
+		}
+	}
+	if err = os.Remove(c.SessionFile); err != nil {
+		return err
+	}
+	return c.print(map[string]string{"login": s.Login}, "Logged out")
+}
+
+// sync exchanges the changes with the server, unlike the other commands it fails if the server cannot be reached.
+func (c *CLI) sync(args []string) error {
+	fs := c.flags("sync")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	s, _, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	if err = c.Service.SyncService.Ping(); err != nil {
+		return err
+	}
+	if err = c.Service.SyncService.Sync(); err != nil {
+		return err
+	}
+	return c.print(map[string]bool{"synced": true}, "Synced")
+}
+
+// trySync exchanges the changes with the server before and after the commands working with the secrets.
+// If the server cannot be reached the command works with the local storage, set DB_PATH to keep it between the runs.
+func (c *CLI) trySync() {
+	if err := c.Service.SyncService.Ping(); err != nil {
+		c.warn("the server is unreachable, using the local storage: %s", err)
+		return
+	}
+	if err := c.Service.SyncService.Sync(); err != nil {
+		c.warn("sync failed: %s", err)
+	}
+}
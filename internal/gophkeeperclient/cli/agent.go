@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/agent"
+)
+
+// errNoAgent the CLI is used without the agent.
+var errNoAgent = errors.New("no agent socket configured")
+
+// agent runs the agent on AgentSocket until it is interrupted. The agent starts locked,
+// the next command asking for the master password or the unlock command unlocks it.
+func (c *CLI) agent(args []string) error {
+	fs := c.flags("agent")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	listener, err := agent.Listen(c.AgentSocket)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		if err := listener.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+	fmt.Fprintf(c.Stderr, "Agent listening on %s, locks after %s idle\n", c.AgentSocket, c.AgentIdle)
+	return agent.New(c.AgentIdle).Serve(listener)
+}
+
+// unlock asks for the master password and unlocks the running agent, so the following commands ask for nothing.
+func (c *CLI) unlock(args []string) error {
+	fs := c.flags("unlock")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	if c.Agent == nil {
+		return errNoAgent
+	}
+	if _, _, err := c.Agent.Status(); err != nil {
+		return fmt.Errorf("no agent is running on %s, start it with the agent command: %w", c.AgentSocket, err)
+	}
+	s, err := loadSession(c.SessionFile)
+	if err != nil {
+		return err
+	}
+	if _, err = c.unlockVault(s); err != nil {
+		return err
+	}
+	if !c.agentUnlocked {
+		return fmt.Errorf("the agent on %s was not unlocked", c.AgentSocket)
+	}
+	return c.print(map[string]interface{}{"login": s.Login, "locked": false}, "Agent unlocked for "+s.Login)
+}
+
+// lock wipes the key and the tokens held by the agent.
+func (c *CLI) lock(args []string) error {
+	fs := c.flags("lock")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	if c.Agent == nil {
+		return errNoAgent
+	}
+	if err := c.Agent.Lock(); err != nil {
+		return err
+	}
+	return c.print(map[string]bool{"locked": true}, "Agent locked")
+}
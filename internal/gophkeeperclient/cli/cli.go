@@ -0,0 +1,241 @@
+// Package cli runs the client commands non-interactively, so the keeper can be scripted from a shell or CI.
+// Every command is a subcommand of the client with its own flags, --json switches the output to JSON.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/agent"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+
+	"golang.org/x/term"
+)
+
+// command is a subcommand of the client.
+type command struct {
+	usage string
+	run   func(c *CLI, args []string) error
+}
+
+// commands are the subcommands by name.
+var commands = map[string]command{
+	"login":  {usage: "login --user NAME [--password-stdin] [--code CODE]", run: (*CLI).login},
+	"logout": {usage: "logout", run: (*CLI).logout},
+	"add":    {usage: "add card|login|text|binary|file [flags]", run: (*CLI).add},
+	"list":   {usage: "list [--type TYPE]", run: (*CLI).list},
+	"get":    {usage: "get <id|name> [--field FIELD] [--out PATH]", run: (*CLI).get},
+	"edit":   {usage: "edit <id|name> [flags]", run: (*CLI).edit},
+	"rm":     {usage: "rm <id|name>", run: (*CLI).rm},
+	"sync":   {usage: "sync", run: (*CLI).sync},
+	"agent":  {usage: "agent", run: (*CLI).agent},
+	"unlock": {usage: "unlock", run: (*CLI).unlock},
+	"lock":   {usage: "lock", run: (*CLI).lock},
+	"run":    {usage: "run --env VAR=<ref> [--env ...] [--] command [args]", run: (*CLI).runCommand},
+	"inject": {usage: "inject [--in TEMPLATE] [--out PATH]", run: (*CLI).inject},
+	"import": {usage: "import [--format keepass|bitwarden|csv] [--dry-run] [--password-stdin] PATH", run: (*CLI).importSecrets},
+}
+
+// CLI runs the commands of the client with the services and the local storage of the interactive window.
+// The session of the logged-in user is kept in SessionFile between the commands.
+// The master password is taken from MasterPassword or asked by ReadPassword, which needs a terminal.
+// While the agent listening on AgentSocket is unlocked for the user, the commands use its key and tokens
+// and ask for nothing, an agent that is locked is unlocked by the next command asking for the master password.
+type CLI struct {
+	Service        service.ClientService
+	Storage        keeperstorage.KeeperStorage
+	Client         service.Clienter
+	SessionFile    string
+	MasterPassword string
+	Agent          *agent.Client
+	AgentSocket    string
+	AgentIdle      time.Duration
+	Stdin          io.Reader
+	Stdout         io.Writer
+	Stderr         io.Writer
+	ReadPassword   func(label string) (string, error)
+	json           bool
+	agentUnlocked  bool
+}
+
+// New creates a CLI reading the standard input and writing the standard output.
+func New(serviceClient service.ClientService, storage keeperstorage.KeeperStorage, client service.Clienter, cfg servermodels.Config) *CLI {
+	sessionFile := cfg.SessionFile
+	if sessionFile == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			sessionFile = filepath.Join(dir, "gophkeeper", "session.json")
+		}
+	}
+	agentSocket := cfg.AgentSocket
+	if agentSocket == "" {
+		agentSocket = agent.DefaultSocket()
+	}
+	return &CLI{
+		Service:        serviceClient,
+		Storage:        storage,
+		Client:         client,
+		SessionFile:    sessionFile,
+		MasterPassword: cfg.MasterPassword,
+		Agent:          agent.NewClient(agentSocket),
+		AgentSocket:    agentSocket,
+		AgentIdle:      cfg.AgentIdleTimeout,
+		Stdin:          os.Stdin,
+		Stdout:         os.Stdout,
+		Stderr:         os.Stderr,
+		ReadPassword:   readTerminalPassword,
+	}
+}
+
+// Run runs the command of the args and returns the exit code: 0 on success, 1 if the command failed
+// and 2 for invalid usage, the run command returns the exit code of its child.
+// A --json flag before the command applies to it as well.
+func (c *CLI) Run(args []string) int {
+	for len(args) != 0 && (args[0] == "--json" || args[0] == "-json") {
+		c.json = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.Stderr, "unknown command %q\n", args[0])
+		c.usage()
+		return 2
+	}
+	err := cmd.run(c, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	var exitErr exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintln(c.Stderr, "error:", err)
+		fmt.Fprintln(c.Stderr, "usage: gophkeeperclient", cmd.usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(c.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// usage prints the commands.
+func (c *CLI) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(c.Stderr, "usage: gophkeeperclient [--json] <command> [flags]")
+	fmt.Fprintln(c.Stderr, "Without a command the interactive window is started. Commands:")
+	for _, name := range names {
+		fmt.Fprintln(c.Stderr, "  "+commands[name].usage)
+	}
+	fmt.Fprintln(c.Stderr, "A <ref> is <id|name>[#field] or gk://<vault>/<secret-name>[/field], the vault is the login.")
+}
+
+// usageError is an invalid invocation of a command.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// flags creates the flag set of the command with the --json flag.
+func (c *CLI) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.BoolVar(&c.json, "json", c.json, "print the output as JSON")
+	return fs
+}
+
+// parse parses the flags placed anywhere among the args and returns the positional args.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		if i := len(args) - fs.NArg() - 1; i >= 0 && args[i] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// print writes the value as indented JSON in the JSON mode and the text otherwise.
+func (c *CLI) print(value interface{}, text string) error {
+	if !c.json {
+		_, err := fmt.Fprintln(c.Stdout, text)
+		return err
+	}
+	encoder := json.NewEncoder(c.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// warn reports a problem the command has worked around.
+func (c *CLI) warn(format string, args ...interface{}) {
+	fmt.Fprintf(c.Stderr, "warning: "+format+"\n", args...)
+}
+
+// readLine reads the first line of the standard input, for the --password-stdin flags.
+func (c *CLI) readLine() (string, error) {
+	line, err := readAll(c.Stdin)
+	if err != nil {
+		return "", err
+	}
+	first, _, _ := strings.Cut(string(line), "\n")
+	return strings.TrimSuffix(first, "\r"), nil
+}
+
+// readAll reads the standard input to the end.
+func readAll(r io.Reader) ([]byte, error) {
+	if r == nil {
+		return nil, errors.New("no standard input")
+	}
+	return io.ReadAll(r)
+}
+
+// masterPassword returns the master password of the vault.
+func (c *CLI) masterPassword() (string, error) {
+	if c.MasterPassword != "" {
+		return c.MasterPassword, nil
+	}
+	return c.ReadPassword("Master password")
+}
+
+// readTerminalPassword asks for the hidden input on the terminal of the standard input.
+func readTerminalPassword(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%s required: no terminal to ask for it", strings.ToLower(label))
+	}
+	fmt.Fprint(os.Stderr, label+": ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
@@ -0,0 +1,381 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/agent"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
+	keepermemstorage "yudinsv/gophkeeper/internal/keeperstorage/memstorage"
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+	mock "yudinsv/gophkeeper/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCLI is a CLI with the mocked services and an in-memory local storage.
+type testCLI struct {
+	*CLI
+	auth     *mock.MockAuthorizationer
+	vault    *mock.MockVaulter
+	syncer   *mock.MockSyncer
+	client   *mock.MockClienter
+	sessions *mock.MockSessioner
+	stdout   *bytes.Buffer
+	stderr   *bytes.Buffer
+}
+
+func newTestCLI(t *testing.T) *testCLI {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	tc := &testCLI{
+		auth:     mock.NewMockAuthorizationer(ctrl),
+		vault:    mock.NewMockVaulter(ctrl),
+		syncer:   mock.NewMockSyncer(ctrl),
+		client:   mock.NewMockClienter(ctrl),
+		sessions: mock.NewMockSessioner(ctrl),
+		stdout:   &bytes.Buffer{},
+		stderr:   &bytes.Buffer{},
+	}
+	tc.CLI = &CLI{
+		Service: service.ClientService{
+			AuthService:    tc.auth,
+			SyncService:    tc.syncer,
+			VaultService:   tc.vault,
+			SessionService: tc.sessions,
+		},
+		Storage:        keepermemstorage.NewMemoryStorage(10),
+		Client:         tc.client,
+		SessionFile:    filepath.Join(t.TempDir(), "gophkeeper", "session.json"),
+		MasterPassword: "master",
+		Stdout:         tc.stdout,
+		Stderr:         tc.stderr,
+		ReadPassword: func(label string) (string, error) {
+			return "", errors.New("no terminal")
+		},
+	}
+	return tc
+}
+
+// run runs the command with the input and returns its exit code and output, the previous output is discarded.
+func (tc *testCLI) run(stdin string, args ...string) (int, string) {
+	tc.stdout.Reset()
+	tc.stderr.Reset()
+	tc.json = false
+	tc.Stdin = strings.NewReader(stdin)
+	return tc.Run(args), tc.stdout.String()
+}
+
+// testKDFParams returns the cheapest valid params for the master password.
+func testKDFParams(masterPassword string) models.KDFParams {
+	params := models.KDFParams{Salt: bytes.Repeat([]byte{1}, 16), Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: 32}
+	utils.SetKeyVerifier(&params, utils.DeriveKey(masterPassword, params))
+	return params
+}
+
+// login logs the user in with the server reachable and the syncs succeeding.
+func (tc *testCLI) login(t *testing.T) {
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+	tc.auth.EXPECT().Authorization(models.User{Login: "user", Password: "password"}).Return(nil)
+	tc.vault.EXPECT().GetKDFParams().Return(testKDFParams("master"), nil)
+	tc.client.EXPECT().Tokens().Return(tokens).AnyTimes()
+	tc.client.EXPECT().SetTokens(tokens).AnyTimes()
+	tc.syncer.EXPECT().SetClientID("user").AnyTimes()
+	tc.syncer.EXPECT().Ping().Return(nil).AnyTimes()
+	tc.syncer.EXPECT().Sync().Return(nil).AnyTimes()
+
+	code, out := tc.run("password\n", "login", "--user", "user", "--password-stdin")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "Logged in as user\n", out)
+}
+
+func TestCLI_Login(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+
+	info, err := os.Stat(tc.SessionFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the session holds the refresh token")
+	s, err := loadSession(tc.SessionFile)
+	require.NoError(t, err)
+	assert.Equal(t, "user", s.Login)
+	assert.Equal(t, "refresh", s.Tokens.RefreshToken)
+
+	tc.MasterPassword = "wrong"
+	code, _ := tc.run("", "list")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), "invalid master password")
+}
+
+func TestCLI_LoginSecondFactor(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.auth.EXPECT().Authorization(gomock.Any()).Return(constants.ErrMFARequired)
+	tc.auth.EXPECT().VerifyTOTP("123456").Return(nil)
+	tc.vault.EXPECT().GetKDFParams().Return(testKDFParams("master"), nil)
+	tc.client.EXPECT().Tokens().Return(models.Tokens{RefreshToken: "refresh"})
+
+	code, out := tc.run("password", "--json", "login", "--user", "user", "--password-stdin", "--code", "123456")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.JSONEq(t, `{"login":"user"}`, out)
+}
+
+func TestCLI_NotLoggedIn(t *testing.T) {
+	tc := newTestCLI(t)
+	code, _ := tc.run("", "list")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), errNotLoggedIn.Error())
+
+	code, _ = tc.run("", "unknown")
+	assert.Equal(t, 2, code)
+	code, _ = tc.run("", "login")
+	assert.Equal(t, 2, code, "--user is required")
+}
+
+func TestCLI_Secrets(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+
+	code, id := tc.run("", "add", "login", "--description", "mail", "--login", "me", "--password", "pa55")
+	require.Equal(t, 0, code, tc.stderr.String())
+	id = strings.TrimSpace(id)
+	code, _ = tc.run("multi\nline", "add", "text", "--description", "note")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, _ = tc.run("", "add", "card", "--json", "--description", "card", "--number", "4111", "--cvv", "123")
+	require.Equal(t, 0, code, tc.stderr.String())
+
+	code, out := tc.run("", "list")
+	require.Equal(t, 0, code, tc.stderr.String())
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, id+"\tlogin_password\tmail", lines[1], "sorted by description")
+
+	code, out = tc.run("", "list", "--type", "login", "--json")
+	require.Equal(t, 0, code, tc.stderr.String())
+	var views []secretView
+	require.NoError(t, json.Unmarshal([]byte(out), &views))
+	require.Len(t, views, 1)
+	assert.Equal(t, "mail", views[0].Description)
+	assert.Nil(t, views[0].Value, "list does not show the values")
+
+	code, out = tc.run("", "get", "mail", "--field", "password")
+	assert.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "pa55\n", out)
+	code, out = tc.run("", "get", "note")
+	assert.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "multi\nline\n", out)
+	code, out = tc.run("", "--json", "get", id)
+	require.Equal(t, 0, code, tc.stderr.String())
+	var view secretView
+	require.NoError(t, json.Unmarshal([]byte(out), &view))
+	assert.JSONEq(t, `{"login":"me","password":"pa55"}`, string(view.Value))
+
+	code, _ = tc.run("new", "edit", "mail", "--password-stdin", "--description", "email")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, out = tc.run("", "get", "email", "--json")
+	require.Equal(t, 0, code, tc.stderr.String())
+	require.NoError(t, json.Unmarshal([]byte(out), &view))
+	assert.JSONEq(t, `{"login":"me","password":"new"}`, string(view.Value), "the other fields are kept")
+	code, _ = tc.run("", "edit", "email", "--number", "1")
+	assert.Equal(t, 2, code, "a card flag does not apply to a login")
+	code, _ = tc.run("", "edit", "note")
+	assert.Equal(t, 2, code, "nothing to change")
+
+	code, _ = tc.run("", "add", "text", "--text", "other", "--description", "note")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, _ = tc.run("", "get", "note")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), "use the ID")
+
+	code, _ = tc.run("", "rm", id)
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, _ = tc.run("", "get", id)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), constants.ErrSecretNotFound.Error())
+}
+
+// TestHelperProcess is the command started by the run tests, it is not a real test.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Println("user " + os.Getenv("DB_USER") + " password " + os.Getenv("DB_PASS"))
+	fmt.Fprintln(os.Stderr, "failed with "+os.Getenv("DB_PASS"))
+	os.Exit(3)
+}
+
+func TestCLI_Run(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+	code, _ := tc.run("", "add", "login", "--description", "db", "--login", "admin", "--password", "s3cret")
+	require.Equal(t, 0, code, tc.stderr.String())
+
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	code, out := tc.run("", "run", "--env", "DB_PASS=db", "--env", "DB_USER=gk://user/db/login", "--",
+		os.Args[0], "-test.run=TestHelperProcess")
+	assert.Equal(t, 3, code, "the exit code of the command is passed through")
+	assert.Equal(t, "user ***** password *****\n", out, "the values are masked")
+	assert.Equal(t, "failed with *****\n", tc.stderr.String())
+
+	code, _ = tc.run("", "run", "--env", "DB_PASS=missing", "--", os.Args[0])
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), "DB_PASS")
+	code, _ = tc.run("", "run", "--env", "DB_PASS=db")
+	assert.Equal(t, 2, code, "the command is required")
+}
+
+func TestCLI_Inject(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+	code, _ := tc.run("", "add", "login", "--description", "prod/db", "--login", "admin", "--password", "s3cret")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, _ = tc.run("", "add", "card", "--description", "card", "--number", "4111", "--cvv", "123")
+	require.Equal(t, 0, code, tc.stderr.String())
+
+	template := "user={{ gk://user/prod%2Fdb/login }}\npassword={{gk://user/prod%2Fdb/.password}}\ncard={{ gk://user/card }}\nkept={{ name }}\n"
+	code, out := tc.run(template, "inject")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "user=admin\npassword=s3cret\ncard=4111\nkept={{ name }}\n", out)
+
+	dir := t.TempDir()
+	in, path := filepath.Join(dir, "config.tmpl"), filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(in, []byte(template), 0o644))
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+	code, _ = tc.run("", "inject", "--in", in, "--out", path)
+	require.Equal(t, 0, code, tc.stderr.String())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "user=admin\npassword=s3cret\ncard=4111\nkept={{ name }}\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the rendered file holds the secrets")
+
+	code, _ = tc.run("a\n{{ gk://other/card }}", "inject", "--out", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, tc.stderr.String(), "line 2")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "s3cret", "the file is kept when a reference fails")
+}
+
+func TestCLI_Import(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+	code, _ := tc.run("", "add", "login", "--description", "github.com", "--login", "octocat", "--password", "s3cret")
+	require.Equal(t, 0, code, tc.stderr.String())
+
+	path := filepath.Join(t.TempDir(), "passwords.csv")
+	require.NoError(t, os.WriteFile(path, []byte("name,url,username,password,note\n"+
+		"github.com,https://github.com,octocat,s3cret,\n"+
+		"bank,https://bank.example,jane,pass,call support\n"+
+		"bank,https://bank.example,jane,pass,call support\n"), 0o600))
+
+	code, out := tc.run("", "import", "--dry-run", path)
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "Dry run, would import 1 secrets: 1 login_password\n"+
+		"Duplicates, not imported:\n"+
+		"  login_password \"github.com\": already in the vault\n"+
+		"  login_password \"bank\": repeated in the export\n"+
+		"Unsupported fields, not imported:\n"+
+		"  login_password \"bank\": url, note\n", out)
+	code, out = tc.run("", "list")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.NotContains(t, out, "bank", "the dry run stores nothing")
+
+	code, out = tc.run("", "--json", "import", path)
+	require.Equal(t, 0, code, tc.stderr.String())
+	var report importReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.False(t, report.DryRun)
+	assert.Equal(t, map[string]int{constatns.TypeLoginPassword: 1}, report.Imported)
+	assert.Len(t, report.Duplicates, 2)
+	code, out = tc.run("", "get", "bank", "--field", "password")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "pass\n", out)
+
+	code, out = tc.run("", "import", path)
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Contains(t, out, "Imported 0 secrets", "the imported entries are duplicates now")
+
+	code, _ = tc.run("", "import", filepath.Join(t.TempDir(), "export.txt"))
+	assert.Equal(t, 2, code, "the format is unknown")
+}
+
+func TestCLI_Offline(t *testing.T) {
+	tc := newTestCLI(t)
+	require.NoError(t, saveSession(tc.SessionFile, session{Login: "user", KDFParams: testKDFParams("master")}))
+	tc.client.EXPECT().SetTokens(gomock.Any()).AnyTimes()
+	tc.client.EXPECT().Tokens().Return(models.Tokens{}).AnyTimes()
+	tc.syncer.EXPECT().SetClientID("user").AnyTimes()
+	tc.syncer.EXPECT().Ping().Return(errors.New("connection refused")).AnyTimes()
+
+	code, _ := tc.run("offline", "add", "text")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Contains(t, tc.stderr.String(), "the server is unreachable")
+	code, out := tc.run("", "get", "--field", "value", "--", "")
+	assert.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "offline\n", out)
+
+	code, _ = tc.run("", "sync")
+	assert.Equal(t, 1, code, "sync fails without the server")
+}
+
+func TestCLI_Logout(t *testing.T) {
+	tc := newTestCLI(t)
+	tc.login(t)
+	tc.sessions.EXPECT().Logout().Return(nil)
+
+	code, _ := tc.run("", "logout")
+	require.Equal(t, 0, code, tc.stderr.String())
+	_, err := os.Stat(tc.SessionFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCLI_Agent(t *testing.T) {
+	tc := newTestCLI(t)
+	dir, err := os.MkdirTemp("", "gk")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tc.AgentSocket = filepath.Join(dir, "agent.sock")
+	tc.Agent = agent.NewClient(tc.AgentSocket)
+	code, _ := tc.run("", "unlock")
+	assert.Equal(t, 1, code, "no agent is running")
+
+	listener, err := agent.Listen(tc.AgentSocket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go agent.New(time.Hour).Serve(listener)
+	tc.login(t)
+
+	// The login unlocked the agent, the commands ask for no master password
+	tc.MasterPassword = ""
+	code, _ = tc.run("", "add", "text", "--text", "via agent", "--description", "note")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, out := tc.run("", "get", "note")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "via agent\n", out)
+
+	code, _ = tc.run("", "lock")
+	require.Equal(t, 0, code, tc.stderr.String())
+	code, _ = tc.run("", "get", "note")
+	assert.Equal(t, 1, code, "the locked agent asks for the master password again")
+
+	tc.MasterPassword = "master"
+	code, _ = tc.run("", "unlock")
+	require.Equal(t, 0, code, tc.stderr.String())
+	tc.MasterPassword = ""
+	code, out = tc.run("", "get", "note", "--field", "description")
+	require.Equal(t, 0, code, tc.stderr.String())
+	assert.Equal(t, "note\n", out)
+}
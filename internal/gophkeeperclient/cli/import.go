@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/importer"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// importReport is the output of the import: what is imported and what is not.
+type importReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Imported map[string]int `json:"imported"`
+	// Duplicates are the entries already in the vault or earlier in the export, with the same type, description and value.
+	Duplicates []importItem `json:"duplicates"`
+	// Unsupported are the imported entries with fields no secret type holds.
+	Unsupported []importItem `json:"unsupported"`
+	// Skipped are the entries of a kind no secret type holds.
+	Skipped []importItem `json:"skipped"`
+}
+
+type importItem struct {
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description"`
+	Fields      []string `json:"fields,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+// importSecrets reads the export of another password manager and stores its entries as secrets,
+// which are synced like the secrets added by hand. --dry-run only reports what would be imported.
+// The format is taken from the extension of the file unless --format is set.
+func (c *CLI) importSecrets(args []string) error {
+	fs := c.flags("import")
+	format := fs.String("format", "", "the format of the export: keepass, bitwarden or csv, by the extension if not set")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without storing anything")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password of the KeePass database from the standard input")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("import takes the path of the export")
+	}
+	path := positional[0]
+	if *format == "" {
+		if *format, err = importer.FormatOf(path); err != nil {
+			return usageError(err.Error())
+		}
+	}
+	switch *format {
+	case importer.FormatKeePass, importer.FormatBitwarden, importer.FormatCSV:
+	default:
+		return usageError(fmt.Sprintf("unknown format %q", *format))
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	imported, err := c.readExport(*format, path, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	c.trySync()
+	ctx := context.Background()
+	existing, err := c.secrets(ctx, s.Login, keyring)
+	if err != nil {
+		return err
+	}
+
+	report := importReport{DryRun: *dryRun, Imported: make(map[string]int)}
+	seen := make(map[string]string)
+	for _, e := range existing {
+		seen[importKey(e.plain.Type, e.plain.Description, e.plain.Value)] = "already in the vault"
+	}
+	var secrets []importer.Entry
+	for _, e := range imported {
+		if e.Type == "" {
+			report.Skipped = append(report.Skipped, importItem{Description: e.Description, Reason: strings.Join(e.Unsupported, ", ")})
+			continue
+		}
+		key := importKey(e.Type, e.Description, e.Value)
+		if reason, ok := seen[key]; ok {
+			report.Duplicates = append(report.Duplicates, importItem{Type: e.Type, Description: e.Description, Reason: reason})
+			continue
+		}
+		seen[key] = "repeated in the export"
+		if len(e.Unsupported) != 0 {
+			report.Unsupported = append(report.Unsupported, importItem{Type: e.Type, Description: e.Description, Fields: e.Unsupported})
+		}
+		report.Imported[e.Type]++
+		secrets = append(secrets, e)
+	}
+	if !*dryRun && len(secrets) != 0 {
+		for _, e := range secrets {
+			secret := models.Secret{ID: uuid.New(), OwnerID: s.Login, Ver: time.Now()}
+			sealed, err := keyring.Seal(secret, crypter.PlainSecret{Type: e.Type, Description: e.Description, Value: e.Value})
+			if err != nil {
+				return err
+			}
+			if err = c.Storage.PutSecret(ctx, sealed); err != nil {
+				return err
+			}
+		}
+		c.trySync()
+	}
+	return c.print(report, report.text(len(secrets)))
+}
+
+// readExport reads the entries of the export, a KeePass database is unlocked with the password asked on the terminal
+// or read from the standard input.
+func (c *CLI) readExport(format, path string, passwordStdin bool) ([]importer.Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch format {
+	case importer.FormatKeePass:
+		var password string
+		if passwordStdin {
+			password, err = c.readLine()
+		} else {
+			password, err = c.ReadPassword("KeePass password")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return importer.ReadKeePass(file, password)
+	case importer.FormatBitwarden:
+		return importer.ReadBitwarden(file)
+	}
+	return importer.ReadBrowserCSV(file)
+}
+
+// importKey identifies a secret for finding the duplicates.
+func importKey(secretType, description string, value []byte) string {
+	return secretType + "\x00" + description + "\x00" + string(value)
+}
+
+// text returns the report for the terminal.
+func (r importReport) text(total int) string {
+	var text strings.Builder
+	verb := "Imported"
+	if r.DryRun {
+		verb = "Dry run, would import"
+	}
+	counts := make([]string, 0, len(r.Imported))
+	for secretType, n := range r.Imported {
+		counts = append(counts, fmt.Sprintf("%d %s", n, secretType))
+	}
+	sort.Strings(counts)
+	fmt.Fprintf(&text, "%s %d secrets", verb, total)
+	if len(counts) != 0 {
+		text.WriteString(": " + strings.Join(counts, ", "))
+	}
+	if len(r.Duplicates) != 0 {
+		text.WriteString("\nDuplicates, not imported:")
+		for _, item := range r.Duplicates {
+			fmt.Fprintf(&text, "\n  %s %q: %s", item.Type, item.Description, item.Reason)
+		}
+	}
+	if len(r.Unsupported) != 0 {
+		text.WriteString("\nUnsupported fields, not imported:")
+		for _, item := range r.Unsupported {
+			fmt.Fprintf(&text, "\n  %s %q: %s", item.Type, item.Description, strings.Join(item.Fields, ", "))
+		}
+	}
+	if len(r.Skipped) != 0 {
+		text.WriteString("\nUnsupported entries, not imported:")
+		for _, item := range r.Skipped {
+			fmt.Fprintf(&text, "\n  %q: %s", item.Description, item.Reason)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// templateRef matches a reference in a template: {{ gk://<vault>/<secret-name>/<field> }}.
+var templateRef = regexp.MustCompile(`\{\{\s*(` + regexp.QuoteMeta(refScheme) + `[^\s{}]*)\s*\}\}`)
+
+// inject renders the template with the references replaced by the values of the secrets.
+// The template is read from --in or the standard input. The output goes to --out, a file readable only by the user,
+// or to the standard output. Nothing is written if a reference cannot be resolved.
+func (c *CLI) inject(args []string) error {
+	fs := c.flags("inject")
+	in := fs.String("in", "", "the template, the standard input by default")
+	out := fs.String("out", "", "the file to write readable only by the user, the standard output by default")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	var template []byte
+	var err error
+	if *in != "" {
+		template, err = os.ReadFile(*in)
+	} else {
+		template, err = readAll(c.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	entries, err := c.secrets(context.Background(), s.Login, keyring)
+	if err != nil {
+		return err
+	}
+
+	var rendered bytes.Buffer
+	last := 0
+	matches := templateRef.FindAllSubmatchIndex(template, -1)
+	for _, m := range matches {
+		value, err := resolveRef(entries, s.Login, string(template[m[2]:m[3]]))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", bytes.Count(template[:m[0]], []byte("\n"))+1, err)
+		}
+		rendered.Write(template[last:m[0]])
+		rendered.WriteString(value)
+		last = m[1]
+	}
+	rendered.Write(template[last:])
+
+	if *out == "" {
+		_, err = c.Stdout.Write(rendered.Bytes())
+		return err
+	}
+	if err = writePrivate(*out, rendered.Bytes()); err != nil {
+		return err
+	}
+	return c.print(map[string]interface{}{"path": *out, "references": len(matches)},
+		fmt.Sprintf("Wrote %s with %d references", *out, len(matches)))
+}
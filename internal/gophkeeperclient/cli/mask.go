@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+)
+
+// maskText replaces the secret values in the output of a command.
+const maskText = "*****"
+
+// maskWriter writes the output of a command with the secret values replaced by maskText.
+// A value split between two writes is masked as well: the end of a write that may begin a value
+// is held back until the next write or Flush.
+type maskWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+	pending []byte
+}
+
+// newMaskWriter creates a maskWriter of the values, the empty values are ignored.
+func newMaskWriter(w io.Writer, values []string) *maskWriter {
+	var secrets [][]byte
+	for _, value := range values {
+		if value != "" {
+			secrets = append(secrets, []byte(value))
+		}
+	}
+	// the longest value is masked first, it may contain a shorter one
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	return &maskWriter{w: w, secrets: secrets}
+}
+
+// Write masks and writes the data, it reports the whole data as written.
+func (m *maskWriter) Write(data []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, data...)
+	if err := m.flush(false); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush writes the data held back.
+func (m *maskWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flush(true)
+}
+
+// flush writes the pending data with the values masked, unless final it holds back the end that may begin a value.
+func (m *maskWriter) flush(final bool) error {
+	var out bytes.Buffer
+	i := 0
+scan:
+	for i < len(m.pending) {
+		rest := m.pending[i:]
+		for _, secret := range m.secrets {
+			if bytes.HasPrefix(rest, secret) {
+				out.WriteString(maskText)
+				i += len(secret)
+				continue scan
+			}
+		}
+		if !final {
+			for _, secret := range m.secrets {
+				if len(rest) < len(secret) && bytes.HasPrefix(secret, rest) {
+					break scan
+				}
+			}
+		}
+		out.WriteByte(m.pending[i])
+		i++
+	}
+	m.pending = append(m.pending[:0], m.pending[i:]...)
+	if out.Len() == 0 {
+		return nil
+	}
+	_, err := m.w.Write(out.Bytes())
+	return err
+}
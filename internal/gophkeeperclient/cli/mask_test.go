@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "whole value",
+			values: []string{"s3cret"},
+			writes: []string{"password is s3cret!\n"},
+			want:   "password is *****!\n",
+		},
+		{
+			name:   "value split between writes",
+			values: []string{"s3cret"},
+			writes: []string{"password is s3", "cr", "et and s3cre", "t\n"},
+			want:   "password is ***** and *****\n",
+		},
+		{
+			name:   "begin of a value at the end",
+			values: []string{"s3cret"},
+			writes: []string{"ends with s3c"},
+			want:   "ends with s3c",
+		},
+		{
+			name:   "longest value first",
+			values: []string{"pass", "password", ""},
+			writes: []string{"password pass"},
+			want:   "***** *****",
+		},
+		{
+			name:   "no values",
+			values: nil,
+			writes: []string{"plain ", "output"},
+			want:   "plain output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newMaskWriter(&out, tt.values)
+			for _, data := range tt.writes {
+				n, err := w.Write([]byte(data))
+				require.NoError(t, err)
+				assert.Equal(t, len(data), n)
+			}
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
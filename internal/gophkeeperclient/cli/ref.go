@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+)
+
+// refScheme is the scheme of a secret reference URI: gk://<vault>/<secret-name>/<field>.
+const refScheme = "gk://"
+
+// defaultFields are the fields a reference without a field resolves to, the value for the other types.
+var defaultFields = map[string]string{
+	constatns.TypeLoginPassword: "password",
+	constatns.TypeBankCards:     "card_number",
+}
+
+// resolveRef returns the value referenced among the secrets of the vault of the login.
+// The reference is either <id|name>[#field] or a URI gk://<vault>/<id|name>[/field],
+// see parseRefURI. Without a field a login resolves to its password, a card to its number
+// and the other secrets to their value.
+func resolveRef(entries []entry, login, ref string) (string, error) {
+	name, field, _ := strings.Cut(ref, "#")
+	if strings.HasPrefix(ref, refScheme) {
+		vault, uriName, uriField, err := parseRefURI(ref)
+		if err != nil {
+			return "", err
+		}
+		if vault != login {
+			return "", fmt.Errorf("%s: the vault %q is not open, logged in as %q", ref, vault, login)
+		}
+		name, field = uriName, uriField
+	}
+	e, err := findIn(entries, name)
+	if err != nil {
+		return "", err
+	}
+	if field == "" {
+		field = defaultFields[e.plain.Type]
+	}
+	if field == "" {
+		field = "value"
+	}
+	return fieldOf(e.plain, field)
+}
+
+// parseRefURI splits the URI gk://<vault>/<secret-name>[/field] into its parts.
+// The vault is the login of its owner, every account has one vault. The secret is named by its ID or description
+// and the field is a key of the JSON value, like password or card_number, with an optional leading dot.
+// A slash in a part is escaped as %2F.
+func parseRefURI(ref string) (vault, name, field string, err error) {
+	parts := strings.Split(strings.TrimPrefix(ref, refScheme), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", "", "", fmt.Errorf("%s: want %s<vault>/<secret-name>[/field]", ref, refScheme)
+	}
+	for i, part := range parts {
+		if parts[i], err = url.PathUnescape(part); err != nil {
+			return "", "", "", fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+	vault, name = parts[0], parts[1]
+	if len(parts) == 3 {
+		field = strings.TrimPrefix(parts[2], ".")
+		if field == "" {
+			return "", "", "", fmt.Errorf("%s: empty field", ref)
+		}
+	}
+	if vault == "" || name == "" {
+		return "", "", "", fmt.Errorf("%s: want %s<vault>/<secret-name>[/field]", ref, refScheme)
+	}
+	return vault, name, field, nil
+}
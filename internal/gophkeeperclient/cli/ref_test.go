@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRefURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		vault   string
+		secret  string
+		field   string
+		wantErr bool
+	}{
+		{name: "field", ref: "gk://user/db/password", vault: "user", secret: "db", field: "password"},
+		{name: "field with a dot", ref: "gk://user/card/.card_number", vault: "user", secret: "card", field: "card_number"},
+		{name: "no field", ref: "gk://user/db", vault: "user", secret: "db"},
+		{name: "escaped", ref: "gk://user/prod%2Fdb%20main/password", vault: "user", secret: "prod/db main", field: "password"},
+		{name: "no secret", ref: "gk://user", wantErr: true},
+		{name: "empty secret", ref: "gk://user//password", wantErr: true},
+		{name: "empty field", ref: "gk://user/db/", wantErr: true},
+		{name: "too many parts", ref: "gk://user/prod/db/password", wantErr: true},
+		{name: "bad escape", ref: "gk://user/db%zz/password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault, secret, field, err := parseRefURI(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.vault, vault)
+			assert.Equal(t, tt.secret, secret)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
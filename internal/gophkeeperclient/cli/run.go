@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// exitError passes the exit code of a child process through Run.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// envFlags collects the repeated --env flags.
+type envFlags []string
+
+func (e *envFlags) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *envFlags) Set(value string) error {
+	name, ref, ok := strings.Cut(value, "=")
+	if !ok || name == "" || ref == "" {
+		return fmt.Errorf("%q is not VAR=<ref>", value)
+	}
+	*e = append(*e, value)
+	return nil
+}
+
+// runCommand starts the command with the referenced secrets in its environment and waits for it.
+// The secrets never touch the disk, the values the command prints are masked and its exit code is passed through.
+// The interrupt from the terminal reaches the command directly and does not stop the client before the command,
+// the terminate signal is passed to the command.
+func (c *CLI) runCommand(args []string) error {
+	fs := c.flags("run")
+	var env envFlags
+	fs.Var(&env, "env", "set the variable to the referenced secret, VAR=<id|name>[#field] or VAR=gk://<vault>/<secret-name>/<field>, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("the command to run is required")
+	}
+	if len(env) == 0 {
+		return usageError("at least one --env is required")
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	entries, err := c.secrets(context.Background(), s.Login, keyring)
+	if err != nil {
+		return err
+	}
+	environ := os.Environ()
+	values := make([]string, 0, len(env))
+	for _, variable := range env {
+		name, ref, _ := strings.Cut(variable, "=")
+		value, err := resolveRef(entries, s.Login, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		environ = append(environ, name+"="+value)
+		values = append(values, value)
+	}
+
+	stdout, stderr := newMaskWriter(c.Stdout, values), newMaskWriter(c.Stderr, values)
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...)
+	cmd.Env = environ
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		for sig := range signals {
+			if sig == os.Interrupt {
+				continue
+			}
+			if err := cmd.Process.Signal(sig); err != nil {
+				c.warn("passing %s to the command: %s", sig, err)
+			}
+		}
+	}()
+	err = cmd.Wait()
+	for _, w := range []*maskWriter{stdout, stderr} {
+		if err := w.Flush(); err != nil {
+			c.warn("writing the output: %s", err)
+		}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return exitError{code: 128 + int(status.Signal())}
+		}
+		return exitError{code: exitErr.ExitCode()}
+	}
+	return err
+}
@@ -0,0 +1,422 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// types maps the names of the secret types in the commands to the stored types.
+var types = map[string]string{
+	"card":   constatns.TypeBankCards,
+	"login":  constatns.TypeLoginPassword,
+	"text":   constatns.TypeText,
+	"binary": constatns.TypeBinary,
+	"file":   constatns.TypeFile,
+}
+
+// entry is a secret with its decrypted content.
+type entry struct {
+	secret models.Secret
+	plain  crypter.PlainSecret
+}
+
+// secretView is the JSON output of a secret, the value is only shown by get.
+type secretView struct {
+	ID          uuid.UUID       `json:"id"`
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Revision    int64           `json:"revision"`
+	Updated     time.Time       `json:"updated"`
+	ConflictOf  *uuid.UUID      `json:"conflict_of,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"`
+}
+
+// add creates a secret of the type named by the first arg and prints its ID.
+// A text or binary value is read from the standard input unless --text is set,
+// the file of a file secret is uploaded in encrypted chunks.
+func (c *CLI) add(args []string) error {
+	if len(args) == 0 {
+		return usageError("the type of the secret is required")
+	}
+	secretType, ok := types[args[0]]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown secret type %q", args[0]))
+	}
+	fs := c.flags("add " + args[0])
+	description := fs.String("description", "", "the description of the secret")
+	value := valueFlags(fs, secretType)
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if secretType == constatns.TypeFile && len(positional) != 1 {
+		return usageError("add file takes the path of the file")
+	}
+	if secretType != constatns.TypeFile && len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	ctx := context.Background()
+	secret := models.Secret{ID: uuid.New(), OwnerID: s.Login, Ver: time.Now()}
+	plain := crypter.PlainSecret{Type: secretType, Description: *description}
+	if secretType == constatns.TypeFile {
+		var file clientmodels.File
+		secret.BlobID, file, err = c.Service.BlobService.Upload(ctx, keyring, positional[0])
+		if err != nil {
+			return err
+		}
+		if plain.Description == "" {
+			plain.Description = file.Name
+		}
+		plain.Value, err = json.Marshal(file)
+	} else {
+		plain.Value, err = value(c, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return c.store(ctx, keyring, secret, plain)
+}
+
+// list prints the secrets of the user sorted by description, without their values.
+func (c *CLI) list(args []string) error {
+	fs := c.flags("list")
+	secretType := fs.String("type", "", "list only the secrets of the type")
+	if positional, err := parse(fs, args); err != nil {
+		return err
+	} else if len(positional) != 0 {
+		return usageError("unexpected arguments")
+	}
+	if stored, ok := types[*secretType]; ok {
+		*secretType = stored
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	entries, err := c.secrets(context.Background(), s.Login, keyring)
+	if err != nil {
+		return err
+	}
+	views := make([]secretView, 0, len(entries))
+	var text strings.Builder
+	for _, e := range entries {
+		if *secretType != "" && e.plain.Type != *secretType {
+			continue
+		}
+		views = append(views, viewOf(e))
+		text.WriteString(e.secret.ID.String() + "\t" + e.plain.Type + "\t" + e.plain.Description)
+		if e.secret.ConflictOf != uuid.Nil {
+			text.WriteString("\t(conflicted copy of " + e.secret.ConflictOf.String() + ")")
+		}
+		text.WriteString("\n")
+	}
+	if c.json {
+		return c.print(views, "")
+	}
+	_, err = fmt.Fprint(c.Stdout, text.String())
+	return err
+}
+
+// get prints the value of the secret: a text as it is, a binary value as raw bytes and the other values as JSON.
+// --field prints one field of the value, --out saves the file of a file secret.
+func (c *CLI) get(args []string) error {
+	fs := c.flags("get")
+	field := fs.String("field", "", "print only the field of the value, for example password")
+	out := fs.String("out", "", "save the file of a file secret to the path")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("get takes the ID or the name of the secret")
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	ctx := context.Background()
+	e, err := c.find(ctx, s.Login, keyring, positional[0])
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if e.plain.Type != constatns.TypeFile {
+			return fmt.Errorf("%s is not a file secret", positional[0])
+		}
+		var file clientmodels.File
+		if err = json.Unmarshal(e.plain.Value, &file); err != nil {
+			return err
+		}
+		if err = c.Service.BlobService.Download(ctx, keyring, e.secret.BlobID, file, *out); err != nil {
+			return err
+		}
+		return c.print(map[string]string{"id": e.secret.ID.String(), "path": *out}, "Saved to "+*out)
+	}
+	if *field != "" {
+		value, err := fieldOf(e.plain, *field)
+		if err != nil {
+			return err
+		}
+		return c.print(map[string]string{*field: value}, value)
+	}
+	view := viewOf(e)
+	if view.Value, err = valueJSON(e.plain); err != nil {
+		return err
+	}
+	if c.json {
+		return c.print(view, "")
+	}
+	switch e.plain.Type {
+	case constatns.TypeBinary:
+		_, err = c.Stdout.Write(e.plain.Value)
+		return err
+	case constatns.TypeText:
+		return c.print(nil, string(e.plain.Value))
+	}
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, view.Value, "", "  "); err != nil {
+		return err
+	}
+	return c.print(nil, indented.String())
+}
+
+// edit changes the description or the fields of the value of the secret, only the given flags are changed.
+// The flags of the value depend on the type of the secret as in add, a text or binary value is read
+// from the standard input with --stdin.
+func (c *CLI) edit(args []string) error {
+	fs := c.flags("edit")
+	description := fs.String("description", "", "the new description of the secret")
+	values := make(map[string]valueFunc)
+	for _, secretType := range types {
+		values[secretType] = valueFlags(fs, secretType)
+	}
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("edit takes the ID or the name of the secret")
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	ctx := context.Background()
+	e, err := c.find(ctx, s.Login, keyring, positional[0])
+	if err != nil {
+		return err
+	}
+	allowed := typeFlags(e.plain.Type)
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		switch {
+		case f.Name == "description":
+			changed = true
+		case allowed[f.Name]:
+			changed = true
+		case f.Name != "json":
+			err = usageError(fmt.Sprintf("--%s does not apply to a %s secret", f.Name, e.plain.Type))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return usageError("nothing to change")
+	}
+	if value, ok := values[e.plain.Type]; ok {
+		if e.plain.Value, err = value(c, e.plain.Value); err != nil {
+			return err
+		}
+	}
+	if _, ok := flagValue(fs, "description"); ok {
+		e.plain.Description = *description
+	}
+	e.secret.Ver = time.Now()
+	return c.store(ctx, keyring, e.secret, e.plain)
+}
+
+// rm deletes the secret, the deletion reaches the other devices on sync.
+func (c *CLI) rm(args []string) error {
+	fs := c.flags("rm")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("rm takes the ID or the name of the secret")
+	}
+	s, keyring, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+	c.trySync()
+	ctx := context.Background()
+	e, err := c.find(ctx, s.Login, keyring, positional[0])
+	if err != nil {
+		return err
+	}
+	if err = c.Storage.DeleteSecret(ctx, s.Login, e.secret.ID); err != nil {
+		return err
+	}
+	c.trySync()
+	return c.print(map[string]interface{}{"id": e.secret.ID, "deleted": true}, "Deleted "+e.secret.ID.String())
+}
+
+// store seals the secret into the local storage, sends it to the server and prints its ID.
+func (c *CLI) store(ctx context.Context, keyring crypter.Keyring, secret models.Secret, plain crypter.PlainSecret) error {
+	sealed, err := keyring.Seal(secret, plain)
+	if err != nil {
+		return err
+	}
+	if err = c.Storage.PutSecret(ctx, sealed); err != nil {
+		return err
+	}
+	c.trySync()
+	if stored, err := c.Storage.GetSecret(ctx, secret.OwnerID, secret.ID); err == nil {
+		sealed = stored
+	}
+	return c.print(viewOf(entry{secret: sealed, plain: plain}), sealed.ID.String())
+}
+
+// secrets returns the secrets of the user that are not deleted, sorted by description.
+// A secret that cannot be decrypted is reported and skipped.
+func (c *CLI) secrets(ctx context.Context, login string, keyring crypter.Keyring) ([]entry, error) {
+	lites, err := c.Storage.SyncSecret(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, lite := range lites {
+		secret, err := c.Storage.GetSecret(ctx, login, lite.ID)
+		if err != nil {
+			return nil, err
+		}
+		if secret.IsDeleted {
+			continue
+		}
+		plain, err := keyring.Open(secret)
+		if err != nil {
+			c.warn("%s: %s", secret.ID, err)
+			continue
+		}
+		entries = append(entries, entry{secret: secret, plain: plain})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].plain.Description != entries[j].plain.Description {
+			return entries[i].plain.Description < entries[j].plain.Description
+		}
+		return entries[i].secret.ID.String() < entries[j].secret.ID.String()
+	})
+	return entries, nil
+}
+
+// find returns the secret with the ID or the description ref, a description shared by several secrets is refused.
+func (c *CLI) find(ctx context.Context, login string, keyring crypter.Keyring, ref string) (entry, error) {
+	entries, err := c.secrets(ctx, login, keyring)
+	if err != nil {
+		return entry{}, err
+	}
+	return findIn(entries, ref)
+}
+
+// findIn returns the secret with the ID or the description ref among the entries.
+func findIn(entries []entry, ref string) (entry, error) {
+	var found []entry
+	for _, e := range entries {
+		if e.secret.ID.String() == strings.ToLower(ref) {
+			return e, nil
+		}
+		if e.plain.Description == ref {
+			found = append(found, e)
+		}
+	}
+	if len(found) > 1 {
+		return entry{}, fmt.Errorf("%d secrets are named %q, use the ID", len(found), ref)
+	}
+	if len(found) == 0 {
+		return entry{}, fmt.Errorf("secret %q: %w", ref, constants.ErrSecretNotFound)
+	}
+	return found[0], nil
+}
+
+// fieldOf returns the field of the secret: its description, type or value, or a field of a JSON value,
+// for example the password of a login secret.
+func fieldOf(plain crypter.PlainSecret, field string) (string, error) {
+	switch field {
+	case "description":
+		return plain.Description, nil
+	case "type":
+		return plain.Type, nil
+	case "value":
+		return string(plain.Value), nil
+	}
+	if plain.Type == constatns.TypeText || plain.Type == constatns.TypeBinary {
+		return "", fmt.Errorf("a %s secret has no field %q", plain.Type, field)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(plain.Value))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return "", err
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("a %s secret has no field %q", plain.Type, field)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// viewOf returns the JSON output of the secret without the value.
+func viewOf(e entry) secretView {
+	view := secretView{
+		ID:          e.secret.ID,
+		Type:        e.plain.Type,
+		Description: e.plain.Description,
+		Revision:    e.secret.Revision,
+		Updated:     e.secret.Ver,
+	}
+	if e.secret.ConflictOf != uuid.Nil {
+		conflictOf := e.secret.ConflictOf
+		view.ConflictOf = &conflictOf
+	}
+	return view
+}
+
+// valueJSON encodes the value of the secret: a text as a string, a binary value as base64
+// and the JSON values of the other types as they are.
+func valueJSON(plain crypter.PlainSecret) (json.RawMessage, error) {
+	switch {
+	case plain.Type == constatns.TypeText:
+		return json.Marshal(string(plain.Value))
+	case plain.Type == constatns.TypeBinary || !json.Valid(plain.Value):
+		return json.Marshal(plain.Value)
+	}
+	return plain.Value, nil
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/agent"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// errNotLoggedIn no session is stored.
+var errNotLoggedIn = errors.New("not logged in, run the login command first")
+
+// session is the state kept between the commands: the user, the tokens of the client and the KDF params of the vault.
+// The params let the commands check the master password without the server.
+type session struct {
+	Login     string           `json:"login"`
+	Tokens    models.Tokens    `json:"tokens"`
+	KDFParams models.KDFParams `json:"kdf_params"`
+}
+
+// loadSession reads the session from the file.
+func loadSession(path string) (session, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return session{}, errNotLoggedIn
+	}
+	if err != nil {
+		return session{}, err
+	}
+	var s session
+	if err = json.Unmarshal(data, &s); err != nil {
+		return session{}, err
+	}
+	return s, nil
+}
+
+// saveSession writes the session to the file readable only by the user.
+// The file is replaced atomically, so a failed write keeps the previous session.
+func saveSession(path string, s session) error {
+	if path == "" {
+		return errors.New("no session file, set SESSION_FILE")
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writePrivate(path, data)
+}
+
+// writePrivate writes the file readable only by the user, an existing file is replaced atomically
+// and gets the same permissions.
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gophkeeper-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// open loads the session, passes its tokens to the client and unlocks the vault.
+// It returns the session and the keyring of the vault: the agent if it is unlocked for the user,
+// otherwise the key derived from the master password, which also unlocks a running agent.
+func (c *CLI) open() (session, crypter.Keyring, error) {
+	s, err := loadSession(c.SessionFile)
+	if err != nil {
+		return session{}, nil, err
+	}
+	var keyring crypter.Keyring
+	if tokens, ok := c.agentTokens(s.Login); ok {
+		s.Tokens, keyring = tokens, c.Agent
+	} else {
+		key, err := c.unlockVault(s)
+		if err != nil {
+			return session{}, nil, err
+		}
+		keyring = crypter.Key(key)
+	}
+	c.Client.SetTokens(s.Tokens)
+	c.Service.SyncService.SetClientID(s.Login)
+	return s, keyring, nil
+}
+
+// agentTokens returns the tokens held by the agent if it is unlocked for the user.
+func (c *CLI) agentTokens(login string) (models.Tokens, bool) {
+	if c.Agent == nil {
+		return models.Tokens{}, false
+	}
+	agentLogin, locked, err := c.Agent.Status()
+	if err != nil || locked || agentLogin != login {
+		return models.Tokens{}, false
+	}
+	tokens, err := c.Agent.Tokens()
+	if err != nil {
+		return models.Tokens{}, false
+	}
+	c.agentUnlocked = true
+	return tokens, true
+}
+
+// unlockVault derives the vault key from the master password and unlocks the agent with it if one is running.
+func (c *CLI) unlockVault(s session) ([]byte, error) {
+	password, err := c.masterPassword()
+	if err != nil {
+		return nil, err
+	}
+	key, err := service.CheckMasterPassword(s.KDFParams, password)
+	if err != nil {
+		return nil, err
+	}
+	c.unlockAgent(s.Login, key, s.Tokens)
+	return key, nil
+}
+
+// unlockAgent passes the key and the tokens to the agent if one is running.
+func (c *CLI) unlockAgent(login string, key []byte, tokens models.Tokens) {
+	if c.Agent == nil {
+		return
+	}
+	if _, _, err := c.Agent.Status(); err != nil {
+		return
+	}
+	if err := c.Agent.Unlock(login, key, tokens); err != nil {
+		c.warn("unlocking the agent: %s", err)
+		return
+	}
+	c.agentUnlocked = true
+}
+
+// close stores the tokens refreshed by the command in the session file and the agent.
+func (c *CLI) close(s session) {
+	tokens := c.Client.Tokens()
+	if tokens.AccessToken == s.Tokens.AccessToken && tokens.RefreshToken == s.Tokens.RefreshToken {
+		return
+	}
+	s.Tokens = tokens
+	if err := saveSession(c.SessionFile, s); err != nil {
+		c.warn("saving the session: %s", err)
+	}
+	if c.agentUnlocked {
+		if err := c.Agent.SetTokens(tokens); err != nil && !errors.Is(err, agent.ErrLocked) {
+			c.warn("passing the tokens to the agent: %s", err)
+		}
+	}
+}
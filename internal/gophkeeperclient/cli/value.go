@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
+)
+
+// valueFunc builds the value of a secret from the flags, it changes the old value of an edited secret
+// and builds a new one if old is nil.
+type valueFunc func(c *CLI, old []byte) ([]byte, error)
+
+// valueFlags registers the flags setting the value of a secret of the type.
+// The flags of several types may share a flag set, a flag of the same name is registered once.
+func valueFlags(fs *flag.FlagSet, secretType string) valueFunc {
+	switch secretType {
+	case constatns.TypeBankCards:
+		return fieldFlags(fs, &clientmodels.BankCard{}, map[string]string{
+			"number": "card_number",
+			"month":  "expiry_month",
+			"year":   "expiry_year",
+			"cvv":    "cvv",
+			"holder": "card_holder_name",
+		})
+	case constatns.TypeLoginPassword:
+		fields := fieldFlags(fs, &clientmodels.LoginPassword{}, map[string]string{
+			"login":    "login",
+			"password": "password",
+		})
+		boolFlag(fs, "password-stdin", "read the password from the standard input")
+		return func(c *CLI, old []byte) ([]byte, error) {
+			if set, _ := flagValue(fs, "password-stdin"); set == "true" {
+				password, err := c.readLine()
+				if err != nil {
+					return nil, err
+				}
+				if err = fs.Set("password", password); err != nil {
+					return nil, err
+				}
+			}
+			return fields(c, old)
+		}
+	case constatns.TypeText:
+		fs.String("text", "", "the text, read from the standard input if not set")
+		boolFlag(fs, "stdin", "read the value from the standard input")
+		return func(c *CLI, old []byte) ([]byte, error) {
+			if text, ok := flagValue(fs, "text"); ok {
+				return []byte(text), nil
+			}
+			return stdinValue(c, fs, old)
+		}
+	case constatns.TypeBinary:
+		boolFlag(fs, "stdin", "read the value from the standard input")
+		return func(c *CLI, old []byte) ([]byte, error) {
+			return stdinValue(c, fs, old)
+		}
+	}
+	return func(c *CLI, old []byte) ([]byte, error) {
+		return old, nil
+	}
+}
+
+// fieldFlags registers a flag for every field of the JSON value. The value is decoded into the model,
+// so the value keeps the fields of the model in order.
+func fieldFlags(fs *flag.FlagSet, model interface{}, fields map[string]string) valueFunc {
+	for name, field := range fields {
+		fs.String(name, "", "the "+strings.ReplaceAll(field, "_", " "))
+	}
+	return func(c *CLI, old []byte) ([]byte, error) {
+		if old != nil {
+			if err := json.Unmarshal(old, model); err != nil {
+				return nil, err
+			}
+		}
+		set := make(map[string]string)
+		for name, field := range fields {
+			if value, ok := flagValue(fs, name); ok {
+				set[field] = value
+			}
+		}
+		changes, err := json.Marshal(set)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(changes, model); err != nil {
+			return nil, err
+		}
+		return json.Marshal(model)
+	}
+}
+
+// stdinValue reads the value from the standard input for a new secret or with --stdin, otherwise it keeps old.
+func stdinValue(c *CLI, fs *flag.FlagSet, old []byte) ([]byte, error) {
+	if stdin, _ := flagValue(fs, "stdin"); old != nil && stdin != "true" {
+		return old, nil
+	}
+	return readAll(c.Stdin)
+}
+
+// boolFlag registers the bool flag unless it is already registered.
+func boolFlag(fs *flag.FlagSet, name string, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.Bool(name, false, usage)
+	}
+}
+
+// flagValue returns the value of the flag and whether it was set on the command line.
+func flagValue(fs *flag.FlagSet, name string) (string, bool) {
+	var value string
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			value, set = f.Value.String(), true
+		}
+	})
+	return value, set
+}
+
+// typeFlags returns the names of the flags setting the value of a secret of the type.
+func typeFlags(secretType string) map[string]bool {
+	fs := flag.NewFlagSet(secretType, flag.ContinueOnError)
+	valueFlags(fs, secretType)
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		names[f.Name] = true
+	})
+	return names
+}
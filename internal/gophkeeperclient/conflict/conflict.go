@@ -0,0 +1,94 @@
+// Package conflict keeps and resolves the conflicted copies of secrets edited on two devices at once.
+// A conflicted copy is a separate secret linked to the original by ConflictOf. It holds the losing version
+// until the user picks one of the versions or merges their fields, then the dropped copy is tombstoned.
+package conflict
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// NewCopy returns a conflicted copy of the secret: a new secret with the same sealed content linked to the original.
+// A copy of a conflicted copy is linked to the same original.
+func NewCopy(secret models.Secret) models.Secret {
+	original := secret.ID
+	if secret.ConflictOf != uuid.Nil {
+		original = secret.ConflictOf
+	}
+	return models.Secret{
+		ID:          uuid.New(),
+		OwnerID:     secret.OwnerID,
+		Value:       secret.Value,
+		Type:        secret.Type,
+		Description: secret.Description,
+		Ver:         time.Now(),
+		ConflictOf:  original,
+		BlobID:      secret.BlobID,
+	}
+}
+
+// DiffFields returns the sorted names of the fields that differ between two values.
+// ok is false if either value is not a JSON object of string fields, such values can only be picked as a whole.
+func DiffFields(original []byte, conflicted []byte) (fields []string, ok bool) {
+	originalFields, conflictedFields := make(map[string]string), make(map[string]string)
+	if json.Unmarshal(original, &originalFields) != nil || json.Unmarshal(conflicted, &conflictedFields) != nil {
+		return nil, false
+	}
+	for name, value := range originalFields {
+		if conflictedValue, ok := conflictedFields[name]; !ok || conflictedValue != value {
+			fields = append(fields, name)
+		}
+	}
+	for name := range conflictedFields {
+		if _, ok := originalFields[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields, true
+}
+
+// MergeFields returns the original value with the given fields taken from the copy.
+// Both values must be JSON objects of string fields, see DiffFields.
+func MergeFields(original []byte, conflicted []byte, fromConflicted []string) ([]byte, error) {
+	originalFields, conflictedFields := make(map[string]string), make(map[string]string)
+	if err := json.Unmarshal(original, &originalFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(conflicted, &conflictedFields); err != nil {
+		return nil, err
+	}
+	for _, name := range fromConflicted {
+		value, ok := conflictedFields[name]
+		if !ok {
+			delete(originalFields, name)
+			continue
+		}
+		originalFields[name] = value
+	}
+	return json.Marshal(originalFields)
+}
+
+// Resolve stores the chosen plain content into the original secret and tombstones the conflicted copy.
+// The original keeps its revision, so the resolution is sent to the server as an edit of the current server copy.
+// A deleted original is restored.
+func Resolve(ctx context.Context, storage keeperstorage.KeeperStorage, key []byte, original models.Secret, plain crypter.PlainSecret, conflicted models.Secret) error {
+	original.IsDeleted = false
+	original.Ver = time.Now()
+	sealed, err := crypter.Seal(key, original, plain)
+	if err != nil {
+		return err
+	}
+	if err = storage.PutSecret(ctx, sealed); err != nil {
+		return err
+	}
+	return storage.DeleteSecret(ctx, conflicted.OwnerID, conflicted.ID)
+}
@@ -0,0 +1,130 @@
+package conflict
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	keepermemstorage "yudinsv/gophkeeper/internal/keeperstorage/memstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestNewCopy(t *testing.T) {
+	key := newKey(t)
+	plain := crypter.PlainSecret{Type: "text", Description: "note", Value: []byte("local edit")}
+	original, err := crypter.Seal(key, models.Secret{ID: uuid.New(), OwnerID: "user1", Ver: time.Now(), Revision: 3}, plain)
+	assert.NoError(t, err)
+
+	conflicted := NewCopy(original)
+	assert.NotEqual(t, original.ID, conflicted.ID)
+	assert.Equal(t, original.ID, conflicted.ConflictOf)
+	assert.Equal(t, original.OwnerID, conflicted.OwnerID)
+	assert.Equal(t, int64(0), conflicted.Revision)
+
+	// The copy is opened without sealing it again
+	opened, err := crypter.Open(key, conflicted)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	// A copy of a copy is linked to the original
+	assert.Equal(t, original.ID, NewCopy(conflicted).ConflictOf)
+}
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		original   string
+		conflicted string
+		want       []string
+		wantOK     bool
+	}{
+		{
+			name:       "Changed fields",
+			original:   `{"login":"user","password":"old"}`,
+			conflicted: `{"login":"user","password":"new"}`,
+			want:       []string{"password"},
+			wantOK:     true,
+		},
+		{
+			name:       "Missing fields",
+			original:   `{"login":"user"}`,
+			conflicted: `{"password":"new"}`,
+			want:       []string{"login", "password"},
+			wantOK:     true,
+		},
+		{
+			name:       "Same fields",
+			original:   `{"login":"user"}`,
+			conflicted: `{"login":"user"}`,
+			wantOK:     true,
+		},
+		{
+			name:       "Text",
+			original:   `some text`,
+			conflicted: `{"login":"user"}`,
+			wantOK:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, ok := DiffFields([]byte(tt.original), []byte(tt.conflicted))
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, fields)
+		})
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+	original := []byte(`{"card_number":"1111","cvv":"123","expiry_year":"2030"}`)
+	conflicted := []byte(`{"card_number":"2222","cvv":"456"}`)
+
+	merged, err := MergeFields(original, conflicted, []string{"card_number", "expiry_year"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"card_number":"2222","cvv":"123"}`, string(merged))
+
+	_, err = MergeFields([]byte("text"), conflicted, nil)
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	key := newKey(t)
+	storage := keepermemstorage.NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+	original, err := crypter.Seal(key, models.Secret{ID: uuid.New(), OwnerID: "user1", Ver: time.Now(), Revision: 2, IsDeleted: true},
+		crypter.PlainSecret{Type: "text", Value: []byte("server")})
+	assert.NoError(t, err)
+	conflicted := NewCopy(original)
+	assert.NoError(t, storage.PutSecret(ctx, original))
+	assert.NoError(t, storage.PutSecret(ctx, conflicted))
+
+	plain := crypter.PlainSecret{Type: "text", Description: "merged", Value: []byte("local")}
+	assert.NoError(t, Resolve(ctx, storage, key, original, plain, conflicted))
+
+	// The original is restored with the chosen content from its current revision
+	stored, err := storage.GetSecret(ctx, "user1", original.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.IsDeleted)
+	assert.Equal(t, int64(2), stored.Revision)
+	opened, err := crypter.Open(key, stored)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	// The dropped copy is tombstoned
+	stored, err = storage.GetSecret(ctx, "user1", conflicted.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.IsDeleted)
+}
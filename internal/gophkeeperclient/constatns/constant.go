@@ -7,3 +7,31 @@ const TimeOutSync = time.Duration(10 * time.Second)
 
 // TimeSleepSync next sync timeout
 const TimeSleepSync = time.Duration(5 * time.Second)
+
+// TimeSleepSyncStreaming next sync timeout while the server reports the changes on the event stream,
+// the local changes are still pushed every TimeSleepSync
+const TimeSleepSyncStreaming = time.Duration(time.Minute)
+
+// ChunkSize size of the chunks a file is uploaded in
+const ChunkSize = 4 << 20
+
+// ChunkRetries attempts to transfer a chunk before the upload or download fails
+const ChunkRetries = 3
+
+// ChunkRetryDelay delay before the next attempt, multiplied by the attempt number
+const ChunkRetryDelay = time.Second
+
+// TypeBankCards type of the secrets holding a models.BankCard
+const TypeBankCards = "bank_cards"
+
+// TypeLoginPassword type of the secrets holding a models.LoginPassword
+const TypeLoginPassword = "login_password"
+
+// TypeBinary type of the secrets holding arbitrary bytes
+const TypeBinary = "binary"
+
+// TypeText type of the secrets holding a text
+const TypeText = "text"
+
+// TypeFile type of the secrets holding the manifest of a file uploaded in chunks
+const TypeFile = "file"
@@ -0,0 +1,30 @@
+package crypter
+
+import (
+	"fmt"
+
+	"yudinsv/gophkeeper/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// SealChunk encrypts a chunk of a file into an envelope bound to the blob, the index and the number of chunks,
+// so the chunks cannot be reordered, dropped or moved to another file unnoticed.
+func SealChunk(key []byte, blobID uuid.UUID, index int, count int, data []byte) ([]byte, error) {
+	return utils.SealEnvelope(data, key, chunkAssociatedData(blobID, index, count))
+}
+
+// OpenChunk decrypts a chunk sealed by SealChunk. Chunks are never stored in the legacy format,
+// so a value that is not an envelope is rejected.
+func OpenChunk(key []byte, blobID uuid.UUID, index int, count int, envelope []byte) ([]byte, error) {
+	if !utils.IsEnvelope(envelope) {
+		return nil, utils.ErrEnvelopeAuth
+	}
+	data, err := utils.OpenEnvelope(envelope, key, chunkAssociatedData(blobID, index, count))
+	return data, err
+}
+
+// chunkAssociatedData is the associated data of the chunk envelope.
+func chunkAssociatedData(blobID uuid.UUID, index int, count int) []byte {
+	return utils.SecretAssociatedData(blobID, fmt.Sprintf("chunk:%d/%d", index, count))
+}
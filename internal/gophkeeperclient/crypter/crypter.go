@@ -0,0 +1,116 @@
+// Package crypter seals secrets on the client before they leave the device.
+// The value, the description and the type of a secret are each sealed into an envelope
+// under the vault key, so the server only stores ciphertext and ciphertext digests.
+// The description and the type are stored as base64-encoded envelopes in the string fields of models.Secret.
+// The envelopes of a conflicted copy stay bound to the original secret (ConflictOf),
+// so sync can keep the copy without the vault key.
+package crypter
+
+import (
+	"encoding/base64"
+
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Associated data labels of the metadata fields, they keep the fields from being swapped.
+const (
+	typeField        = "meta:type"
+	descriptionField = "meta:description"
+)
+
+// PlainSecret is the decrypted content of a models.Secret.
+// Legacy is set when some field was stored in a legacy format and should be sealed again.
+type PlainSecret struct {
+	Type        string
+	Description string
+	Value       []byte
+	Legacy      bool
+}
+
+// Seal encrypts the plain secret with the key into a copy of the secret.
+// The ID, owner, version and tombstone flag are taken from the secret unchanged.
+func Seal(key []byte, secret models.Secret, plain PlainSecret) (models.Secret, error) {
+	value, err := utils.SealEnvelope(plain.Value, key, utils.SecretAssociatedData(boundID(secret), plain.Type))
+	if err != nil {
+		return models.Secret{}, err
+	}
+	secretType, err := sealField(key, boundID(secret), typeField, plain.Type)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	description, err := sealField(key, boundID(secret), descriptionField, plain.Description)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	secret.Value = value
+	secret.Type = secretType
+	secret.Description = description
+	return secret, nil
+}
+
+// Open decrypts the value and metadata of the secret with the key.
+// Metadata stored in plaintext by older clients is returned as is and reported with Legacy set.
+func Open(key []byte, secret models.Secret) (PlainSecret, error) {
+	secretType, legacyType, err := openField(key, boundID(secret), typeField, secret.Type)
+	if err != nil {
+		return PlainSecret{}, err
+	}
+	description, legacyDescription, err := openField(key, boundID(secret), descriptionField, secret.Description)
+	if err != nil {
+		return PlainSecret{}, err
+	}
+	value, err := utils.OpenEnvelope(secret.Value, key, utils.SecretAssociatedData(boundID(secret), secretType))
+	if err != nil {
+		return PlainSecret{}, err
+	}
+	return PlainSecret{
+		Type:        secretType,
+		Description: description,
+		Value:       value,
+		Legacy:      legacyType || legacyDescription,
+	}, nil
+}
+
+// OpenLegacy decrypts a secret stored by the clients before the vault key:
+// the value is encrypted with the private key of the user, see utils.DecryptLegacyValue, and the metadata is plaintext.
+func OpenLegacy(privateKey string, secret models.Secret) (PlainSecret, error) {
+	value, err := utils.DecryptLegacyValue(secret.Value, privateKey)
+	if err != nil {
+		return PlainSecret{}, err
+	}
+	return PlainSecret{Type: secret.Type, Description: secret.Description, Value: value, Legacy: true}, nil
+}
+
+// boundID returns the ID the envelopes of the secret are bound to.
+func boundID(secret models.Secret) uuid.UUID {
+	if secret.ConflictOf != uuid.Nil {
+		return secret.ConflictOf
+	}
+	return secret.ID
+}
+
+// sealField seals a metadata field into a base64-encoded envelope.
+func sealField(key []byte, secretID uuid.UUID, field string, text string) (string, error) {
+	envelope, err := utils.SealEnvelope([]byte(text), key, utils.SecretAssociatedData(secretID, field))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// openField opens a metadata field sealed by sealField.
+// A field that is not a base64-encoded envelope is legacy plaintext.
+func openField(key []byte, secretID uuid.UUID, field string, stored string) (string, bool, error) {
+	envelope, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil || !utils.IsEnvelope(envelope) {
+		return stored, true, nil
+	}
+	text, err := utils.OpenEnvelope(envelope, key, utils.SecretAssociatedData(secretID, field))
+	if err != nil {
+		return "", false, err
+	}
+	return string(text), false, nil
+}
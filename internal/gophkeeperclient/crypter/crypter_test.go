@@ -0,0 +1,89 @@
+package crypter
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	key := newKey(t)
+	base := models.Secret{ID: uuid.New(), OwnerID: "user1", Ver: time.Now()}
+	plain := PlainSecret{Type: "text", Description: "my note", Value: []byte("hello")}
+
+	secret, err := Seal(key, base, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, base.ID, secret.ID)
+	assert.Equal(t, base.OwnerID, secret.OwnerID)
+	assert.NotContains(t, secret.Description, "my note")
+	assert.NotEqual(t, "text", secret.Type)
+
+	opened, err := Open(key, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, opened)
+}
+
+func TestOpenTampered(t *testing.T) {
+	key := newKey(t)
+	first, err := Seal(key, models.Secret{ID: uuid.New()}, PlainSecret{Type: "text", Description: "first", Value: []byte("1")})
+	assert.NoError(t, err)
+	second, err := Seal(key, models.Secret{ID: uuid.New()}, PlainSecret{Type: "text", Description: "second", Value: []byte("2")})
+	assert.NoError(t, err)
+
+	// Metadata moved from another secret must not be accepted
+	first.Description = second.Description
+	_, err = Open(key, first)
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth)
+
+	// Another key must not open the secret
+	_, err = Open(newKey(t), second)
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth)
+}
+
+func TestOpenLegacyMetadata(t *testing.T) {
+	key := newKey(t)
+	secretID := uuid.New()
+	value, err := utils.SealEnvelope([]byte("hello"), key, utils.SecretAssociatedData(secretID, "text"))
+	assert.NoError(t, err)
+
+	opened, err := Open(key, models.Secret{ID: secretID, Type: "text", Description: "plain description", Value: value})
+	assert.NoError(t, err)
+	assert.True(t, opened.Legacy)
+	assert.Equal(t, "text", opened.Type)
+	assert.Equal(t, "plain description", opened.Description)
+	assert.Equal(t, []byte("hello"), opened.Value)
+}
+
+func TestSealOpenChunk(t *testing.T) {
+	key := newKey(t)
+	blobID := uuid.New()
+	sealed, err := SealChunk(key, blobID, 1, 3, []byte("chunk"))
+	assert.NoError(t, err)
+
+	data, err := OpenChunk(key, blobID, 1, 3, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("chunk"), data)
+
+	_, err = OpenChunk(key, blobID, 2, 3, sealed)
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth, "moved to another index")
+	_, err = OpenChunk(key, blobID, 1, 2, sealed)
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth, "file truncated")
+	_, err = OpenChunk(key, uuid.New(), 1, 3, sealed)
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth, "moved to another file")
+	_, err = OpenChunk(key, blobID, 1, 3, []byte("chunk"))
+	assert.ErrorIs(t, err, utils.ErrEnvelopeAuth, "not an envelope")
+}
@@ -0,0 +1,51 @@
+package crypter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Keyring seals and opens with the vault key. The key may be held by another process,
+// such as the agent, so the callers never need the key itself.
+// MAC authenticates the data with a key derived from the vault key, it identifies the content without revealing it.
+type Keyring interface {
+	Seal(secret models.Secret, plain PlainSecret) (models.Secret, error)
+	Open(secret models.Secret) (PlainSecret, error)
+	SealChunk(blobID uuid.UUID, index int, count int, data []byte) ([]byte, error)
+	OpenChunk(blobID uuid.UUID, index int, count int, envelope []byte) ([]byte, error)
+	MAC(data []byte) ([]byte, error)
+}
+
+// Key is a Keyring holding the vault key in memory.
+type Key []byte
+
+// Seal seals the secret with Seal.
+func (k Key) Seal(secret models.Secret, plain PlainSecret) (models.Secret, error) {
+	return Seal(k, secret, plain)
+}
+
+// Open opens the secret with Open.
+func (k Key) Open(secret models.Secret) (PlainSecret, error) {
+	return Open(k, secret)
+}
+
+// SealChunk seals the chunk with SealChunk.
+func (k Key) SealChunk(blobID uuid.UUID, index int, count int, data []byte) ([]byte, error) {
+	return SealChunk(k, blobID, index, count, data)
+}
+
+// OpenChunk opens the chunk with OpenChunk.
+func (k Key) OpenChunk(blobID uuid.UUID, index int, count int, envelope []byte) ([]byte, error) {
+	return OpenChunk(k, blobID, index, count, envelope)
+}
+
+// MAC returns the HMAC-SHA256 of the data under the key.
+func (k Key) MAC(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
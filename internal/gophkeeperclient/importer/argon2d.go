@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// argon2 version 1.3, the only version KDBX 4 uses.
+const argon2Version = 0x13
+
+// argon2Block is a memory block of Argon2, 1 KiB.
+type argon2Block [128]uint64
+
+// argon2dKey derives a key of keyLen bytes with Argon2d (RFC 9106), the default KDF of KeePassXC.
+// golang.org/x/crypto/argon2 implements only Argon2i and Argon2id. The secret and the associated data are optional.
+// memory is in KiB.
+func argon2dKey(password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	lanes := uint32(threads)
+	if memory < 8*lanes {
+		memory = 8 * lanes
+	}
+	segment := memory / (4 * lanes)
+	columns := 4 * segment
+
+	h := mustBlake2b(64)
+	for _, v := range []uint32{lanes, keyLen, memory, time, argon2Version, 0} {
+		writeUint32(h, v)
+	}
+	for _, v := range [][]byte{password, salt, secret, data} {
+		writeUint32(h, uint32(len(v)))
+		h.Write(v)
+	}
+	h0 := h.Sum(nil)
+
+	b := make([]argon2Block, lanes*columns)
+	seed := make([]byte, 1024)
+	for lane := uint32(0); lane < lanes; lane++ {
+		for i := uint32(0); i < 2; i++ {
+			input := append(append([]byte{}, h0...), 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(input[64:], i)
+			binary.LittleEndian.PutUint32(input[68:], lane)
+			blake2bLong(seed, input)
+			for k := range b[lane*columns+i] {
+				b[lane*columns+i][k] = binary.LittleEndian.Uint64(seed[k*8:])
+			}
+		}
+	}
+
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < 4; slice++ {
+			for lane := uint32(0); lane < lanes; lane++ {
+				first := uint32(0)
+				if pass == 0 && slice == 0 {
+					first = 2
+				}
+				for j := first; j < segment; j++ {
+					index := slice*segment + j
+					prev := index - 1
+					if index == 0 {
+						prev = columns - 1
+					}
+					prevBlock := &b[lane*columns+prev]
+					j1, j2 := uint32(prevBlock[0]), uint32(prevBlock[0]>>32)
+
+					refLane := uint64(j2) % uint64(lanes)
+					if pass == 0 && slice == 0 {
+						refLane = uint64(lane)
+					}
+					var area, start uint32
+					switch {
+					case pass == 0 && refLane == uint64(lane):
+						area = slice*segment + j - 1
+					case pass == 0:
+						area = slice * segment
+					case refLane == uint64(lane):
+						area = columns - segment + j - 1
+					default:
+						area = columns - segment
+					}
+					if refLane != uint64(lane) && j == 0 {
+						area--
+					}
+					if pass != 0 && slice != 3 {
+						start = (slice + 1) * segment
+					}
+					x := uint64(j1) * uint64(j1) >> 32
+					y := uint64(area) * x >> 32
+					ref := (uint64(start) + uint64(area) - 1 - y) % uint64(columns)
+
+					out := &b[lane*columns+index]
+					next := compress(prevBlock, &b[refLane*uint64(columns)+ref])
+					if pass == 0 {
+						*out = next
+						continue
+					}
+					for k := range out {
+						out[k] ^= next[k]
+					}
+				}
+			}
+		}
+	}
+
+	final := b[columns-1]
+	for lane := uint32(1); lane < lanes; lane++ {
+		for k, v := range b[lane*columns+columns-1] {
+			final[k] ^= v
+		}
+	}
+	block := make([]byte, 1024)
+	for k, v := range final {
+		binary.LittleEndian.PutUint64(block[k*8:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bLong(key, block)
+	return key
+}
+
+// compress is the compression function G of Argon2.
+func compress(x, y *argon2Block) argon2Block {
+	var r, q argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	q = r
+	for i := 0; i < 128; i += 16 {
+		blamka(&q[i], &q[i+1], &q[i+2], &q[i+3], &q[i+4], &q[i+5], &q[i+6], &q[i+7],
+			&q[i+8], &q[i+9], &q[i+10], &q[i+11], &q[i+12], &q[i+13], &q[i+14], &q[i+15])
+	}
+	for i := 0; i < 16; i += 2 {
+		blamka(&q[i], &q[i+1], &q[i+16], &q[i+17], &q[i+32], &q[i+33], &q[i+48], &q[i+49],
+			&q[i+64], &q[i+65], &q[i+80], &q[i+81], &q[i+96], &q[i+97], &q[i+112], &q[i+113])
+	}
+	for i := range q {
+		q[i] ^= r[i]
+	}
+	return q
+}
+
+// blamka is the permutation P of Argon2, a round of BLAKE2b with multiplications added.
+func blamka(v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 *uint64) {
+	gb(v0, v4, v8, v12)
+	gb(v1, v5, v9, v13)
+	gb(v2, v6, v10, v14)
+	gb(v3, v7, v11, v15)
+	gb(v0, v5, v10, v15)
+	gb(v1, v6, v11, v12)
+	gb(v2, v7, v8, v13)
+	gb(v3, v4, v9, v14)
+}
+
+func gb(a, b, c, d *uint64) {
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d = bits.RotateLeft64(*d^*a, -32)
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b = bits.RotateLeft64(*b^*c, -24)
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d = bits.RotateLeft64(*d^*a, -16)
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b = bits.RotateLeft64(*b^*c, -63)
+}
+
+// blake2bLong is the variable-length hash H' of Argon2, it fills out.
+func blake2bLong(out, in []byte) {
+	if len(out) <= blake2b.Size {
+		h := mustBlake2b(len(out))
+		writeUint32(h, uint32(len(out)))
+		h.Write(in)
+		h.Sum(out[:0])
+		return
+	}
+	h := mustBlake2b(blake2b.Size)
+	writeUint32(h, uint32(len(out)))
+	h.Write(in)
+	v := h.Sum(nil)
+	n := copy(out, v[:32])
+	for len(out)-n > blake2b.Size {
+		sum := blake2b.Sum512(v)
+		v = sum[:]
+		n += copy(out[n:], v[:32])
+	}
+	h = mustBlake2b(len(out) - n)
+	h.Write(v)
+	h.Sum(out[n:n])
+}
+
+func mustBlake2b(size int) hash.Hash {
+	h, err := blake2b.New(size, nil)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func writeUint32(h hash.Hash, v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	h.Write(buf[:])
+}
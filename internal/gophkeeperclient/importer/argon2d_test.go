@@ -0,0 +1,16 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgon2dKey(t *testing.T) {
+	// the test vector of Argon2d in RFC 9106, section 5.1
+	key := argon2dKey(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), bytes.Repeat([]byte{3}, 8),
+		bytes.Repeat([]byte{4}, 12), 3, 32, 4, 32)
+	assert.Equal(t, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb", hex.EncodeToString(key))
+}
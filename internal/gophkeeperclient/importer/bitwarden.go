@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
+)
+
+// The types of the items of a Bitwarden export.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+	bitwardenSSHKey     = 5
+)
+
+// bitwardenExport is an unencrypted JSON export of a Bitwarden vault, only the fields the keeper reads.
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type   int    `json:"type"`
+	Name   string `json:"name"`
+	Notes  string `json:"notes"`
+	Fields []struct {
+		Name string `json:"name"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+		Passkeys []json.RawMessage `json:"fido2Credentials"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Brand          string `json:"brand"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+}
+
+// ReadBitwarden reads the entries of an unencrypted Bitwarden JSON export.
+func ReadBitwarden(r io.Reader) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("bitwarden export is encrypted, export the vault as unencrypted JSON")
+	}
+	entries := make([]Entry, 0, len(export.Items))
+	for _, item := range export.Items {
+		var unsupported []string
+		for _, field := range item.Fields {
+			unsupported = append(unsupported, "field "+field.Name)
+		}
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			login := item.Login
+			if len(login.URIs) != 0 {
+				unsupported = append(unsupported, "uris")
+			}
+			unsupported = appendIf(unsupported, "totp", login.TOTP)
+			unsupported = appendIf(unsupported, "notes", item.Notes)
+			if len(login.Passkeys) != 0 {
+				unsupported = append(unsupported, "passkeys")
+			}
+			entry, err := loginEntry(item.Name, login.Username, login.Password, unsupported)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case item.Type == bitwardenSecureNote:
+			entries = append(entries, textEntry(item.Name, item.Notes, unsupported))
+		case item.Type == bitwardenCard && item.Card != nil:
+			card := item.Card
+			unsupported = appendIf(unsupported, "brand", card.Brand)
+			unsupported = appendIf(unsupported, "notes", item.Notes)
+			value, err := json.Marshal(clientmodels.BankCard{
+				CardNumber:     card.Number,
+				ExpiryMonth:    card.ExpMonth,
+				ExpiryYear:     card.ExpYear,
+				CVV:            card.Code,
+				CardHolderName: card.CardholderName,
+			})
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, Entry{
+				Type: constatns.TypeBankCards, Description: item.Name, Value: value, Unsupported: unsupported,
+			})
+		case item.Type == bitwardenIdentity:
+			entries = append(entries, unsupportedEntry(item.Name, "identity"))
+		case item.Type == bitwardenSSHKey:
+			entries = append(entries, unsupportedEntry(item.Name, "ssh key"))
+		default:
+			entries = append(entries, unsupportedEntry(item.Name, fmt.Sprintf("item type %d", item.Type)))
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bitwardenJSON = `{
+  "encrypted": false,
+  "folders": [],
+  "items": [
+    {
+      "type": 1, "name": "github", "notes": "work account",
+      "fields": [{"name": "recovery", "value": "abc", "type": 1}],
+      "login": {"username": "octocat", "password": "s3cret", "totp": "otpauth://totp/x",
+        "uris": [{"match": null, "uri": "https://github.com"}]}
+    },
+    {"type": 2, "name": "wifi", "notes": "the code is 1234", "secureNote": {"type": 0}},
+    {
+      "type": 3, "name": "visa", "notes": null,
+      "card": {"cardholderName": "Jane Doe", "brand": "Visa", "number": "4111111111111111",
+        "expMonth": "12", "expYear": "2030", "code": "123"}
+    },
+    {"type": 4, "name": "me", "identity": {"firstName": "Jane"}}
+  ]
+}`
+
+func TestReadBitwarden(t *testing.T) {
+	entries, err := ReadBitwarden(strings.NewReader(bitwardenJSON))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Type: constatns.TypeLoginPassword, Description: "github",
+			Value:       []byte(`{"login":"octocat","password":"s3cret"}`),
+			Unsupported: []string{"field recovery", "uris", "totp", "notes"},
+		},
+		{Type: constatns.TypeText, Description: "wifi", Value: []byte("the code is 1234")},
+		{
+			Type: constatns.TypeBankCards, Description: "visa",
+			Value: []byte(`{"card_number":"4111111111111111","expiry_month":"12","expiry_year":"2030",` +
+				`"cvv":"123","card_holder_name":"Jane Doe"}`),
+			Unsupported: []string{"brand"},
+		},
+		{Description: "me", Unsupported: []string{"identity"}},
+	}, entries)
+}
+
+func TestReadBitwarden_Encrypted(t *testing.T) {
+	_, err := ReadBitwarden(strings.NewReader(`{"encrypted": true, "passwordProtected": true, "data": "..."}`))
+	assert.ErrorContains(t, err, "unencrypted")
+
+	_, err = ReadBitwarden(strings.NewReader(`name,url`))
+	assert.Error(t, err)
+}
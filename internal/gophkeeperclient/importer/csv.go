@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns are the names the browsers give the columns of their password CSV files:
+// Chrome writes name,url,username,password,note and Firefox writes url,username,password followed by
+// httpRealm,formActionOrigin,guid and the times of the login, which the keeper does not need.
+var csvColumns = map[string]string{
+	"name":     "name",
+	"title":    "name",
+	"url":      "url",
+	"username": "username",
+	"login":    "username",
+	"password": "password",
+	"note":     "note",
+	"notes":    "note",
+	"otpauth":  "totp",
+}
+
+// ReadBrowserCSV reads the logins of a password CSV file exported by Chrome or Firefox.
+// An entry without a name is described by the host of its URL.
+func ReadBrowserCSV(r io.Reader) ([]Entry, error) {
+	buffered := bufio.NewReader(r)
+	// a file saved by a spreadsheet may start with a byte order mark
+	if bom, err := buffered.Peek(3); err == nil && string(bom) == "\ufeff" {
+		_, _ = buffered.Discard(3)
+	}
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if column, ok := csvColumns[name]; ok {
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, errors.New("csv: no password column, not a password export of a browser")
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		description := describe(get("name"), get("url"))
+		var unsupported []string
+		unsupported = appendIf(unsupported, "url", get("url"))
+		unsupported = appendIf(unsupported, "note", get("note"))
+		unsupported = appendIf(unsupported, "totp", get("totp"))
+		entry, err := loginEntry(description, get("username"), get("password"), unsupported)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBrowserCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []Entry
+	}{
+		{
+			name: "chrome",
+			csv: "name,url,username,password,note\n" +
+				"github.com,https://github.com/login,octocat,s3cret,\n" +
+				"bank,https://bank.example,jane,\"pa,ss\",\"call \"\"support\"\"\"\n",
+			want: []Entry{
+				{
+					Type: constatns.TypeLoginPassword, Description: "github.com",
+					Value: []byte(`{"login":"octocat","password":"s3cret"}`), Unsupported: []string{"url"},
+				},
+				{
+					Type: constatns.TypeLoginPassword, Description: "bank",
+					Value: []byte(`{"login":"jane","password":"pa,ss"}`), Unsupported: []string{"url", "note"},
+				},
+			},
+		},
+		{
+			name: "firefox",
+			csv: "\ufeff\"url\",\"username\",\"password\",\"httpRealm\",\"formActionOrigin\",\"guid\",\"timeCreated\"," +
+				"\"timeLastUsed\",\"timePasswordChanged\"\n" +
+				"\"https://github.com\",\"octocat\",\"s3cret\",,\"https://github.com\",\"{1}\",\"1\",\"2\",\"3\"\n",
+			want: []Entry{
+				{
+					Type: constatns.TypeLoginPassword, Description: "github.com",
+					Value: []byte(`{"login":"octocat","password":"s3cret"}`), Unsupported: []string{"url"},
+				},
+			},
+		},
+		{
+			name: "empty",
+			csv:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ReadBrowserCSV(strings.NewReader(tt.csv))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, entries)
+		})
+	}
+}
+
+func TestReadBrowserCSV_NotPasswords(t *testing.T) {
+	_, err := ReadBrowserCSV(strings.NewReader("date,amount\n2023-01-01,10\n"))
+	assert.ErrorContains(t, err, "no password column")
+}
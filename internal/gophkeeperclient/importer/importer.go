@@ -0,0 +1,84 @@
+// Package importer reads the exports of other password managers into secrets of the keeper:
+// KeePass KDBX 4 databases, unencrypted Bitwarden JSON exports and the password CSV files of Chrome and Firefox.
+// Logins become login_password secrets, cards bank_cards and notes text secrets. The fields with no place
+// in these types, like URLs or custom fields, are reported and not imported.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
+)
+
+// The formats of the exports.
+const (
+	FormatKeePass   = "keepass"
+	FormatBitwarden = "bitwarden"
+	FormatCSV       = "csv"
+)
+
+// Entry is an entry of an export as a secret of the keeper.
+// Type is empty for an entry of a kind no secret type holds, like a Bitwarden identity, then Unsupported names the kind.
+type Entry struct {
+	Type        string
+	Description string
+	Value       []byte
+	// Unsupported names the fields of the entry that are not imported.
+	Unsupported []string
+}
+
+// FormatOf returns the format of the export by the extension of its file.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".kdbx":
+		return FormatKeePass, nil
+	case ".json":
+		return FormatBitwarden, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown format of %s, set it explicitly", filepath.Base(path))
+}
+
+// loginEntry returns the entry of a login.
+func loginEntry(description, login, password string, unsupported []string) (Entry, error) {
+	value, err := json.Marshal(clientmodels.LoginPassword{Login: login, Password: password})
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Type: constatns.TypeLoginPassword, Description: description, Value: value, Unsupported: unsupported}, nil
+}
+
+// textEntry returns the entry of a note.
+func textEntry(description, text string, unsupported []string) Entry {
+	return Entry{Type: constatns.TypeText, Description: description, Value: []byte(text), Unsupported: unsupported}
+}
+
+// unsupportedEntry returns the entry of a kind no secret type holds.
+func unsupportedEntry(description, kind string) Entry {
+	return Entry{Description: description, Unsupported: []string{kind}}
+}
+
+// describe returns the description of an entry: its name or the host of its URL.
+func describe(name, rawURL string) string {
+	if name != "" {
+		return name
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// appendIf appends the name of the field to the unsupported fields if its value is set.
+func appendIf(unsupported []string, name, value string) []string {
+	if value == "" {
+		return unsupported
+	}
+	return append(unsupported, name)
+}
@@ -0,0 +1,525 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+// The signatures starting a KDBX file.
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+)
+
+// The fields of the outer header of KDBX 4.
+const (
+	kdbxEndOfHeader      = 0
+	kdbxCipherID         = 2
+	kdbxCompressionFlags = 3
+	kdbxMasterSeed       = 4
+	kdbxEncryptionIV     = 7
+	kdbxKDFParameters    = 11
+)
+
+// The fields of the inner header of KDBX 4.
+const (
+	kdbxInnerEndOfHeader = 0
+	kdbxInnerStreamID    = 1
+	kdbxInnerStreamKey   = 2
+)
+
+// kdbxInnerChaCha20 is the inner stream protecting the values in the XML, KDBX 4 always uses ChaCha20.
+const kdbxInnerChaCha20 = 3
+
+// The ciphers and the KDFs of KDBX 4 by UUID.
+var (
+	kdbxAES256   = uuid.MustParse("31c1f2e6-bf71-4350-be58-05216afc5aff")
+	kdbxChaCha20 = uuid.MustParse("d6038a2b-8b6f-4cb5-a524-339a31dbb59a")
+	kdbxTwofish  = uuid.MustParse("ad68f29f-576f-4bb9-a36a-d47af965346c")
+
+	kdbxAESKDF   = uuid.MustParse("c9d9f39a-628a-4460-bf74-0d08c18a4fea")
+	kdbxAESKDF3  = uuid.MustParse("7c02bb82-79a7-4ac0-927d-114a00648238")
+	kdbxArgon2d  = uuid.MustParse("ef636ddf-8c29-444b-91f7-a9a403e30a0c")
+	kdbxArgon2id = uuid.MustParse("9e298b19-56db-4773-b23d-fc3ec6f0a1e6")
+)
+
+// The limits of the Argon2 parameters. The parameters are used before the HMAC of the header
+// is checked, so a damaged or crafted file must not make the KDF run out of memory.
+const (
+	argon2MaxMemory      = 1 << 30 // 1 GiB
+	argon2MaxParallelism = 64
+)
+
+var (
+	// errKDBXFormat the file cannot be read as a KDBX 4 database.
+	errKDBXFormat = errors.New("keepass: not a KDBX file or the file is damaged")
+	// errKeePassPassword the database cannot be unlocked with the password.
+	errKeePassPassword = errors.New("keepass: wrong password or the database is damaged")
+)
+
+// kdbxHeader is the outer header of a KDBX 4 database.
+type kdbxHeader struct {
+	cipher     uuid.UUID
+	compressed bool
+	masterSeed []byte
+	iv         []byte
+	kdf        map[string][]byte
+	// size is the size of the header in the file.
+	size int
+}
+
+// ReadKeePass reads the entries of a KeePass KDBX 4 database unlocked with its master password, key files
+// are not supported. The entries of the recycle bin and the history of the entries are not imported.
+func ReadKeePass(r io.Reader, password string) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	header, err := readKDBXHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[header.size:]
+	if len(rest) < 64 {
+		return nil, errKDBXFormat
+	}
+	if sum := sha256.Sum256(data[:header.size]); !bytes.Equal(sum[:], rest[:32]) {
+		return nil, errKDBXFormat
+	}
+
+	composite := sha256.Sum256([]byte(password))
+	composite = sha256.Sum256(composite[:])
+	transformed, err := header.transformKey(composite[:])
+	if err != nil {
+		return nil, err
+	}
+	hmacBase := sha512.Sum512(append(append(append([]byte{}, header.masterSeed...), transformed...), 1))
+	if !hmac.Equal(kdbxHMAC(hmacBase[:], ^uint64(0), data[:header.size]), rest[32:64]) {
+		return nil, errKeePassPassword
+	}
+	encrypted, err := readKDBXBlocks(rest[64:], hmacBase[:])
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(append(append([]byte{}, header.masterSeed...), transformed...))
+	payload, err := header.decrypt(key[:], encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if header.compressed {
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("keepass: %w", err)
+		}
+		if payload, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("keepass: %w", err)
+		}
+	}
+	stream, document, err := readKDBXInnerHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+	return readKDBXDocument(bytes.NewReader(document), stream)
+}
+
+// readKDBXHeader reads the outer header, only KDBX 4 is supported.
+func readKDBXHeader(data []byte) (kdbxHeader, error) {
+	if len(data) < 12 || binary.LittleEndian.Uint32(data) != kdbxSignature1 ||
+		binary.LittleEndian.Uint32(data[4:]) != kdbxSignature2 {
+		return kdbxHeader{}, errKDBXFormat
+	}
+	if major, minor := binary.LittleEndian.Uint16(data[10:]), binary.LittleEndian.Uint16(data[8:]); major != 4 {
+		return kdbxHeader{}, fmt.Errorf("keepass: KDBX %d.%d is not supported, save the database as KDBX 4", major, minor)
+	}
+	header := kdbxHeader{size: 12}
+	for {
+		if len(data) < header.size+5 {
+			return kdbxHeader{}, errKDBXFormat
+		}
+		id := data[header.size]
+		size := int(binary.LittleEndian.Uint32(data[header.size+1:]))
+		header.size += 5
+		if size < 0 || len(data) < header.size+size {
+			return kdbxHeader{}, errKDBXFormat
+		}
+		value := data[header.size : header.size+size]
+		header.size += size
+		switch id {
+		case kdbxEndOfHeader:
+			if header.masterSeed == nil || header.iv == nil || header.kdf == nil {
+				return kdbxHeader{}, errKDBXFormat
+			}
+			return header, nil
+		case kdbxCipherID:
+			cipherID, err := uuid.FromBytes(value)
+			if err != nil {
+				return kdbxHeader{}, errKDBXFormat
+			}
+			header.cipher = cipherID
+		case kdbxCompressionFlags:
+			header.compressed = len(value) == 4 && binary.LittleEndian.Uint32(value) == 1
+		case kdbxMasterSeed:
+			header.masterSeed = value
+		case kdbxEncryptionIV:
+			header.iv = value
+		case kdbxKDFParameters:
+			kdf, err := readVariantDictionary(value)
+			if err != nil {
+				return kdbxHeader{}, err
+			}
+			header.kdf = kdf
+		}
+	}
+}
+
+// readVariantDictionary reads the raw values of a variant dictionary by key, the KDF parameters are one.
+func readVariantDictionary(data []byte) (map[string][]byte, error) {
+	if len(data) < 2 || data[1] != 1 {
+		return nil, errKDBXFormat
+	}
+	dict := make(map[string][]byte)
+	data = data[2:]
+	for len(data) != 0 && data[0] != 0 {
+		var parts [2][]byte
+		data = data[1:]
+		for i := range parts {
+			if len(data) < 4 {
+				return nil, errKDBXFormat
+			}
+			size := int(binary.LittleEndian.Uint32(data))
+			if size < 0 || len(data) < 4+size {
+				return nil, errKDBXFormat
+			}
+			parts[i], data = data[4:4+size], data[4+size:]
+		}
+		dict[string(parts[0])] = parts[1]
+	}
+	return dict, nil
+}
+
+// kdfUint returns the unsigned integer of the KDF parameters.
+func (h kdbxHeader) kdfUint(key string) (uint64, error) {
+	switch value := h.kdf[key]; len(value) {
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(value)), nil
+	case 8:
+		return binary.LittleEndian.Uint64(value), nil
+	}
+	return 0, fmt.Errorf("keepass: no KDF parameter %s", key)
+}
+
+// transformKey derives the key of the database from the composite key with the KDF of the header.
+func (h kdbxHeader) transformKey(composite []byte) ([]byte, error) {
+	kdf, err := uuid.FromBytes(h.kdf["$UUID"])
+	if err != nil {
+		return nil, errKDBXFormat
+	}
+	switch kdf {
+	case kdbxAESKDF, kdbxAESKDF3:
+		rounds, err := h.kdfUint("R")
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(h.kdf["S"])
+		if err != nil {
+			return nil, fmt.Errorf("keepass: %w", err)
+		}
+		key := append([]byte{}, composite...)
+		for i := uint64(0); i < rounds; i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+	case kdbxArgon2d, kdbxArgon2id:
+		iterations, err := h.kdfUint("I")
+		if err != nil {
+			return nil, err
+		}
+		memory, err := h.kdfUint("M")
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := h.kdfUint("P")
+		if err != nil {
+			return nil, err
+		}
+		if version, err := h.kdfUint("V"); err != nil || version != argon2Version {
+			return nil, errors.New("keepass: only Argon2 version 1.3 is supported")
+		}
+		if iterations < 1 || iterations > 1<<32-1 || memory > argon2MaxMemory ||
+			parallelism < 1 || parallelism > argon2MaxParallelism {
+			return nil, errKDBXFormat
+		}
+		if kdf == kdbxArgon2d {
+			return argon2dKey(composite, h.kdf["S"], h.kdf["K"], h.kdf["A"],
+				uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+		}
+		if len(h.kdf["K"]) != 0 || len(h.kdf["A"]) != 0 {
+			return nil, errors.New("keepass: Argon2id with a secret key is not supported")
+		}
+		return argon2.IDKey(composite, h.kdf["S"], uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	}
+	return nil, fmt.Errorf("keepass: KDF %s is not supported", kdf)
+}
+
+// decrypt decrypts the payload with the cipher of the header.
+func (h kdbxHeader) decrypt(key, data []byte) ([]byte, error) {
+	var block cipher.Block
+	var err error
+	switch h.cipher {
+	case kdbxChaCha20:
+		stream, err := chacha20.NewUnauthenticatedCipher(key, h.iv)
+		if err != nil {
+			return nil, fmt.Errorf("keepass: %w", err)
+		}
+		stream.XORKeyStream(data, data)
+		return data, nil
+	case kdbxAES256:
+		block, err = aes.NewCipher(key)
+	case kdbxTwofish:
+		block, err = twofish.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("keepass: cipher %s is not supported", h.cipher)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keepass: %w", err)
+	}
+	if len(h.iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errKDBXFormat
+	}
+	cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(data, data)
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, errKDBXFormat
+	}
+	return data[:len(data)-padding], nil
+}
+
+// kdbxHMAC returns the HMAC-SHA256 of the block of the index, the header is the block ^uint64(0).
+// Every block has its own key derived from the HMAC base key.
+func kdbxHMAC(base []byte, index uint64, data ...[]byte) []byte {
+	var i [8]byte
+	binary.LittleEndian.PutUint64(i[:], index)
+	key := sha512.Sum512(append(i[:], base...))
+	mac := hmac.New(sha256.New, key[:])
+	if index != ^uint64(0) {
+		mac.Write(i[:])
+	}
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// readKDBXBlocks checks the HMAC of every block of the payload and returns the encrypted payload.
+func readKDBXBlocks(data, hmacBase []byte) ([]byte, error) {
+	var payload []byte
+	for index := uint64(0); ; index++ {
+		if len(data) < 36 {
+			return nil, errKDBXFormat
+		}
+		mac, size := data[:32], data[32:36]
+		n := int(binary.LittleEndian.Uint32(size))
+		if n < 0 || len(data) < 36+n {
+			return nil, errKDBXFormat
+		}
+		block := data[36 : 36+n]
+		if !hmac.Equal(kdbxHMAC(hmacBase, index, size, block), mac) {
+			return nil, errKDBXFormat
+		}
+		if n == 0 {
+			return payload, nil
+		}
+		payload = append(payload, block...)
+		data = data[36+n:]
+	}
+}
+
+// readKDBXInnerHeader reads the inner header and returns the stream of the protected values and the XML document.
+func readKDBXInnerHeader(data []byte) (cipher.Stream, []byte, error) {
+	var streamID uint32
+	var streamKey []byte
+	for {
+		if len(data) < 5 {
+			return nil, nil, errKDBXFormat
+		}
+		id := data[0]
+		size := int(binary.LittleEndian.Uint32(data[1:]))
+		if size < 0 || len(data) < 5+size {
+			return nil, nil, errKDBXFormat
+		}
+		value := data[5 : 5+size]
+		data = data[5+size:]
+		switch id {
+		case kdbxInnerStreamID:
+			if len(value) != 4 {
+				return nil, nil, errKDBXFormat
+			}
+			streamID = binary.LittleEndian.Uint32(value)
+		case kdbxInnerStreamKey:
+			streamKey = value
+		case kdbxInnerEndOfHeader:
+			if streamID != kdbxInnerChaCha20 {
+				return nil, nil, fmt.Errorf("keepass: inner stream %d is not supported", streamID)
+			}
+			key := sha512.Sum512(streamKey)
+			stream, err := chacha20.NewUnauthenticatedCipher(key[:32], key[32:44])
+			if err != nil {
+				return nil, nil, fmt.Errorf("keepass: %w", err)
+			}
+			return stream, data, nil
+		}
+	}
+}
+
+// kdbxEntry is an entry of the XML document.
+type kdbxEntry struct {
+	fields      map[string]string
+	custom      []string
+	attachments []string
+}
+
+// The standard fields of an entry, the others are custom.
+var kdbxStandardFields = map[string]bool{"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true}
+
+// entry returns the entry as a secret: a login if it has a user name or a password, otherwise a note.
+func (e *kdbxEntry) entry() (Entry, error) {
+	title, notes := e.fields["Title"], e.fields["Notes"]
+	description := describe(title, e.fields["URL"])
+	unsupported := appendIf(nil, "URL", e.fields["URL"])
+	for _, name := range e.custom {
+		unsupported = append(unsupported, "field "+name)
+	}
+	for _, name := range e.attachments {
+		unsupported = append(unsupported, "attachment "+name)
+	}
+	switch {
+	case e.fields["UserName"] != "" || e.fields["Password"] != "":
+		return loginEntry(description, e.fields["UserName"], e.fields["Password"], appendIf(unsupported, "Notes", notes))
+	case notes != "":
+		return textEntry(description, notes, unsupported), nil
+	}
+	return unsupportedEntry(description, "entry without a login or notes"), nil
+}
+
+// readKDBXDocument reads the entries of the XML document. The protected values are decrypted with the stream
+// in the order of the document, so every protected value is decrypted, including those of the skipped entries.
+func readKDBXDocument(r io.Reader, stream cipher.Stream) ([]Entry, error) {
+	decoder := xml.NewDecoder(r)
+	var (
+		path       []string
+		recycleBin string
+		// skipDepth is the depth of the recycle bin group while its entries are read, 0 otherwise
+		skipDepth int
+		history   int
+		entry     *kdbxEntry
+		key       string
+		entries   []Entry
+	)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("keepass: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			parent, grandparent := "", ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			if len(path) > 1 {
+				grandparent = path[len(path)-2]
+			}
+			name := t.Name.Local
+			if name != "Key" && name != "Value" && name != "UUID" && name != "RecycleBinUUID" {
+				path = append(path, name)
+				switch {
+				case name == "History":
+					history++
+				case name == "Entry" && history == 0:
+					entry = &kdbxEntry{fields: make(map[string]string)}
+				}
+				continue
+			}
+			var text string
+			if err = decoder.DecodeElement(&text, &t); err != nil {
+				return nil, fmt.Errorf("keepass: %w", err)
+			}
+			if name == "Value" && isProtected(t) {
+				value, err := base64.StdEncoding.DecodeString(text)
+				if err != nil {
+					return nil, fmt.Errorf("keepass: %w", err)
+				}
+				stream.XORKeyStream(value, value)
+				text = string(value)
+			}
+			inEntry := entry != nil && history == 0 && grandparent == "Entry"
+			switch {
+			case name == "RecycleBinUUID":
+				recycleBin = text
+			case name == "UUID" && parent == "Group" && text == recycleBin && skipDepth == 0:
+				skipDepth = len(path)
+			case name == "Key":
+				key = text
+			case name == "Value" && parent == "String" && inEntry:
+				entry.fields[key] = text
+				if !kdbxStandardFields[key] {
+					entry.custom = append(entry.custom, key)
+				}
+			case name == "Value" && parent == "Binary" && inEntry:
+				entry.attachments = append(entry.attachments, key)
+			}
+		case xml.EndElement:
+			if len(path) == 0 {
+				return nil, errKDBXFormat
+			}
+			path = path[:len(path)-1]
+			if len(path) < skipDepth {
+				skipDepth = 0
+			}
+			switch {
+			case t.Name.Local == "History":
+				history--
+			case t.Name.Local == "Entry" && history == 0 && entry != nil:
+				if skipDepth == 0 {
+					e, err := entry.entry()
+					if err != nil {
+						return nil, err
+					}
+					entries = append(entries, e)
+				}
+				entry = nil
+			}
+		}
+	}
+}
+
+// isProtected reports if the value is encrypted with the inner stream.
+func isProtected(t xml.StartElement) bool {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == "Protected" && attr.Value == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package models
+
+// File is the manifest of a file secret, it is sealed as the value of the secret.
+// The content is stored on the server as the encrypted chunks of the blob the secret refers to by BlobID.
+// Every chunk but the last holds ChunkSize bytes of the file, SHA256 is the hex digest of the whole file.
+type File struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ChunkSize int    `json:"chunk_size"`
+	Chunks    int    `json:"chunks"`
+	SHA256    string `json:"sha256"`
+}
@@ -9,12 +9,17 @@ import (
 	"log"
 	"net/http"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
 )
 
-// Authorizationer interface defines two methods: "Authorization" and "Ping".
+// Authorizationer interface defines the methods: "Authorization" and "Ping",
+// and the methods completing the login with the second factor and enrolling it.
 type Authorizationer interface {
 	Authorization(user models.User) error
+	VerifyTOTP(code string) error
+	EnrollTOTP() (models.TOTPEnrollment, error)
+	ConfirmTOTP(code string) (models.RecoveryCodes, error)
 	Ping() error
 }
 
@@ -55,6 +60,9 @@ func (s *Authorization) Ping() error {
 }
 
 // Authorization sends an HTTP POST request to the /api/v1/login endpoint with a JSON-encoded user object.
+// The issued tokens are stored in the client.
+// If the user has enabled the second factor, constants.ErrMFARequired is returned
+// and the login is completed by VerifyTOTP.
 // It returns an error if the request fails or the response status code is not 200 OK.
 func (s *Authorization) Authorization(user models.User) error {
 	marshal, err := json.Marshal(user)
@@ -78,5 +86,82 @@ func (s *Authorization) Authorization(user models.User) error {
 	if post.StatusCode != http.StatusOK {
 		return fmt.Errorf(string(all))
 	}
+	var tokens models.Tokens
+	if err = json.Unmarshal(all, &tokens); err != nil {
+		return err
+	}
+	s.client.SetTokens(tokens)
+	if tokens.MFARequired {
+		return constants.ErrMFARequired
+	}
 	return nil
 }
+
+// VerifyTOTP sends an HTTP POST request to the /api/v1/2fa/verify endpoint with the code from the authenticator app
+// or a recovery code, completing the login started by Authorization. The issued tokens are stored in the client.
+func (s *Authorization) VerifyTOTP(code string) error {
+	all, err := s.post("/api/v1/2fa/verify", models.TOTPCode{Code: code})
+	if err != nil {
+		return err
+	}
+	var tokens models.Tokens
+	if err = json.Unmarshal(all, &tokens); err != nil {
+		return err
+	}
+	s.client.SetTokens(tokens)
+	return nil
+}
+
+// EnrollTOTP sends an HTTP POST request to the /api/v1/2fa/enroll endpoint and returns the secret of the new second factor.
+func (s *Authorization) EnrollTOTP() (models.TOTPEnrollment, error) {
+	all, err := s.post("/api/v1/2fa/enroll", nil)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	var enrollment models.TOTPEnrollment
+	if err = json.Unmarshal(all, &enrollment); err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTOTP sends an HTTP POST request to the /api/v1/2fa/confirm endpoint with a code from the authenticator app,
+// enabling the second factor. It returns the recovery codes.
+func (s *Authorization) ConfirmTOTP(code string) (models.RecoveryCodes, error) {
+	all, err := s.post("/api/v1/2fa/confirm", models.TOTPCode{Code: code})
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	var codes models.RecoveryCodes
+	if err = json.Unmarshal(all, &codes); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return codes, nil
+}
+
+// post sends the JSON-encoded body to the endpoint and returns the response body.
+// It returns an error with the response body if the response status code is not 200 OK.
+func (s *Authorization) post(path string, body interface{}) ([]byte, error) {
+	marshal, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.client.Post(s.address+path, "application/json", bytes.NewReader(marshal))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = post.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(post.Body)
+	if err != nil {
+		return nil, err
+	}
+	if post.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", all)
+	}
+	return all, nil
+}
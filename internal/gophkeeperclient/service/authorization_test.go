@@ -3,11 +3,13 @@ package service
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
 	mock "yudinsv/gophkeeper/mocks"
 
@@ -57,11 +59,17 @@ func TestAuthorization_Authorization(t *testing.T) {
 		t.Fatal(err)
 	}
 	mockClient := mock.NewMockClienter(ctrl)
-	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	body, err := json.Marshal(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}
 	mockClient.EXPECT().Post("http://localhost:8080/api/v1/login", "application/json", bytes.NewReader(marshal)).Return(
 		&response,
 		nil,
 	)
+	mockClient.EXPECT().SetTokens(tokens)
 	// Call the method being tested
 	authorizationer := NewAuthorizationer(mockClient, "http://localhost:8080")
 	err = authorizationer.Authorization(user)
@@ -69,3 +77,66 @@ func TestAuthorization_Authorization(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAuthorization_AuthorizationMFARequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := models.User{Login: "1", Password: "test"}
+	marshal, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted := models.Tokens{AccessToken: "restricted", ExpiresIn: 300, MFARequired: true}
+	body, err := json.Marshal(restricted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	verified, err := json.Marshal(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := json.Marshal(models.TOTPCode{Code: "123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := mock.NewMockClienter(ctrl)
+	gomock.InOrder(
+		mockClient.EXPECT().Post("http://localhost:8080/api/v1/login", "application/json", bytes.NewReader(marshal)).Return(
+			&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))},
+			nil,
+		),
+		mockClient.EXPECT().SetTokens(restricted),
+		mockClient.EXPECT().Post("http://localhost:8080/api/v1/2fa/verify", "application/json", bytes.NewReader(code)).Return(
+			&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(verified))},
+			nil,
+		),
+		mockClient.EXPECT().SetTokens(tokens),
+	)
+	authorizationer := NewAuthorizationer(mockClient, "http://localhost:8080")
+	err = authorizationer.Authorization(user)
+	if !errors.Is(err, constants.ErrMFARequired) {
+		t.Fatalf("Authorization() error = %v, want %v", err, constants.ErrMFARequired)
+	}
+	if err = authorizationer.VerifyTOTP("123456"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthorization_ConfirmTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	code, err := json.Marshal(models.TOTPCode{Code: "000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := mock.NewMockClienter(ctrl)
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/2fa/confirm", "application/json", bytes.NewReader(code)).Return(
+		&http.Response{StatusCode: http.StatusUnprocessableEntity, Body: io.NopCloser(strings.NewReader("code is not correct"))},
+		nil,
+	)
+	_, err = NewAuthorizationer(mockClient, "http://localhost:8080").ConfirmTOTP("000000")
+	if err == nil || err.Error() != "code is not correct" {
+		t.Errorf("ConfirmTOTP() error = %v", err)
+	}
+}
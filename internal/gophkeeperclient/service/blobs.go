@@ -0,0 +1,356 @@
+// Package service implementation of an interface called Blober.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// errChunkRejected the server rejected the chunk, sending it again does not help.
+var errChunkRejected = errors.New("chunk rejected")
+
+// Blober interface defines methods for transferring the content of the file secrets.
+// Upload encrypts the file chunk by chunk, uploads the chunks missing on the server and returns the blob ID
+// and the manifest to seal as the value of the secret. The blob ID is derived from the vault key and the file,
+// so uploading the same file again resumes an interrupted upload.
+// Download writes the file of the manifest to the path, resuming from the path.part file left
+// by an interrupted download. Every chunk and the whole file are verified.
+type Blober interface {
+	Upload(ctx context.Context, keyring crypter.Keyring, path string) (uuid.UUID, clientmodels.File, error)
+	Download(ctx context.Context, keyring crypter.Keyring, blobID uuid.UUID, file clientmodels.File, path string) error
+}
+
+// NewBlober creates a new Blober instance with the specified client, and address.
+func NewBlober(client Clienter, address string) Blober {
+	return NewServiceBlobs(client, address)
+}
+
+// Blobs type implements the Blober interface and has fields for client and address.
+type Blobs struct {
+	client     Clienter
+	address    string
+	chunkSize  int
+	retryDelay time.Duration
+}
+
+// NewServiceBlobs creates a new Blobs instance.
+func NewServiceBlobs(client Clienter, address string) *Blobs {
+	return &Blobs{client: client, address: address, chunkSize: constatns.ChunkSize, retryDelay: constatns.ChunkRetryDelay}
+}
+
+// Upload encrypts and uploads the file at the path.
+func (b *Blobs) Upload(ctx context.Context, keyring crypter.Keyring, path string) (uuid.UUID, clientmodels.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return uuid.Nil, clientmodels.File{}, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return uuid.Nil, clientmodels.File{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return uuid.Nil, clientmodels.File{}, fmt.Errorf("%s is not a regular file", path)
+	}
+	manifest := clientmodels.File{
+		Name:      filepath.Base(path),
+		Size:      info.Size(),
+		ChunkSize: b.chunkSize,
+		Chunks:    int((info.Size() + int64(b.chunkSize) - 1) / int64(b.chunkSize)),
+	}
+	if manifest.Chunks == 0 {
+		manifest.Chunks = 1
+	}
+	blobID, err := fileBlobID(keyring, path, info)
+	if err != nil {
+		return uuid.Nil, clientmodels.File{}, err
+	}
+	blob, err := b.createBlob(blobID, manifest.Chunks)
+	if err != nil {
+		return uuid.Nil, clientmodels.File{}, err
+	}
+	uploaded := make(map[int]bool, len(blob.Uploaded))
+	for _, index := range blob.Uploaded {
+		uploaded[index] = true
+	}
+	digest := sha256.New()
+	data := make([]byte, b.chunkSize)
+	for index := 0; index < manifest.Chunks; index++ {
+		if err = ctx.Err(); err != nil {
+			return uuid.Nil, clientmodels.File{}, err
+		}
+		n, err := io.ReadFull(file, data)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return uuid.Nil, clientmodels.File{}, err
+		}
+		digest.Write(data[:n])
+		if uploaded[index] || blob.Complete {
+			continue
+		}
+		sealed, err := keyring.SealChunk(blobID, index, manifest.Chunks, data[:n])
+		if err != nil {
+			return uuid.Nil, clientmodels.File{}, err
+		}
+		if err = b.retry(ctx, func() error { return b.putChunk(blobID, index, sealed) }); err != nil {
+			return uuid.Nil, clientmodels.File{}, err
+		}
+	}
+	manifest.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	if !blob.Complete {
+		if err = b.completeBlob(blobID); err != nil {
+			return uuid.Nil, clientmodels.File{}, err
+		}
+	}
+	return blobID, manifest, nil
+}
+
+// Download downloads and decrypts the file of the manifest to the path.
+func (b *Blobs) Download(ctx context.Context, keyring crypter.Keyring, blobID uuid.UUID, manifest clientmodels.File, path string) error {
+	if manifest.ChunkSize <= 0 || manifest.Chunks <= 0 {
+		return errors.New("invalid file manifest")
+	}
+	partPath := path + ".part"
+	part, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := part.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			log.Println(err)
+		}
+	}()
+	digest, next, err := resumePart(part, manifest)
+	if err != nil {
+		return err
+	}
+	for index := next; index < manifest.Chunks; index++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		var sealed []byte
+		err = b.retry(ctx, func() error {
+			var err error
+			sealed, err = b.getChunk(blobID, index)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		data, err := keyring.OpenChunk(blobID, index, manifest.Chunks, sealed)
+		if err != nil {
+			return fmt.Errorf("chunk %d: %w", index, err)
+		}
+		if index < manifest.Chunks-1 && len(data) != manifest.ChunkSize {
+			return fmt.Errorf("chunk %d has unexpected size", index)
+		}
+		if _, err = part.Write(data); err != nil {
+			return err
+		}
+		digest.Write(data)
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != manifest.SHA256 {
+		if err = os.Remove(partPath); err != nil {
+			log.Println(err)
+		}
+		return errors.New("downloaded file does not match the manifest")
+	}
+	if err = part.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partPath, path)
+}
+
+// resumePart keeps the whole chunks already written to the part file and returns the digest of them
+// and the index of the next chunk.
+func resumePart(part *os.File, manifest clientmodels.File) (hash.Hash, int, error) {
+	info, err := part.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	next := int(info.Size() / int64(manifest.ChunkSize))
+	if next >= manifest.Chunks {
+		next = 0
+	}
+	written := int64(next) * int64(manifest.ChunkSize)
+	if err = part.Truncate(written); err != nil {
+		return nil, 0, err
+	}
+	digest := sha256.New()
+	if _, err = part.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	if _, err = io.CopyN(digest, part, written); err != nil {
+		return nil, 0, err
+	}
+	return digest, next, nil
+}
+
+// retry calls the transfer until it succeeds, the server rejects the chunk or the attempts run out.
+func (b *Blobs) retry(ctx context.Context, transfer func() error) error {
+	var err error
+	for attempt := 1; attempt <= constatns.ChunkRetries; attempt++ {
+		if err = transfer(); err == nil || errors.Is(err, errChunkRejected) {
+			return err
+		}
+		log.Println(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.retryDelay * time.Duration(attempt)):
+		}
+	}
+	return err
+}
+
+// createBlob sends an HTTP POST request to the /api/v1/blobs endpoint and returns the status of the blob.
+func (b *Blobs) createBlob(blobID uuid.UUID, chunks int) (models.Blob, error) {
+	marshal, err := json.Marshal(models.Blob{ID: blobID, Chunks: chunks})
+	if err != nil {
+		return models.Blob{}, err
+	}
+	post, err := b.client.Post(b.address+"/api/v1/blobs", "application/json", bytes.NewBuffer(marshal))
+	if err != nil {
+		return models.Blob{}, err
+	}
+	defer func() {
+		err = post.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(post.Body)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	if post.StatusCode != http.StatusOK {
+		return models.Blob{}, fmt.Errorf("create blob failed %s", all)
+	}
+	var blob models.Blob
+	if err = json.Unmarshal(all, &blob); err != nil {
+		return models.Blob{}, err
+	}
+	return blob, nil
+}
+
+// putChunk sends an HTTP PUT request with the sealed chunk and its digest.
+func (b *Blobs) putChunk(blobID uuid.UUID, index int, sealed []byte) error {
+	sum := sha256.Sum256(sealed)
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set(constants.ChunkHashHeader, hex.EncodeToString(sum[:]))
+	resp, err := b.client.PutWithHeader(b.chunkURL(blobID, index), header, bytes.NewReader(sealed))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = resp.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusUnprocessableEntity {
+		return fmt.Errorf("put chunk %d failed: %w %s", index, errChunkRejected, all)
+	}
+	return fmt.Errorf("put chunk %d failed %s", index, all)
+}
+
+// getChunk sends an HTTP GET request for the sealed chunk and verifies it against its digest.
+func (b *Blobs) getChunk(blobID uuid.UUID, index int) ([]byte, error) {
+	get, err := b.client.Get(b.chunkURL(blobID, index))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = get.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(get.Body)
+	if err != nil {
+		return nil, err
+	}
+	if get.StatusCode != http.StatusOK {
+		if get.StatusCode < http.StatusInternalServerError {
+			return nil, fmt.Errorf("get chunk %d failed: %w %s", index, errChunkRejected, all)
+		}
+		return nil, fmt.Errorf("get chunk %d failed %s", index, all)
+	}
+	sum := sha256.Sum256(all)
+	if hex.EncodeToString(sum[:]) != get.Header.Get(constants.ChunkHashHeader) {
+		return nil, fmt.Errorf("chunk %d does not match its digest", index)
+	}
+	return all, nil
+}
+
+// completeBlob sends an HTTP POST request to the /api/v1/blobs/:id/complete endpoint.
+func (b *Blobs) completeBlob(blobID uuid.UUID) error {
+	post, err := b.client.Post(b.address+"/api/v1/blobs/"+blobID.String()+"/complete", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = post.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	if post.StatusCode != http.StatusNoContent {
+		all, err := io.ReadAll(post.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("complete blob failed %s", all)
+	}
+	return nil
+}
+
+// chunkURL returns the address of the chunk.
+func (b *Blobs) chunkURL(blobID uuid.UUID, index int) string {
+	return b.address + "/api/v1/blobs/" + blobID.String() + "/chunks/" + strconv.Itoa(index)
+}
+
+// fileBlobID derives the blob ID of the file from the vault key, its path, size and modification time.
+// The ID is keyed, so the server cannot link it to the file.
+func fileBlobID(keyring crypter.Keyring, path string, info os.FileInfo) (uuid.UUID, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mac, err := keyring.MAC([]byte(fmt.Sprintf("blob:%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.NewHash(sha256.New(), uuid.Nil, mac, 8), nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// blobServer is an in-memory blob API, failing is the index of a chunk answered with 503.
+type blobServer struct {
+	mu       sync.Mutex
+	blob     models.Blob
+	chunks   map[int][]byte
+	puts     int
+	gets     int
+	failing  int
+	complete bool
+}
+
+func (s *blobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/blobs"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 1:
+		var blob models.Blob
+		_ = json.NewDecoder(r.Body).Decode(&blob)
+		if s.blob.ID == uuid.Nil {
+			s.blob = blob
+		}
+		status := s.blob
+		status.Complete = s.complete
+		status.Uploaded = []int{}
+		for index := range s.chunks {
+			status.Uploaded = append(status.Uploaded, index)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	case r.Method == http.MethodPost && parts[2] == "complete":
+		if len(s.chunks) != s.blob.Chunks {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.complete = true
+		w.WriteHeader(http.StatusNoContent)
+	case parts[2] == "chunks":
+		index, _ := strconv.Atoi(parts[3])
+		if index == s.failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method == http.MethodGet {
+			s.gets++
+			sum := sha256.Sum256(s.chunks[index])
+			w.Header().Set(constants.ChunkHashHeader, hex.EncodeToString(sum[:]))
+			_, _ = w.Write(s.chunks[index])
+			return
+		}
+		s.puts++
+		data, _ := io.ReadAll(r.Body)
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != r.Header.Get(constants.ChunkHashHeader) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		s.chunks[index] = data
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestBlobs_UploadDownload(t *testing.T) {
+	fake := &blobServer{chunks: make(map[int][]byte), failing: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	blobs := NewServiceBlobs(&MyClient{}, server.URL)
+	blobs.chunkSize = 16
+	blobs.retryDelay = 0
+
+	key := make(crypter.Key, 32)
+	content := make([]byte, 70)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The upload stops at the failing chunk and is resumed
+	_, _, err := blobs.Upload(ctx, key, path)
+	assert.Error(t, err)
+	assert.Len(t, fake.chunks, 2)
+	fake.failing = -1
+	fake.puts = 0
+	blobID, manifest, err := blobs.Upload(ctx, key, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fake.blob.ID, blobID)
+	assert.Equal(t, 3, fake.puts, "the uploaded chunks are not sent again")
+	assert.True(t, fake.complete)
+	sum := sha256.Sum256(content)
+	assert.Equal(t, "photo.jpg", manifest.Name)
+	assert.Equal(t, int64(len(content)), manifest.Size)
+	assert.Equal(t, 5, manifest.Chunks)
+	assert.Equal(t, hex.EncodeToString(sum[:]), manifest.SHA256)
+	for _, sealed := range fake.chunks {
+		assert.NotContains(t, string(sealed), string(content[:16]), "the chunks are encrypted")
+	}
+
+	// The download stops at the failing chunk and is resumed from the part file
+	target := filepath.Join(dir, "saved.jpg")
+	fake.failing = 3
+	assert.Error(t, blobs.Download(ctx, key, blobID, manifest, target))
+	_, err = os.Stat(target)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	fake.failing = -1
+	fake.gets = 0
+	if err = blobs.Download(ctx, key, blobID, manifest, target); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, fake.gets, "only the chunks not written are downloaded again")
+	saved, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, saved)
+	_, err = os.Stat(target + ".part")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// A tampered chunk and another key are detected
+	fake.chunks[1][len(fake.chunks[1])-1] ^= 1
+	assert.Error(t, blobs.Download(ctx, key, blobID, manifest, filepath.Join(dir, "tampered.jpg")))
+	fake.chunks[1][len(fake.chunks[1])-1] ^= 1
+	otherKey := make(crypter.Key, 32)
+	assert.Error(t, blobs.Download(ctx, otherKey, blobID, manifest, filepath.Join(dir, "other.jpg")))
+}
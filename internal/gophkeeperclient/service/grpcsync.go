@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+	pb "yudinsv/gophkeeper/internal/proto"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// NewGRPCSyncer creates a Syncer exchanging the secrets with the gRPC API over the connection.
+// The calls carry the access token of the client, which is refreshed with the REST API at address
+// as for the REST requests. The history of the secrets is requested with the REST API as well.
+func NewGRPCSyncer(storage keeperstorage.KeeperStorage, client *MyClient, conn grpc.ClientConnInterface, address string) Syncer {
+	sync := NewSync(storage, client, address)
+	sync.remote = &grpcRemote{keeper: pb.NewKeeperClient(conn), client: client, address: address}
+	return sync
+}
+
+// DialGRPC connects to the gRPC API of the server, with TLS if tlsConfig is set.
+func DialGRPC(address string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return grpc.Dial(address, grpc.WithTransportCredentials(creds))
+}
+
+// grpcRemote exchanges the secrets with the gRPC API of the server.
+type grpcRemote struct {
+	keeper  pb.KeeperClient
+	client  *MyClient
+	address string
+}
+
+func (r *grpcRemote) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), constatns.TimeOutSync)
+	defer cancel()
+	if _, err := r.keeper.Ping(ctx, &emptypb.Empty{}); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// changes receives the change feed after the cursor, the batches of the feed are merged.
+func (r *grpcRemote) changes(cursor int64) (models.Changes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), constatns.TimeOutSync)
+	defer cancel()
+	var changes models.Changes
+	err := r.call(ctx, func(ctx context.Context) error {
+		stream, err := r.keeper.Changes(ctx, &pb.ChangesRequest{Since: cursor})
+		if err != nil {
+			return err
+		}
+		changes = models.Changes{Cursor: cursor}
+		for {
+			batch, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			page, err := pb.ToChanges(batch)
+			if err != nil {
+				return err
+			}
+			changes.Secrets = append(changes.Secrets, page.Secrets...)
+			changes.Cursor = page.Cursor
+		}
+	})
+	if err != nil {
+		return models.Changes{}, grpcError("sync", err)
+	}
+	return changes, nil
+}
+
+func (r *grpcRemote) put(ctx context.Context, secret models.Secret) (int64, error) {
+	var revision *pb.Revision
+	err := r.call(ctx, func(ctx context.Context) (err error) {
+		revision, err = r.keeper.PutSecret(ctx, pb.FromSecret(secret))
+		return err
+	})
+	if err != nil {
+		return 0, grpcError("put secret", err)
+	}
+	return revision.GetRevision(), nil
+}
+
+func (r *grpcRemote) get(ctx context.Context, secretID uuid.UUID) (models.Secret, error) {
+	var message *pb.Secret
+	err := r.call(ctx, func(ctx context.Context) (err error) {
+		message, err = r.keeper.GetSecret(ctx, &pb.SecretID{Id: secretID.String()})
+		return err
+	})
+	if err != nil {
+		return models.Secret{}, grpcError("get secret", err)
+	}
+	return pb.ToSecret(message, "")
+}
+
+func (r *grpcRemote) delete(ctx context.Context, secret models.Secret) (int64, error) {
+	var revision *pb.Revision
+	err := r.call(ctx, func(ctx context.Context) (err error) {
+		revision, err = r.keeper.DeleteSecret(ctx, &pb.DeleteRequest{Id: secret.ID.String(), Revision: secret.Revision})
+		return err
+	})
+	if err != nil {
+		return 0, grpcError("delete secret", err)
+	}
+	return revision.GetRevision(), nil
+}
+
+// call makes the call with the current access token and repeats it once after refreshing the tokens
+// if the server rejects the access token, like MyClient does for the REST requests.
+func (r *grpcRemote) call(ctx context.Context, f func(ctx context.Context) error) error {
+	accessToken := r.client.currentAccessToken()
+	err := f(r.outgoing(ctx, accessToken))
+	if status.Code(err) != codes.Unauthenticated {
+		return err
+	}
+	if refreshErr := r.client.refresh(r.address, accessToken); refreshErr != nil {
+		log.Println(refreshErr)
+		return err
+	}
+	return f(r.outgoing(ctx, r.client.currentAccessToken()))
+}
+
+// outgoing adds the access token and the device name to the metadata of the call.
+func (r *grpcRemote) outgoing(ctx context.Context, accessToken string) context.Context {
+	if r.client.DeviceName != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, constants.DeviceNameHeader, r.client.DeviceName)
+	}
+	if accessToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+accessToken)
+	}
+	return ctx
+}
+
+// grpcError wraps the error of the call, NOT_FOUND and ABORTED are reported as the errors of the REST requests.
+func grpcError(operation string, err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%s failed: %w", operation, constants.ErrSecretNotFound)
+	case codes.Aborted:
+		return fmt.Errorf("%s failed: %w", operation, constants.ErrRevisionConflict)
+	default:
+		return fmt.Errorf("%s failed: %w", operation, err)
+	}
+}
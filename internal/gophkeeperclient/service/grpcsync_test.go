@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/api/handlers"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	keepermemstorage "yudinsv/gophkeeper/internal/keeperstorage/memstorage"
+	"yudinsv/gophkeeper/internal/models"
+	pb "yudinsv/gophkeeper/internal/proto"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCSync(t *testing.T) {
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	require.NoError(t, err)
+	serverStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	require.NoError(t, err)
+	require.NoError(t, container.BuildContainer(cfg, userStorage, serverStorage))
+	listener := bufconn.Listen(1 << 20)
+	srv := handlers.GRPCServer()
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Stop()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	tokens, err := pb.NewKeeperClient(conn).Register(ctx, &pb.Credentials{Login: "user", Password: "password"})
+	require.NoError(t, err)
+	// The expired access token is refreshed with the REST API.
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != refreshPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pb.ToTokens(tokens))
+	}))
+	defer rest.Close()
+	client := NewMyClient("laptop", nil)
+	client.SetTokens(models.Tokens{AccessToken: "expired", RefreshToken: "refresh"})
+
+	local := keepermemstorage.NewMemoryStorage(10)
+	syncer := NewGRPCSyncer(local, client, conn, rest.URL).(*Sync)
+	syncer.clientID = "user"
+	require.NoError(t, syncer.Ping())
+
+	pushed := models.Secret{ID: uuid.New(), OwnerID: "user", Value: []byte("local"), Description: "card", Ver: time.Now()}
+	require.NoError(t, local.PutSecret(ctx, pushed))
+	pulled := models.Secret{ID: uuid.New(), OwnerID: "user", Value: []byte("server"), Ver: time.Now()}
+	_, err = serverStorage.CompareAndPutSecret(ctx, pulled, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, syncer.Sync())
+	assert.Equal(t, pb.ToTokens(tokens).AccessToken, client.currentAccessToken())
+	onServer, err := serverStorage.GetSecret(ctx, "user", pushed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("local"), onServer.Value)
+	onClient, err := local.GetSecret(ctx, "user", pulled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("server"), onClient.Value)
+	assert.Equal(t, int64(1), onClient.Revision)
+
+	require.NoError(t, syncer.DeleteService(ctx, pulled.ID))
+	err = syncer.DeleteService(ctx, uuid.New())
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+	err = syncer.GetService(ctx, uuid.New())
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+}
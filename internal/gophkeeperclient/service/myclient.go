@@ -1,71 +1,219 @@
 // Package service The Clienter interface and MyClient struct are used for making HTTP requests.
-// The MyClient struct implements the Clienter interface and provides the implementation for the Get, Post, and Put methods.
+// The MyClient struct implements the Clienter interface and provides the implementation for the Get, Post, Put and Delete methods.
 package service
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
 )
 
-// Clienter interface defines methods: Get and Post, and Put.
+// refreshPath is the endpoint exchanging a refresh token for a new pair of tokens.
+const refreshPath = "/api/v1/token/refresh"
+
+// Clienter interface defines methods: Get and Post, Put and Delete, PutWithHeader and DeleteWithHeader sending extra headers,
+// SetTokens storing the tokens issued on login and Tokens returning the current ones.
 type Clienter interface {
 	Get(url string) (resp *http.Response, err error)
 	Post(url string, contentType string, body io.Reader) (resp *http.Response, err error)
 	Put(url string, contentType string, body io.Reader) (resp *http.Response, err error)
+	Delete(url string, contentType string, body io.Reader) (resp *http.Response, err error)
+	PutWithHeader(url string, header http.Header, body io.Reader) (resp *http.Response, err error)
+	DeleteWithHeader(url string, header http.Header, body io.Reader) (resp *http.Response, err error)
+	SetTokens(tokens models.Tokens)
+	Tokens() models.Tokens
 }
 
-// MyClient struct implements the Clienter interface and provides the implementation for the Get, Post, and Put methods.
+// MyClient struct implements the Clienter interface and provides the implementation for the Get, Post, Put and Delete methods.
+// Every request carries the access token. When the server answers 401 the client exchanges the refresh token
+// for a new pair once and repeats the request, so an expired access token is not noticed by the caller.
+//
+// DeviceName is sent with every request, the server records it in the session created on login.
 type MyClient struct {
-	client http.Client
-	Jwt    string
+	DeviceName   string
+	client       http.Client
+	mu           sync.Mutex
+	refreshMu    sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+// SetTokens stores the access and refresh tokens used by the following requests.
+func (c *MyClient) SetTokens(tokens models.Tokens) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = tokens.AccessToken
+	c.refreshToken = tokens.RefreshToken
+}
+
+// Tokens returns the current tokens, they change when the client refreshes them.
+func (c *MyClient) Tokens() models.Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return models.Tokens{AccessToken: c.accessToken, RefreshToken: c.refreshToken}
 }
 
 // Get method creates a new GET request with the specified URL and sends it using the http.Client client.
-// The Authorization header is set to the JWT token stored in the MyClient struct.
 // It returns the HTTP response and an error if any.
 func (c *MyClient) Get(url string) (resp *http.Response, err error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Authorization", c.Jwt)
-	return c.client.Do(req)
+	return c.do(http.MethodGet, url, "", nil, nil)
 }
 
 // Post method creates a new POST request with the specified URL, content type, and request body,
 // and sends it using the http.Client client.
-// The Authorization header is set to the JWT token stored in the MyClient struct.
-// If the response contains an Authorization header, the JWT token is updated accordingly.
 // It returns the HTTP response and an error if any.
 func (c *MyClient) Post(url string, contentType string, body io.Reader) (resp *http.Response, err error) {
-	req, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Authorization", c.Jwt)
-	do, err := c.client.Do(req)
-	if do.Header.Get("Authorization") != "" {
-		c.Jwt = do.Header.Get("Authorization")
-	}
-	return do, err
+	return c.do(http.MethodPost, url, contentType, nil, body)
 }
 
 // Put  method creates a new PUT request with the specified URL, content type, and request body,
 // and sends it using the http.Client client.
-// The Authorization header is set to the JWT token stored in the MyClient struct.
-// If the response contains an Authorization header, the JWT token is updated accordingly.
 // It returns the HTTP response and an error if any.
 func (c *MyClient) Put(url string, contentType string, body io.Reader) (resp *http.Response, err error) {
-	req, err := http.NewRequest(http.MethodPut, url, body)
+	return c.do(http.MethodPut, url, contentType, nil, body)
+}
+
+// Delete method creates a new DELETE request with the specified URL, content type, and request body,
+// and sends it using the http.Client client.
+// It returns the HTTP response and an error if any.
+func (c *MyClient) Delete(url string, contentType string, body io.Reader) (resp *http.Response, err error) {
+	return c.do(http.MethodDelete, url, contentType, nil, body)
+}
+
+// PutWithHeader method creates a new PUT request with the specified URL, headers, and request body,
+// and sends it using the http.Client client. The Content-Type is taken from the headers.
+// It returns the HTTP response and an error if any.
+func (c *MyClient) PutWithHeader(url string, header http.Header, body io.Reader) (resp *http.Response, err error) {
+	return c.do(http.MethodPut, url, "", header, body)
+}
+
+// DeleteWithHeader method creates a new DELETE request with the specified URL, headers, and request body,
+// and sends it using the http.Client client. The Content-Type is taken from the headers.
+// It returns the HTTP response and an error if any.
+func (c *MyClient) DeleteWithHeader(url string, header http.Header, body io.Reader) (resp *http.Response, err error) {
+	return c.do(http.MethodDelete, url, "", header, body)
+}
+
+// do sends the request with the current access token and repeats it once after refreshing the tokens
+// if the server rejects the access token. The body is buffered so it can be sent twice.
+func (c *MyClient) do(method string, address string, contentType string, header http.Header, body io.Reader) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	accessToken := c.currentAccessToken()
+	resp, err := c.send(method, address, contentType, header, payload, accessToken)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !refreshable(address) {
+		return resp, err
+	}
+	if err = c.refresh(address, accessToken); err != nil {
+		log.Println(err)
+		return resp, nil
+	}
+	if err = resp.Body.Close(); err != nil {
+		log.Println(err)
+	}
+	return c.send(method, address, contentType, header, payload, c.currentAccessToken())
+}
+
+// send creates and sends one request with the given access token.
+func (c *MyClient) send(method string, address string, contentType string, header http.Header, payload []byte, accessToken string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, address, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Authorization", c.Jwt)
-	do, err := c.client.Do(req)
-	if do.Header.Get("Authorization") != "" {
-		c.Jwt = do.Header.Get("Authorization")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.DeviceName != "" {
+		req.Header.Set(constants.DeviceNameHeader, c.DeviceName)
 	}
-	return do, err
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return c.client.Do(req)
+}
+
+// refresh exchanges the refresh token for a new pair of tokens.
+// Concurrent requests rejected with the same access token refresh it only once:
+// a request that finds the token already replaced just retries with the new one.
+func (c *MyClient) refresh(address string, staleAccessToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.mu.Lock()
+	accessToken, refreshToken := c.accessToken, c.refreshToken
+	c.mu.Unlock()
+	if accessToken != staleAccessToken {
+		return nil
+	}
+	if refreshToken == "" {
+		return fmt.Errorf("no refresh token")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	marshal, err := json.Marshal(models.Tokens{RefreshToken: refreshToken})
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(http.MethodPost, u.Scheme+"://"+u.Host+refreshPath, "application/json", nil, marshal, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = resp.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// The refresh token is no longer valid, the user has to log in again.
+		c.SetTokens(models.Tokens{})
+		return fmt.Errorf("refresh token failed %s", all)
+	}
+	var tokens models.Tokens
+	if err = json.Unmarshal(all, &tokens); err != nil {
+		return err
+	}
+	c.SetTokens(tokens)
+	return nil
+}
+
+// currentAccessToken returns the access token sent with the next request.
+func (c *MyClient) currentAccessToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accessToken
+}
+
+// refreshable reports whether a 401 answer to the address means the access token has to be refreshed.
+// The login, register, second factor verification and refresh endpoints answer 401 for wrong credentials instead.
+func refreshable(address string) bool {
+	return !strings.Contains(address, "/login") && !strings.Contains(address, "/register") &&
+		!strings.Contains(address, "/2fa/verify") && !strings.Contains(address, refreshPath)
 }
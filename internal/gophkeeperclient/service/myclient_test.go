@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyClient_RefreshOnUnauthorized(t *testing.T) {
+	var refreshes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == refreshPath {
+			atomic.AddInt32(&refreshes, 1)
+			var request models.Tokens
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken != "refresh-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(models.Tokens{AccessToken: "access-2", RefreshToken: "refresh-2"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &MyClient{}
+	client.SetTokens(models.Tokens{AccessToken: "access-1", RefreshToken: "refresh-1"})
+	resp, err := client.Put(server.URL+"/api/v1/", "application/json", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "payload", string(body), "the body has to be sent again after refreshing")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+
+	resp, err = client.Get(server.URL + "/api/v1/sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshes), "the new access token has to be reused")
+}
+
+func TestMyClient_NoRefreshOnLogin(t *testing.T) {
+	var refreshes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == refreshPath {
+			atomic.AddInt32(&refreshes, 1)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := &MyClient{}
+	client.SetTokens(models.Tokens{AccessToken: "access", RefreshToken: "refresh"})
+	resp, err := client.Post(server.URL+"/api/v1/login", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&refreshes))
+}
@@ -59,7 +59,7 @@ func (s *Register) Ping() error {
 
 // Register sends an HTTP POST request with a JSON-encoded models.User object to the endpoint /api/v1/register.
 // If the response status code is not 200, it returns an error with the response body as the error message.
-// Otherwise, it returns nil.
+// Otherwise, it stores the tokens issued for the new user in the client and returns nil.
 func (s *Register) Register(user models.User) error {
 	marshal, err := json.Marshal(user)
 	if err != nil {
@@ -82,5 +82,10 @@ func (s *Register) Register(user models.User) error {
 	if post.StatusCode != http.StatusOK {
 		return fmt.Errorf(string(all))
 	}
+	var tokens models.Tokens
+	if err = json.Unmarshal(all, &tokens); err != nil {
+		return err
+	}
+	s.client.SetTokens(tokens)
 	return nil
 }
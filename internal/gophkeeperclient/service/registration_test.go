@@ -57,11 +57,17 @@ func TestRegister_Register(t *testing.T) {
 		t.Fatal(err)
 	}
 	mockClient := mock.NewMockClienter(ctrl)
-	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	body, err := json.Marshal(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}
 	mockClient.EXPECT().Post("http://localhost:8080/api/v1/register", "application/json", bytes.NewReader(marshal)).Return(
 		&response,
 		nil,
 	)
+	mockClient.EXPECT().SetTokens(tokens)
 	// Call the method being tested
 	registrationer := NewRegistrationer(mockClient, "http://localhost:8080")
 	err = registrationer.Register(user)
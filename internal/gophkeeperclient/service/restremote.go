@@ -0,0 +1,175 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// restRemote exchanges the secrets with the REST API of the server.
+type restRemote struct {
+	client  Clienter
+	address string
+}
+
+// ping sends a GET request to the server to check connectivity.
+// If the response is not 200 OK, an error is returned.
+func (r *restRemote) ping() error {
+	get, err := r.client.Get(r.address + "/ping")
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(get.Body)
+	if get.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping failed")
+	}
+	return nil
+}
+
+// changes sends a GET request to the server to get the secrets changed after the cursor.
+func (r *restRemote) changes(cursor int64) (models.Changes, error) {
+	get, err := r.client.Get(fmt.Sprintf("%s/api/v1/changes?since=%d", r.address, cursor))
+	if err != nil {
+		return models.Changes{}, err
+	}
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(get.Body)
+	all, err := io.ReadAll(get.Body)
+	if err != nil {
+		return models.Changes{}, err
+	}
+	if get.StatusCode != http.StatusOK {
+		return models.Changes{}, fmt.Errorf("sync failed %s", all)
+	}
+	var changes models.Changes
+	if err = json.Unmarshal(all, &changes); err != nil {
+		return models.Changes{}, err
+	}
+	return changes, nil
+}
+
+// put marshals the secret into JSON and sends it to the server with a PUT request.
+func (r *restRemote) put(_ context.Context, secret models.Secret) (int64, error) {
+	marshal, err := json.Marshal(secret)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Put(r.address+"/api/v1/", "application/json", bytes.NewBuffer(marshal))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = resp.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return 0, fmt.Errorf("put secret failed: %w", constants.ErrRevisionConflict)
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("put secret failed: %w", constants.ErrSecretNotFound)
+	default:
+		return 0, fmt.Errorf("put secret failed %s", all)
+	}
+	var revision models.Revision
+	if err = json.Unmarshal(all, &revision); err != nil {
+		return 0, err
+	}
+	return revision.Revision, nil
+}
+
+// get sends a POST request to the server with a JSON payload containing the ID of the secret to retrieve.
+func (r *restRemote) get(_ context.Context, secretID uuid.UUID) (models.Secret, error) {
+	marshal, err := json.Marshal(secretID)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	post, err := r.client.Post(r.address+"/api/v1/", "application/json", bytes.NewBuffer(marshal))
+	if err != nil {
+		return models.Secret{}, err
+	}
+	defer func() {
+		err = post.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(post.Body)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	if post.StatusCode != http.StatusOK {
+		return models.Secret{}, fmt.Errorf("get secret failed %s", all)
+	}
+	var secret models.Secret
+	if err = json.Unmarshal(all, &secret); err != nil {
+		return models.Secret{}, err
+	}
+	return secret, nil
+}
+
+// delete sends a DELETE request to the server with a JSON payload containing the ID of the secret to delete
+// and the revision the tombstone was deleted from in the If-Match header,
+// and returns the revision of the tombstone on the server from the ETag header.
+func (r *restRemote) delete(_ context.Context, secret models.Secret) (int64, error) {
+	marshal, err := json.Marshal(secret.ID)
+	if err != nil {
+		return 0, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("If-Match", strconv.Quote(strconv.FormatInt(secret.Revision, 10)))
+	resp, err := r.client.DeleteWithHeader(r.address+"/api/v1/", header, bytes.NewBuffer(marshal))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = resp.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+	case http.StatusPreconditionFailed:
+		return 0, fmt.Errorf("delete secret failed: %w", constants.ErrRevisionConflict)
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("delete secret failed: %w", constants.ErrSecretNotFound)
+	default:
+		return 0, fmt.Errorf("delete secret failed %s", all)
+	}
+	tag, err := strconv.Unquote(resp.Header.Get("ETag"))
+	if err != nil {
+		return 0, fmt.Errorf("delete secret failed: invalid ETag %s", resp.Header.Get("ETag"))
+	}
+	return strconv.ParseInt(tag, 10, 64)
+}
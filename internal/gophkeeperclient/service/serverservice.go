@@ -4,4 +4,7 @@ type ClientService struct {
 	AuthService     Authorizationer
 	RegistryService Registrationer
 	SyncService     Syncer
+	VaultService    Vaulter
+	SessionService  Sessioner
+	BlobService     Blober
 }
@@ -0,0 +1,119 @@
+// Package service implementation of an interface called Sessioner.
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// Sessioner interface defines methods for managing the login sessions of the user on the server.
+type Sessioner interface {
+	List() ([]models.Session, error)
+	Logout() error
+	Revoke(sessionID string) error
+	RevokeOthers() error
+}
+
+// NewSessioner creates a new Sessioner instance with the specified client, and address.
+func NewSessioner(client Clienter, address string) Sessioner {
+	return NewServiceSessions(client, address)
+}
+
+// Sessions type implements the Sessioner interface and has fields for client and address.
+type Sessions struct {
+	client  Clienter
+	address string
+}
+
+// NewServiceSessions creates a new Sessions instance.
+func NewServiceSessions(client Clienter, address string) *Sessions {
+	return &Sessions{client: client, address: address}
+}
+
+// List sends an HTTP GET request to the /api/v1/sessions endpoint and returns the sessions of the user.
+func (s *Sessions) List() ([]models.Session, error) {
+	get, err := s.client.Get(s.address + "/api/v1/sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = get.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(get.Body)
+	if err != nil {
+		return nil, err
+	}
+	if get.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list sessions failed %s", all)
+	}
+	var sessions []models.Session
+	if err = json.Unmarshal(all, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Logout sends an HTTP POST request to the /api/v1/logout endpoint revoking the current session
+// and forgets the tokens of the client.
+func (s *Sessions) Logout() error {
+	post, err := s.client.Post(s.address+"/api/v1/logout", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	if err = s.check(post, "logout"); err != nil {
+		return err
+	}
+	s.client.SetTokens(models.Tokens{})
+	return nil
+}
+
+// Revoke sends an HTTP DELETE request to the /api/v1/sessions/:id endpoint revoking the session.
+// If the user has no such session, constants.ErrSessionNotFound is returned.
+func (s *Sessions) Revoke(sessionID string) error {
+	del, err := s.client.Delete(s.address+"/api/v1/sessions/"+sessionID, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	return s.check(del, "revoke session")
+}
+
+// RevokeOthers sends an HTTP POST request to the /api/v1/sessions/revoke-others endpoint
+// revoking all the sessions of the user except the current one.
+func (s *Sessions) RevokeOthers() error {
+	post, err := s.client.Post(s.address+"/api/v1/sessions/revoke-others", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	return s.check(post, "revoke sessions")
+}
+
+// check closes the response body and returns an error if the response status code is not 200 OK.
+func (s *Sessions) check(resp *http.Response, action string) error {
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%s failed: %w", action, constants.ErrSessionNotFound)
+	default:
+		return fmt.Errorf("%s failed %s", action, all)
+	}
+}
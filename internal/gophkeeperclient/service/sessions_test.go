@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+	mock "yudinsv/gophkeeper/mocks"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestSessions_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sessions := []models.Session{{ID: "1", Device: "laptop", Current: true}, {ID: "2", Device: "phone"}}
+	marshal, err := json.Marshal(sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/sessions").Return(&response, nil)
+
+	got, err := NewSessioner(mockClient, "http://localhost:8080").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Device != "laptop" || !got[0].Current {
+		t.Errorf("List() = %v, want %v", got, sessions)
+	}
+}
+
+func TestSessions_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/logout", "application/json", nil).Return(&response, nil)
+	mockClient.EXPECT().SetTokens(models.Tokens{})
+
+	if err := NewSessioner(mockClient, "http://localhost:8080").Logout(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessions_RevokeNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("session not found"))}
+	mockClient.EXPECT().Delete("http://localhost:8080/api/v1/sessions/2", "application/json", nil).Return(&response, nil)
+
+	err := NewSessioner(mockClient, "http://localhost:8080").Revoke("2")
+	if !errors.Is(err, constants.ErrSessionNotFound) {
+		t.Errorf("Revoke() error = %v, want %v", err, constants.ErrSessionNotFound)
+	}
+}
@@ -2,18 +2,25 @@
 package service
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/conflict"
 	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
 	"yudinsv/gophkeeper/internal/keeperstorage"
 	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
 
 	"github.com/google/uuid"
 	"github.com/pterm/pterm"
@@ -21,13 +28,21 @@ import (
 
 // Syncer interface has several methods, including Sync() for syncing secrets,
 // Ping() for checking connectivity, StartSync() for starting the synchronization process,
-// PutService() for sending a secret to the server, and GetService() for receiving a secret from the server.
+// SetClientID() for selecting the user synced by Sync() without starting the process,
+// PutService() for sending a secret to the server, GetService() for receiving a secret from the server,
+// DeleteService() for deleting a secret on the server, ChangesService() for receiving the server changes,
+// VersionsService() for receiving the history of a secret and RestoreService() for restoring a revision of a secret.
 type Syncer interface {
 	Sync() error
 	Ping() error
 	StartSync(string)
+	SetClientID(string)
 	PutService(ctx context.Context, secretID uuid.UUID) error
 	GetService(ctx context.Context, secretID uuid.UUID) error
+	DeleteService(ctx context.Context, secretID uuid.UUID) error
+	ChangesService(cursor int64) (models.Changes, error)
+	VersionsService(secretID uuid.UUID) ([]models.Secret, error)
+	RestoreService(ctx context.Context, secretID uuid.UUID, revision int64) error
 }
 
 // NewSyncer creates a new Syncer instance with the specified storage, client, and address.
@@ -36,23 +51,111 @@ func NewSyncer(storage keeperstorage.KeeperStorage, client Clienter, address str
 }
 
 // Sync type implements the Syncer interface and has fields for storage,
-// client, clientID, and address.
+// client, clientID, and address. The secrets are exchanged with the server by remote,
+// the history of the secrets and the event stream are always requested with the REST API.
+// streaming reports whether the event stream is connected.
 type Sync struct {
-	storage  keeperstorage.KeeperStorage
-	client   Clienter
-	clientID string
-	address  string
+	storage   keeperstorage.KeeperStorage
+	client    Clienter
+	clientID  string
+	address   string
+	remote    remote
+	streaming atomic.Bool
 }
 
-// NewSync creates a new Syncer instance with the specified storage, client, and address.
+// NewSync creates a new Syncer instance with the specified storage, client, and address,
+// it exchanges the secrets with the REST API.
 func NewSync(storage keeperstorage.KeeperStorage, client Clienter, address string) *Sync {
-	return &Sync{storage: storage, client: client, address: address}
+	return &Sync{storage: storage, client: client, address: address, remote: &restRemote{client: client, address: address}}
 }
 
-// Ping method sends a GET request to the server to check connectivity.
-// If the response is not 200 OK, an error is returned.
+// remote exchanges the secrets with the server.
+// put and delete return the new revision of the secret, a rejected base revision is reported
+// as constants.ErrRevisionConflict and a missing secret as constants.ErrSecretNotFound.
+type remote interface {
+	ping() error
+	changes(cursor int64) (models.Changes, error)
+	put(ctx context.Context, secret models.Secret) (int64, error)
+	get(ctx context.Context, secretID uuid.UUID) (models.Secret, error)
+	delete(ctx context.Context, secret models.Secret) (int64, error)
+}
+
+// Ping checks the connectivity to the server.
 func (s *Sync) Ping() error {
-	get, err := s.client.Get(s.address + "/ping")
+	return s.remote.ping()
+}
+
+// SetClientID sets the user whose secrets are synced.
+func (s *Sync) SetClientID(clientID string) {
+	s.clientID = clientID
+}
+
+// StartSync starts the synchronization process by calling Ping() and then Sync() in a loop.
+// If either method returns an error, the loop continues.
+// The first sync runs at once, the next ones as soon as the server reports a change on the event stream.
+// While the stream is down the changes are polled every constatns.TimeSleepSync.
+// While it is up the local storage is checked every constatns.TimeSleepSync and a sync runs
+// when a secret was changed locally, a full sync still runs every constatns.TimeSleepSyncStreaming.
+func (s *Sync) StartSync(clientID string) {
+	s.SetClientID(clientID)
+	wake := make(chan struct{}, 1)
+	go s.watchEvents(wake)
+	var lastSync time.Time
+	for {
+		if err := s.Ping(); err == nil {
+			if err = s.Sync(); err != nil {
+				pterm.Error.Println(err)
+			}
+		}
+		lastSync = time.Now()
+		for !s.syncDue(wake, lastSync) {
+		}
+	}
+}
+
+// syncDue waits for the next check of the sync loop and reports whether a sync is due.
+func (s *Sync) syncDue(wake <-chan struct{}, lastSync time.Time) bool {
+	select {
+	case <-wake:
+		return true
+	case <-time.After(constatns.TimeSleepSync):
+	}
+	if !s.streaming.Load() || time.Since(lastSync) >= constatns.TimeSleepSyncStreaming {
+		return true
+	}
+	return s.hasLocalChanges()
+}
+
+// hasLocalChanges reports whether a secret was changed locally after the last sync.
+// A failed check is reported as a change, the sync reports the error.
+func (s *Sync) hasLocalChanges() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), constatns.TimeOutSync)
+	defer cancel()
+	cursor, err := s.storage.GetCursor(ctx, localCursorName(s.clientID))
+	if err != nil {
+		return true
+	}
+	changes, _, err := s.storage.Changes(ctx, s.clientID, cursor, 1)
+	return err != nil || len(changes) != 0
+}
+
+// watchEvents keeps the event stream of the server connected and wakes the sync loop on its events.
+// A dropped stream is reconnected after constatns.TimeSleepSync.
+func (s *Sync) watchEvents(wake chan<- struct{}) {
+	for {
+		if err := s.readEvents(wake); err != nil {
+			log.Println(err)
+		}
+		s.streaming.Store(false)
+		time.Sleep(constatns.TimeSleepSync)
+	}
+}
+
+// readEvents sends a GET request to the /api/v1/events endpoint and reads the server-sent events until
+// the stream is closed. The "ready" event opening the stream wakes the sync loop as well,
+// so the changes made while the stream was down are pulled.
+func (s *Sync) readEvents(wake chan<- struct{}) error {
+	get, err := s.client.Get(s.address + "/api/v1/events")
 	if err != nil {
 		return err
 	}
@@ -63,164 +166,351 @@ func (s *Sync) Ping() error {
 		}
 	}(get.Body)
 	if get.StatusCode != http.StatusOK {
-		return fmt.Errorf("ping failed")
-	} else {
-		return nil
-	}
-}
-
-// StartSync starts the synchronization process by calling Ping() and then Sync() in a loop.
-// If either method returns an error, the loop continues.
-func (s *Sync) StartSync(clientID string) {
-	s.clientID = clientID
-	for {
-		time.Sleep(constatns.TimeSleepSync)
-		err := s.Ping()
+		all, err := io.ReadAll(get.Body)
 		if err != nil {
+			return err
+		}
+		return fmt.Errorf("events failed %s", all)
+	}
+	scanner := bufio.NewScanner(get.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "event:") {
 			continue
 		}
-		err = s.Sync()
-		if err != nil {
-			pterm.Error.Println(err)
+		switch strings.TrimSpace(strings.TrimPrefix(line, "event:")) {
+		case "ready":
+			s.streaming.Store(true)
+		case "change":
+		default:
+			continue
+		}
+		select {
+		case wake <- struct{}{}:
+		default:
+			// a sync is already pending
 		}
 	}
+	return scanner.Err()
 }
 
-// Sync sends a GET request to the server to get a list of secrets.
-// If the response is 204 No Content, there are no secrets to sync, so the method returns.
-// Otherwise, it unmarshals the response into an array of models.LiteSecret structs.
+// Sync exchanges the changes made since the previous sync with the server.
+// It asks the server only for the secrets changed after the server cursor and scans the local storage
+// only for the secrets changed after the local cursor, both cursors are persisted in the local storage.
+// A secret changed on both sides is resolved by resolve, a secret changed on one side is copied to the other one.
 func (s *Sync) Sync() error {
-	get, err := s.client.Get(s.address + "/api/v1/sync")
+	ctx, cancel := context.WithTimeout(context.Background(), constatns.TimeOutSync)
+	defer cancel()
+	serverCursor, err := s.storage.GetCursor(ctx, serverCursorName(s.clientID))
 	if err != nil {
 		return err
 	}
-	defer func(Body io.ReadCloser) {
-		err = Body.Close()
-		if err != nil {
-			log.Println(err)
-		}
-	}(get.Body)
-	all, err := io.ReadAll(get.Body)
+	localCursor, err := s.storage.GetCursor(ctx, localCursorName(s.clientID))
+	if err != nil {
+		return err
+	}
+	changes, err := s.ChangesService(serverCursor)
+	if err != nil {
+		return err
+	}
+	localChanges, newLocalCursor, err := s.storage.Changes(ctx, s.clientID, localCursor, 0)
 	if err != nil {
 		return err
 	}
-	var serviceSecrets []models.LiteSecret
 
-	if get.StatusCode != http.StatusOK && get.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("sync failed %s", all)
+	serviceSecretsMap := make(map[uuid.UUID]models.LiteSecret)
+	for _, servicelite := range changes.Secrets {
+		serviceSecretsMap[servicelite.ID] = servicelite
 	}
 
-	if get.StatusCode != http.StatusNoContent {
-		err = json.Unmarshal(all, &serviceSecrets)
+	// synced holds the local state of every secret written to the local storage by this sync
+	synced := make(map[uuid.UUID]models.LiteSecret)
+	for _, locallite := range localChanges {
+		tmps, ok := serviceSecretsMap[locallite.ID]
+		if ok {
+			delete(serviceSecretsMap, locallite.ID)
+			err = s.resolve(ctx, synced, locallite, tmps)
+		} else {
+			err = s.push(ctx, synced, locallite)
+		}
 		if err != nil {
 			return err
 		}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), constatns.TimeOutSync)
-	defer cancel()
-	secret, err := s.storage.SyncSecret(ctx, s.clientID)
-	if err != nil {
-		return err
+
+	for _, tmps := range serviceSecretsMap {
+		local, err := s.storage.GetSecret(ctx, s.clientID, tmps.ID)
+		if err != nil && !errors.Is(err, constants.ErrSecretNotFound) {
+			return err
+		}
+		if err == nil && sameLiteSecret(liteSecretOf(local), tmps) && local.Revision == tmps.Revision {
+			continue
+		}
+		//	load in client
+		if err = s.GetService(ctx, tmps.ID); err != nil {
+			return err
+		}
+		synced[tmps.ID] = tmps
 	}
 
-	if len(secret) == 0 && len(serviceSecrets) != 0 {
-		for _, v := range serviceSecrets {
-			err = s.GetService(ctx, v.ID)
-			if err != nil {
-				return err
+	// The secrets written by the sync are local changes now, skip them unless they were edited meanwhile
+	if len(synced) != 0 {
+		afterSync, cursor, err := s.storage.Changes(ctx, s.clientID, newLocalCursor, 0)
+		if err != nil {
+			return err
+		}
+		onlySynced := true
+		for _, locallite := range afterSync {
+			if result, ok := synced[locallite.ID]; !ok || !sameLiteSecret(locallite, result) {
+				onlySynced = false
+				break
 			}
 		}
-		return nil
+		if onlySynced {
+			newLocalCursor = cursor
+		}
 	}
 
-	serviceSecretsMap := make(map[uuid.UUID]models.LiteSecret)
-	for _, servicelite := range serviceSecrets {
-		serviceSecretsMap[servicelite.ID] = servicelite
+	if err = s.storage.PutCursor(ctx, serverCursorName(s.clientID), changes.Cursor); err != nil {
+		return err
 	}
+	return s.storage.PutCursor(ctx, localCursorName(s.clientID), newLocalCursor)
+}
 
-	for _, locallite := range secret {
-		tmps := serviceSecretsMap[locallite.ID]
-		if locallite.IsDeleted != tmps.IsDeleted {
-			if locallite.IsDeleted {
-				//	load in service
-				err = s.PutService(ctx, locallite.ID)
-				if err != nil {
-					return err
-				}
-			} else {
-				//	load in client
-				err = s.GetService(ctx, tmps.ID)
-				if err != nil {
-					return err
-				}
-			}
+// resolve syncs a secret changed both locally and on the server since the previous sync.
+// The local copy is pushed only if it was edited from the current server revision.
+// Otherwise both sides changed since the last common revision: the server copy is loaded
+// and a different local version is kept as a conflicted copy, see conflict.NewCopy.
+// The local state of the synced secrets is recorded in synced.
+func (s *Sync) resolve(ctx context.Context, synced map[uuid.UUID]models.LiteSecret, locallite models.LiteSecret, tmps models.LiteSecret) error {
+	if locallite.IsDeleted && tmps.IsDeleted {
+		// nothing to propagate
+		synced[locallite.ID] = locallite
+		return nil
+	}
+	if locallite.Revision == tmps.Revision {
+		if sameLiteSecret(locallite, tmps) {
+			// the local copy was loaded from the server
+			synced[locallite.ID] = locallite
+			return nil
 		}
-		if locallite.Ver.Sub(tmps.Ver) > (1 * time.Second) {
-			//	load in service
-			err = s.PutService(ctx, locallite.ID)
-			if err != nil {
-				return err
-			}
-		} else {
-			if locallite.ValueHash != tmps.ValueHash || locallite.DescriptionHash != tmps.DescriptionHash {
-				//	load in client
-				err = s.GetService(ctx, tmps.ID)
-				if err != nil {
-					return err
-				}
-			}
+		return s.push(ctx, synced, locallite)
+	}
+	if !locallite.IsDeleted && !sameLiteSecret(locallite, tmps) {
+		if err := s.keepConflictedCopy(ctx, synced, locallite.ID); err != nil {
+			return err
+		}
+	}
+	//	load in client
+	synced[tmps.ID] = tmps
+	return s.GetService(ctx, tmps.ID)
+}
+
+// push sends a secret changed locally to the server.
+// If the server rejects the base revision of the secret, the local version is kept as a conflicted copy
+// and the server copy is loaded. A rejected delete only loads the server copy: the secret was edited
+// on another device after this one deleted it, the edit wins and nothing local is lost.
+// The local state of the synced secrets is recorded in synced.
+func (s *Sync) push(ctx context.Context, synced map[uuid.UUID]models.LiteSecret, locallite models.LiteSecret) error {
+	synced[locallite.ID] = locallite
+	var err error
+	if locallite.IsDeleted {
+		//	delete in service, a secret the server never had has nothing to delete
+		err = s.DeleteService(ctx, locallite.ID)
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			return nil
+		}
+	} else {
+		//	load in service
+		err = s.PutService(ctx, locallite.ID)
+	}
+	if !errors.Is(err, constants.ErrRevisionConflict) {
+		return err
+	}
+	if !locallite.IsDeleted {
+		if err = s.keepConflictedCopy(ctx, synced, locallite.ID); err != nil {
+			return err
 		}
 	}
+	//	load in client
+	if err = s.GetService(ctx, locallite.ID); err != nil {
+		return err
+	}
+	local, err := s.storage.GetSecret(ctx, s.clientID, locallite.ID)
+	if err != nil {
+		return err
+	}
+	synced[locallite.ID] = liteSecretOf(local)
 	return nil
 }
 
-// PutService gets a secret from local storage with the specified ID, marshals it into JSON
-// and sends it to the server with a PUT request.
-func (s *Sync) PutService(ctx context.Context, secretID uuid.UUID) error {
-	getSecret, err := s.storage.GetSecret(ctx, secretID)
+// keepConflictedCopy stores the local version of the secret as a conflicted copy and sends it to the server.
+func (s *Sync) keepConflictedCopy(ctx context.Context, synced map[uuid.UUID]models.LiteSecret, secretID uuid.UUID) error {
+	local, err := s.storage.GetSecret(ctx, s.clientID, secretID)
 	if err != nil {
 		return err
 	}
-	marshal, err := json.Marshal(getSecret)
+	conflicted := conflict.NewCopy(local)
+	if err = s.storage.PutSecret(ctx, conflicted); err != nil {
+		return err
+	}
+	synced[conflicted.ID] = liteSecretOf(conflicted)
+	pterm.Warning.Printfln("conflicting changes of secret %s, the local version is kept as %s", secretID, conflicted.ID)
+	//	load in service
+	return s.PutService(ctx, conflicted.ID)
+}
+
+// ChangesService receives the secrets changed after the cursor from the server.
+// The server pages the changes, the pages are requested until no more changes follow
+// or the cursor stops advancing.
+func (s *Sync) ChangesService(cursor int64) (models.Changes, error) {
+	changes := models.Changes{Cursor: cursor}
+	for {
+		page, err := s.remote.changes(changes.Cursor)
+		if err != nil {
+			return models.Changes{}, err
+		}
+		changes.Secrets = append(changes.Secrets, page.Secrets...)
+		advanced := page.Cursor > changes.Cursor
+		changes.Cursor = page.Cursor
+		if !page.HasMore || !advanced {
+			return changes, nil
+		}
+	}
+}
+
+// PutService gets a secret from local storage with the specified ID and sends it to the server.
+// The revision of the local copy is the base revision of the update,
+// on success the new revision assigned by the server is stored locally.
+// If the server copy was changed after the base revision, an error wrapping constants.ErrRevisionConflict is returned.
+func (s *Sync) PutService(ctx context.Context, secretID uuid.UUID) error {
+	getSecret, err := s.storage.GetSecret(ctx, s.clientID, secretID)
 	if err != nil {
 		return err
 	}
-	resp, err := s.client.Put(s.address+"/api/v1/", "application/json", bytes.NewBuffer(marshal))
+	revision, err := s.remote.put(ctx, getSecret)
 	if err != nil {
 		return err
 	}
-	return resp.Body.Close()
+	getSecret.Revision = revision
+	return s.storage.PutSecret(ctx, getSecret)
 }
 
-// GetService sends a POST request to the server with a JSON payload containing the ID of the secret to retrieve.
-// It unmarshals the response into a models.Secret struct and stores it in local storage.
+// GetService receives the secret with the specified ID from the server and stores it in local storage
+// as a secret of the client.
 func (s *Sync) GetService(ctx context.Context, secretID uuid.UUID) error {
-	marshal, err := json.Marshal(secretID)
+	secret, err := s.remote.get(ctx, secretID)
+	if err != nil {
+		return err
+	}
+	secret.OwnerID = s.clientID
+	return s.storage.PutSecret(ctx, secret)
+}
+
+// DeleteService deletes the secret with the specified ID on the server.
+// The server keeps a tombstone of the secret, so the deletion reaches the other devices.
+// The revision of the local tombstone is the base revision of the delete,
+// on success the revision of the server tombstone is stored locally.
+// If the server does not have the secret, an error wrapping constants.ErrSecretNotFound is returned,
+// if the server copy was changed after the base revision, an error wrapping constants.ErrRevisionConflict.
+func (s *Sync) DeleteService(ctx context.Context, secretID uuid.UUID) error {
+	getSecret, err := s.storage.GetSecret(ctx, s.clientID, secretID)
 	if err != nil {
 		return err
 	}
-	post, err := s.client.Post(s.address+"/api/v1/", "application/json", bytes.NewBuffer(marshal))
+	revision, err := s.remote.delete(ctx, getSecret)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err = post.Body.Close()
+	getSecret.Revision = revision
+	return s.storage.PutSecret(ctx, getSecret)
+}
+
+// VersionsService sends a GET request to the server to get the history of the secret, newest first.
+func (s *Sync) VersionsService(secretID uuid.UUID) ([]models.Secret, error) {
+	get, err := s.client.Get(fmt.Sprintf("%s/api/v1/secrets/%s/versions", s.address, secretID))
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
 		if err != nil {
 			log.Println(err)
 		}
-	}()
-	all, err := io.ReadAll(post.Body)
+	}(get.Body)
+	all, err := io.ReadAll(get.Body)
+	if err != nil {
+		return nil, err
+	}
+	if get.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("get versions failed: %w", constants.ErrSecretNotFound)
+	}
+	if get.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get versions failed %s", all)
+	}
+	var versions []models.Secret
+	if err = json.Unmarshal(all, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// RestoreService sends a POST request to the server to restore the revision of the secret
+// and loads the restored secret into local storage.
+func (s *Sync) RestoreService(ctx context.Context, secretID uuid.UUID, revision int64) error {
+	marshal, err := json.Marshal(models.Revision{Revision: revision})
 	if err != nil {
 		return err
 	}
-	var secret models.Secret
-	err = json.Unmarshal(all, &secret)
+	post, err := s.client.Post(fmt.Sprintf("%s/api/v1/secrets/%s/restore", s.address, secretID), "application/json", bytes.NewBuffer(marshal))
 	if err != nil {
 		return err
 	}
-	err = s.storage.PutSecret(ctx, secret)
+	defer func() {
+		err = post.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(post.Body)
 	if err != nil {
 		return err
 	}
-	return nil
+	switch post.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return fmt.Errorf("restore secret failed: %w", constants.ErrRevisionConflict)
+	default:
+		return fmt.Errorf("restore secret failed %s", all)
+	}
+	return s.GetService(ctx, secretID)
+}
+
+// serverCursorName is the name of the cursor of the last changes received from the server.
+func serverCursorName(clientID string) string {
+	return "server:" + clientID
+}
+
+// localCursorName is the name of the cursor of the last local changes sent to the server.
+func localCursorName(clientID string) string {
+	return "local:" + clientID
+}
+
+// liteSecretOf builds the models.LiteSecret of a secret the same way the storages do.
+func liteSecretOf(secret models.Secret) models.LiteSecret {
+	return models.LiteSecret{
+		ID:              secret.ID,
+		ValueHash:       utils.GetSHA256Hash(secret.Value),
+		DescriptionHash: utils.GetSHA256Hash([]byte(secret.Description)),
+		IsDeleted:       secret.IsDeleted,
+		Ver:             secret.Ver,
+		Seq:             secret.Seq,
+		Revision:        secret.Revision,
+	}
+}
+
+// sameLiteSecret reports whether two digests describe the same content of a secret.
+// The revisions are not compared.
+func sameLiteSecret(a models.LiteSecret, b models.LiteSecret) bool {
+	return a.ValueHash == b.ValueHash && a.DescriptionHash == b.DescriptionHash && a.IsDeleted == b.IsDeleted
 }
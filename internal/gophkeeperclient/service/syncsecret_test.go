@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
+	keepermemstorage "yudinsv/gophkeeper/internal/keeperstorage/memstorage"
 	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
 	mock "yudinsv/gophkeeper/mocks"
 
 	"github.com/golang/mock/gomock"
@@ -54,14 +60,15 @@ func TestSync_PutService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockClient := mock.NewMockClienter(ctrl)
-	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"revision":3}`))}
 	mockClient.EXPECT().Put("http://localhost:8080/api/v1/", "application/json", gomock.Any()).Return(
 		&response,
 		nil,
 	)
 	key := uuid.New()
 	mockStorage := mock.NewMockKeeperStorage(ctrl)
-	mockStorage.EXPECT().GetSecret(gomock.Any(), key).Return(models.Secret{}, nil)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(models.Secret{ID: key, Revision: 2}, nil)
+	mockStorage.EXPECT().PutSecret(gomock.Any(), models.Secret{ID: key, Revision: 3}).Return(nil)
 	// Call the method being tested
 	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
 	err := syncer.PutService(context.Background(), key)
@@ -70,6 +77,26 @@ func TestSync_PutService(t *testing.T) {
 	}
 }
 
+func TestSync_PutService_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusConflict, Body: io.NopCloser(strings.NewReader("revision conflict"))}
+	mockClient.EXPECT().Put("http://localhost:8080/api/v1/", "application/json", gomock.Any()).Return(
+		&response,
+		nil,
+	)
+	key := uuid.New()
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(models.Secret{ID: key, Revision: 1}, nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	err := syncer.PutService(context.Background(), key)
+	if !errors.Is(err, constants.ErrRevisionConflict) {
+		t.Fatalf("PutService() error = %v, want %v", err, constants.ErrRevisionConflict)
+	}
+}
+
 func TestSync_GetService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -95,3 +122,453 @@ func TestSync_GetService(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSync_DeleteService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	key := uuid.New()
+	marshal, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Content-Type": {"application/json"}, "If-Match": {`"2"`}}
+	response := http.Response{StatusCode: http.StatusNoContent, Header: http.Header{"Etag": {`"3"`}}, Body: io.NopCloser(strings.NewReader(""))}
+	mockClient.EXPECT().DeleteWithHeader("http://localhost:8080/api/v1/", header, bytes.NewBuffer(marshal)).Return(
+		&response,
+		nil,
+	)
+	tombstone := models.Secret{ID: key, OwnerID: "client", IsDeleted: true, Revision: 2}
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(tombstone, nil)
+	tombstone.Revision = 3
+	mockStorage.EXPECT().PutSecret(gomock.Any(), tombstone).Return(nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	err = syncer.DeleteService(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync_DeleteService_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusPreconditionFailed, Body: io.NopCloser(strings.NewReader("revision conflict"))}
+	mockClient.EXPECT().DeleteWithHeader("http://localhost:8080/api/v1/", gomock.Any(), gomock.Any()).Return(
+		&response,
+		nil,
+	)
+	key := uuid.New()
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(models.Secret{ID: key, IsDeleted: true, Revision: 1}, nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	err := syncer.DeleteService(context.Background(), key)
+	if !errors.Is(err, constants.ErrRevisionConflict) {
+		t.Fatalf("expected revision conflict, got %v", err)
+	}
+}
+
+func TestSync_DeleteService_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("secret not found"))}
+	mockClient.EXPECT().DeleteWithHeader("http://localhost:8080/api/v1/", gomock.Any(), gomock.Any()).Return(
+		&response,
+		nil,
+	)
+	key := uuid.New()
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(models.Secret{ID: key, IsDeleted: true}, nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	err := syncer.DeleteService(context.Background(), key)
+	if !errors.Is(err, constants.ErrSecretNotFound) {
+		t.Fatalf("expected error for not found secret, got %v", err)
+	}
+}
+
+func TestSync_SyncPropagatesTombstone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	key := uuid.New()
+	serverVer := time.Now().Add(-time.Hour)
+	serviceChanges, err := json.Marshal(models.Changes{
+		Secrets: []models.LiteSecret{{ID: key, ValueHash: "v", DescriptionHash: "d", Ver: serverVer, Seq: 3}},
+		Cursor:  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/changes?since=0").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(serviceChanges))},
+		nil,
+	)
+	mockClient.EXPECT().DeleteWithHeader("http://localhost:8080/api/v1/", gomock.Any(), gomock.Any()).Return(
+		&http.Response{StatusCode: http.StatusNoContent, Header: http.Header{"Etag": {`"4"`}}, Body: io.NopCloser(strings.NewReader(""))},
+		nil,
+	)
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().GetSecret(gomock.Any(), gomock.Any(), key).Return(models.Secret{ID: key, IsDeleted: true, Revision: 3}, nil)
+	mockStorage.EXPECT().PutSecret(gomock.Any(), models.Secret{ID: key, IsDeleted: true, Revision: 4}).Return(nil)
+	mockStorage.EXPECT().GetCursor(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(2)
+	mockStorage.EXPECT().Changes(gomock.Any(), gomock.Any(), int64(0), 0).Return(
+		[]models.LiteSecret{{ID: key, ValueHash: "v", DescriptionHash: "d", IsDeleted: true, Ver: time.Now(), Seq: 2}},
+		int64(2),
+		nil,
+	)
+	mockStorage.EXPECT().Changes(gomock.Any(), gomock.Any(), int64(2), 0).Return(nil, int64(2), nil)
+	mockStorage.EXPECT().PutCursor(gomock.Any(), "server:", int64(3)).Return(nil)
+	mockStorage.EXPECT().PutCursor(gomock.Any(), "local:", int64(2)).Return(nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	err = syncer.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync_SyncPullsOnlyChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	storage := keepermemstorage.NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+	unchanged := models.Secret{ID: uuid.New(), OwnerID: "user", Value: []byte("value"), Ver: time.Now()}
+	if err := storage.PutSecret(ctx, unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.PutCursor(ctx, "local:user", 1); err != nil {
+		t.Fatal(err)
+	}
+	changed := models.Secret{ID: uuid.New(), OwnerID: "user", Value: []byte("new value"), Ver: time.Now()}
+	serviceChanges, err := json.Marshal(models.Changes{
+		Secrets: []models.LiteSecret{
+			{ID: unchanged.ID, ValueHash: utils.GetSHA256Hash(unchanged.Value), DescriptionHash: utils.GetSHA256Hash(nil), Ver: unchanged.Ver, Seq: 4},
+			{ID: changed.ID, ValueHash: utils.GetSHA256Hash(changed.Value), DescriptionHash: utils.GetSHA256Hash(nil), Ver: changed.Ver, Seq: 5},
+		},
+		Cursor: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/changes?since=0").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(serviceChanges))},
+		nil,
+	)
+	secret, err := json.Marshal(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/", "application/json", gomock.Any()).Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(secret))},
+		nil,
+	)
+	// Call the method being tested
+	syncer := NewSync(storage, mockClient, "http://localhost:8080")
+	syncer.clientID = "user"
+	if err = syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := storage.GetSecret(ctx, "user", changed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Value, changed.Value) {
+		t.Errorf("pulled value = %q, want %q", got.Value, changed.Value)
+	}
+	serverCursor, err := storage.GetCursor(ctx, "server:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serverCursor != 5 {
+		t.Errorf("server cursor = %d, want 5", serverCursor)
+	}
+	// The pulled secret is not sent back to the server on the next sync
+	localCursor, err := storage.GetCursor(ctx, "local:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localCursor != 2 {
+		t.Errorf("local cursor = %d, want 2", localCursor)
+	}
+}
+
+func TestSync_ChangesService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	want := models.Changes{Secrets: []models.LiteSecret{{ID: uuid.New(), Seq: 8}}, Cursor: 8}
+	marshal, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/changes?since=7").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))},
+		nil,
+	)
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	changes, err := syncer.ChangesService(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes.Cursor != want.Cursor || len(changes.Secrets) != 1 || changes.Secrets[0].ID != want.Secrets[0].ID {
+		t.Errorf("ChangesService() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestSync_ChangesService_Pages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	pages := []models.Changes{
+		{Secrets: []models.LiteSecret{{ID: uuid.New(), Seq: 1}}, Cursor: 1, HasMore: true},
+		{Secrets: []models.LiteSecret{{ID: uuid.New(), Seq: 2}}, Cursor: 2},
+	}
+	for i, page := range pages {
+		marshal, err := json.Marshal(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mockClient.EXPECT().Get(fmt.Sprintf("http://localhost:8080/api/v1/changes?since=%d", i)).Return(
+			&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))},
+			nil,
+		)
+	}
+	// A page asking for more without moving the cursor ends the loop
+	stuck, err := json.Marshal(models.Changes{Cursor: 2, HasMore: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/changes?since=2").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(stuck))},
+		nil,
+	)
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+
+	changes, err := syncer.ChangesService(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes.Cursor != 2 || len(changes.Secrets) != 2 || changes.Secrets[1].ID != pages[1].Secrets[0].ID {
+		t.Errorf("ChangesService() = %+v, want both pages", changes)
+	}
+	changes, err = syncer.ChangesService(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes.Cursor != 2 || len(changes.Secrets) != 0 {
+		t.Errorf("ChangesService() = %+v, want no changes", changes)
+	}
+}
+
+func TestSync_SyncKeepsConflictedCopy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	storage := keepermemstorage.NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+
+	// Both devices edited revision 1 of the secret
+	local := models.Secret{ID: uuid.New(), OwnerID: "user", Value: []byte("local edit"), Ver: time.Now(), Revision: 1}
+	if err := storage.PutSecret(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+	server := models.Secret{ID: local.ID, OwnerID: "user", Value: []byte("server edit"), Ver: time.Now(), Revision: 2}
+	serviceChanges, err := json.Marshal(models.Changes{
+		Secrets: []models.LiteSecret{
+			{ID: server.ID, ValueHash: utils.GetSHA256Hash(server.Value), DescriptionHash: utils.GetSHA256Hash(nil), Ver: server.Ver, Seq: 7, Revision: 2},
+		},
+		Cursor: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/changes?since=0").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(serviceChanges))},
+		nil,
+	)
+	// The local version is sent to the server as a new secret
+	mockClient.EXPECT().Put("http://localhost:8080/api/v1/", "application/json", gomock.Any()).DoAndReturn(
+		func(url string, contentType string, body io.Reader) (*http.Response, error) {
+			var conflicted models.Secret
+			if err := json.NewDecoder(body).Decode(&conflicted); err != nil {
+				t.Fatal(err)
+			}
+			if conflicted.ConflictOf != local.ID || !bytes.Equal(conflicted.Value, local.Value) || conflicted.Revision != 0 {
+				t.Errorf("unexpected conflicted copy %+v", conflicted)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"revision":1}`))}, nil
+		},
+	)
+	secret, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/", "application/json", gomock.Any()).Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(secret))},
+		nil,
+	)
+	// Call the method being tested
+	syncer := NewSync(storage, mockClient, "http://localhost:8080")
+	syncer.clientID = "user"
+	if err = syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The original holds the server version and the conflicted copy the local one
+	secrets, err := storage.SyncSecret(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 {
+		t.Fatalf("got %d secrets, want 2", len(secrets))
+	}
+	for _, liteSecret := range secrets {
+		stored, err := storage.GetSecret(ctx, "user", liteSecret.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.ID == local.ID && !bytes.Equal(stored.Value, server.Value) {
+			t.Errorf("original value = %q, want %q", stored.Value, server.Value)
+		}
+		if stored.ID != local.ID && (stored.ConflictOf != local.ID || !bytes.Equal(stored.Value, local.Value)) {
+			t.Errorf("unexpected conflicted copy %+v", stored)
+		}
+	}
+	// Nothing is left to send on the next sync
+	localChanges, _, err := storage.Changes(ctx, "user", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localCursor, err := storage.GetCursor(ctx, "local:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localCursor != localChanges[len(localChanges)-1].Seq {
+		t.Errorf("local cursor = %d, want %d", localCursor, localChanges[len(localChanges)-1].Seq)
+	}
+}
+
+func TestSync_VersionsService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	key := uuid.New()
+	want := []models.Secret{{ID: key, Value: []byte("second"), Revision: 2}, {ID: key, Value: []byte("first"), Revision: 1}}
+	marshal, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/secrets/"+key.String()+"/versions").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))},
+		nil,
+	)
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	versions, err := syncer.VersionsService(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Revision != 2 || !bytes.Equal(versions[1].Value, want[1].Value) {
+		t.Errorf("VersionsService() = %+v, want %+v", versions, want)
+	}
+}
+
+func TestSync_RestoreService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	key := uuid.New()
+	restored := models.Secret{ID: key, Value: []byte("first"), Revision: 3}
+	marshal, err := json.Marshal(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/secrets/"+key.String()+"/restore", "application/json", bytes.NewBufferString(`{"revision":1}`)).Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"revision":3}`))},
+		nil,
+	)
+	mockClient.EXPECT().Post("http://localhost:8080/api/v1/", "application/json", gomock.Any()).Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))},
+		nil,
+	)
+	mockStorage := mock.NewMockKeeperStorage(ctrl)
+	mockStorage.EXPECT().PutSecret(gomock.Any(), restored).Return(nil)
+	// Call the method being tested
+	syncer := NewSyncer(mockStorage, mockClient, "http://localhost:8080")
+	if err = syncer.RestoreService(context.Background(), key, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync_readEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	stream := "event:ready\ndata:\n\n: ping\n\nevent:change\ndata:{\"secret_id\":\"" + uuid.NewString() + "\",\"revision\":2}\n\n"
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/events").Return(
+		&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(stream))},
+		nil,
+	)
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/events").Return(
+		&http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader("unauthorized"))},
+		nil,
+	)
+	syncer := NewSync(mock.NewMockKeeperStorage(ctrl), mockClient, "http://localhost:8080")
+
+	wake := make(chan struct{}, 1)
+	if err := syncer.readEvents(wake); err != nil {
+		t.Fatal(err)
+	}
+	if len(wake) != 1 {
+		t.Error("the events did not wake the sync")
+	}
+	if !syncer.streaming.Load() {
+		t.Error("the stream is not reported as connected")
+	}
+
+	<-wake
+	syncer.streaming.Store(false)
+	if err := syncer.readEvents(wake); err == nil {
+		t.Error("a rejected stream must fail")
+	}
+	if len(wake) != 0 || syncer.streaming.Load() {
+		t.Error("a rejected stream must not wake the sync")
+	}
+}
+
+func TestSync_HasLocalChanges(t *testing.T) {
+	ctx := context.Background()
+	storage := keepermemstorage.NewMemoryStorage(constants.DefaultSecretVersions)
+	syncer := NewSync(storage, nil, "http://localhost:8080")
+	syncer.SetClientID("client")
+	if syncer.hasLocalChanges() {
+		t.Fatal("an empty storage has no local changes")
+	}
+	if err := storage.PutSecret(ctx, models.Secret{ID: uuid.New(), OwnerID: "client", Value: []byte("value")}); err != nil {
+		t.Fatal(err)
+	}
+	if !syncer.hasLocalChanges() {
+		t.Fatal("a secret stored after the last sync is a local change")
+	}
+	// The sync moves the local cursor past the change
+	_, cursor, err := storage.Changes(ctx, "client", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.PutCursor(ctx, localCursorName("client"), cursor); err != nil {
+		t.Fatal(err)
+	}
+	if syncer.hasLocalChanges() {
+		t.Fatal("the synced secret is not a local change")
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// NewMyClient creates a client sending the device name with every request.
+// tlsConfig sets the trusted CAs and the client certificate of the HTTPS connections, nil keeps the defaults.
+func NewMyClient(deviceName string, tlsConfig *tls.Config) *MyClient {
+	client := &MyClient{DeviceName: deviceName}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.client.Transport = transport
+	}
+	return client
+}
+
+// ClientTLSConfig builds the client TLS config: caFile is a PEM bundle trusted in addition to the system roots,
+// for example the self-signed certificate of a development server; certFile and keyFile are the certificate
+// presented to a server requiring mutual TLS. It returns nil if no file is set.
+func ClientTLSConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
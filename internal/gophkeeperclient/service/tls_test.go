@@ -0,0 +1,64 @@
+package service
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/api/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTLSConfig(t *testing.T) {
+	config, err := ClientTLSConfig("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	dir := t.TempDir()
+	_, err = ClientTLSConfig(filepath.Join(dir, "missing.pem"), "", "")
+	assert.Error(t, err)
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+	_, err = ClientTLSConfig(empty, "", "")
+	assert.Error(t, err)
+}
+
+func TestNewMyClient_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	require.NoError(t, server.GenerateSelfSigned(certFile, keyFile, []string{"127.0.0.1"}))
+	require.NoError(t, server.GenerateSelfSigned(clientCertFile, clientKeyFile, []string{"device"}))
+	certs, err := server.NewCertReloader(certFile, keyFile, clientCertFile)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Device-Name")))
+	}))
+	srv.Listener = tls.NewListener(srv.Listener, certs.TLSConfig())
+	srv.Start()
+	defer srv.Close()
+	address := strings.Replace(srv.URL, "http://", "https://", 1)
+
+	tlsConfig, err := ClientTLSConfig(certFile, clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	client := NewMyClient("laptop", tlsConfig)
+	response, err := client.Get(address)
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "laptop", string(body))
+
+	tlsConfig, err = ClientTLSConfig(certFile, "", "")
+	require.NoError(t, err)
+	_, err = NewMyClient("laptop", tlsConfig).Get(address)
+	assert.Error(t, err)
+}
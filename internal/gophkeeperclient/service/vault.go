@@ -0,0 +1,187 @@
+// Package service implementation of an interface called Vaulter.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+)
+
+// Vaulter interface defines methods for loading and storing the vault key derivation params on the server.
+type Vaulter interface {
+	GetKDFParams() (models.KDFParams, error)
+	PutKDFParams(params models.KDFParams) error
+}
+
+// NewVaulter creates a new Vaulter instance with the specified client, and address.
+func NewVaulter(client Clienter, address string) Vaulter {
+	return NewServiceVault(client, address)
+}
+
+// Vault type implements the Vaulter interface and has fields for client and address.
+type Vault struct {
+	client  Clienter
+	address string
+}
+
+// NewServiceVault creates a new Vault instance.
+func NewServiceVault(client Clienter, address string) *Vault {
+	return &Vault{client: client, address: address}
+}
+
+// GetKDFParams sends an HTTP GET request to the /api/v1/kdf endpoint and returns the params of the logged-in user.
+// If the params have not been stored yet, constants.ErrKDFParamsNotFound is returned.
+func (s *Vault) GetKDFParams() (models.KDFParams, error) {
+	get, err := s.client.Get(s.address + "/api/v1/kdf")
+	if err != nil {
+		return models.KDFParams{}, err
+	}
+	defer func() {
+		err = get.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(get.Body)
+	if err != nil {
+		return models.KDFParams{}, err
+	}
+	if get.StatusCode == http.StatusNotFound {
+		return models.KDFParams{}, constants.ErrKDFParamsNotFound
+	}
+	if get.StatusCode != http.StatusOK {
+		return models.KDFParams{}, fmt.Errorf("get kdf params failed %s", all)
+	}
+	var params models.KDFParams
+	if err = json.Unmarshal(all, &params); err != nil {
+		return models.KDFParams{}, err
+	}
+	return params, nil
+}
+
+// PutKDFParams sends an HTTP PUT request to the /api/v1/kdf endpoint with the JSON-encoded params.
+// It returns an error if the request fails or the response status code is not 200 OK.
+func (s *Vault) PutKDFParams(params models.KDFParams) error {
+	marshal, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	put, err := s.client.Put(s.address+"/api/v1/kdf", "application/json", bytes.NewReader(marshal))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = put.Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	all, err := io.ReadAll(put.Body)
+	if err != nil {
+		return err
+	}
+	if put.StatusCode != http.StatusOK {
+		return fmt.Errorf("put kdf params failed %s", all)
+	}
+	return nil
+}
+
+// UnlockVault derives the vault key from the master password with the params stored on the server
+// and returns the key and the params. On the first unlock of the account new params are generated
+// and stored on the server.
+func UnlockVault(vault Vaulter, masterPassword string) ([]byte, models.KDFParams, error) {
+	params, err := vault.GetKDFParams()
+	if errors.Is(err, constants.ErrKDFParamsNotFound) {
+		params, err = utils.NewKDFParams()
+		if err != nil {
+			return nil, models.KDFParams{}, err
+		}
+		key := utils.DeriveKey(masterPassword, params)
+		utils.SetKeyVerifier(&params, key)
+		return key, params, vault.PutKDFParams(params)
+	}
+	if err != nil {
+		return nil, models.KDFParams{}, err
+	}
+	key, err := CheckMasterPassword(params, masterPassword)
+	return key, params, err
+}
+
+// CheckMasterPassword derives the vault key from the master password with the params
+// and checks it against the verifier of the params. The params come from the server,
+// so the params out of the bounds of utils.ValidKDFParams are refused before deriving.
+func CheckMasterPassword(params models.KDFParams, masterPassword string) ([]byte, error) {
+	if err := utils.ValidKDFParams(params); err != nil {
+		return nil, fmt.Errorf("refusing to unlock the vault: %w", err)
+	}
+	key := utils.DeriveKey(masterPassword, params)
+	if !utils.CheckKey(params, key) {
+		return nil, errors.New("invalid master password")
+	}
+	return key, nil
+}
+
+// LegacySecrets returns the local secrets of the owner whose values are not envelopes.
+// They were stored before the vault key and are encrypted with the private key of the user,
+// see ReencryptLegacySecrets. The secrets whose envelopes cannot be opened with the vault key
+// are returned as corrupted, the private key does not help with them.
+func LegacySecrets(ctx context.Context, storage keeperstorage.KeeperStorage, ownerID string, key []byte) (legacy, corrupted []models.Secret, err error) {
+	liteSecrets, err := storage.SyncSecret(ctx, ownerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, liteSecret := range liteSecrets {
+		if liteSecret.IsDeleted {
+			continue
+		}
+		secret, err := storage.GetSecret(ctx, ownerID, liteSecret.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = crypter.Open(key, secret)
+		switch {
+		case errors.Is(err, utils.ErrNotEnvelope):
+			legacy = append(legacy, secret)
+		case err != nil:
+			corrupted = append(corrupted, secret)
+		}
+	}
+	return legacy, corrupted, nil
+}
+
+// ReencryptLegacySecrets decrypts the legacy secrets with the private key of the user and seals them
+// with the vault key, the new versions are sent to the server on the next sync.
+// Nothing is stored unless every secret is decrypted, so a wrong private key leaves the secrets as they are.
+func ReencryptLegacySecrets(ctx context.Context, storage keeperstorage.KeeperStorage, legacy []models.Secret, key []byte, privateKey string) error {
+	sealed := make([]models.Secret, 0, len(legacy))
+	for _, secret := range legacy {
+		plain, err := crypter.OpenLegacy(privateKey, secret)
+		if err != nil {
+			return fmt.Errorf("decrypt secret %s: %w", secret.ID, err)
+		}
+		secret.Ver = time.Now()
+		secret, err = crypter.Seal(key, secret, plain)
+		if err != nil {
+			return err
+		}
+		sealed = append(sealed, secret)
+	}
+	for _, secret := range sealed {
+		if err := storage.PutSecret(ctx, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
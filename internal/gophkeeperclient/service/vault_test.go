@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
+	keepermemstorage "yudinsv/gophkeeper/internal/keeperstorage/memstorage"
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+	mock "yudinsv/gophkeeper/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestNewVaulter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+
+	// Call the method being tested
+	vaulter := NewVaulter(mockClient, "http://localhost:8080")
+
+	// Check if the Vaulter was created successfully
+	if vaulter == nil {
+		t.Error("NewVaulter failed to create a Vaulter instance")
+	}
+}
+
+func TestVault_GetKDFParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	params := models.KDFParams{Salt: []byte("salt"), Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	marshal, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(marshal))}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/kdf").Return(
+		&response,
+		nil,
+	)
+	// Call the method being tested
+	vaulter := NewVaulter(mockClient, "http://localhost:8080")
+	got, err := vaulter.GetKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Salt, params.Salt) || got.Memory != params.Memory {
+		t.Errorf("GetKDFParams() = %v, want %v", got, params)
+	}
+}
+
+func TestVault_GetKDFParams_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}
+	mockClient.EXPECT().Get("http://localhost:8080/api/v1/kdf").Return(
+		&response,
+		nil,
+	)
+	// Call the method being tested
+	vaulter := NewVaulter(mockClient, "http://localhost:8080")
+	_, err := vaulter.GetKDFParams()
+	if !errors.Is(err, constants.ErrKDFParamsNotFound) {
+		t.Fatalf("GetKDFParams() error = %v, want %v", err, constants.ErrKDFParamsNotFound)
+	}
+}
+
+func TestVault_PutKDFParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	params := models.KDFParams{Salt: []byte("salt"), Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	marshal, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := mock.NewMockClienter(ctrl)
+	response := http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	mockClient.EXPECT().Put("http://localhost:8080/api/v1/kdf", "application/json", bytes.NewReader(marshal)).Return(
+		&response,
+		nil,
+	)
+	// Call the method being tested
+	vaulter := NewVaulter(mockClient, "http://localhost:8080")
+	err = vaulter.PutKDFParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReencryptLegacySecrets(t *testing.T) {
+	ctx := context.Background()
+	storage := keepermemstorage.NewMemoryStorage(constants.DefaultSecretVersions)
+	key := bytes.Repeat([]byte{1}, 32)
+	// A secret stored before the vault key, encrypted with the private key
+	privateKey := uuid.NewString()
+	keyStr := []byte(privateKey)
+	value, err := utils.Encrypt([]byte("hello"), keyStr[:aes.BlockSize], keyStr[len(keyStr)-aes.BlockSize:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := models.Secret{ID: uuid.New(), OwnerID: "client", Value: value, Type: "text", Description: "note"}
+	current, err := crypter.Seal(key, models.Secret{ID: uuid.New(), OwnerID: "client"}, crypter.PlainSecret{Type: "text", Value: []byte("new")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A secret sealed with the vault key and damaged afterwards
+	tampered, err := crypter.Seal(key, models.Secret{ID: uuid.New(), OwnerID: "client"}, crypter.PlainSecret{Type: "text", Value: []byte("tampered")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered.Value[len(tampered.Value)-1] ^= 1
+	for _, secret := range []models.Secret{old, current, tampered} {
+		if err = storage.PutSecret(ctx, secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	legacy, corrupted, err := LegacySecrets(ctx, storage, "client", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 1 || legacy[0].ID != old.ID {
+		t.Fatalf("LegacySecrets() = %v, want only the secret encrypted with the private key", legacy)
+	}
+	if len(corrupted) != 1 || corrupted[0].ID != tampered.ID {
+		t.Fatalf("LegacySecrets() corrupted = %v, want the tampered secret", corrupted)
+	}
+	if err = ReencryptLegacySecrets(ctx, storage, legacy, key, "not a private key"); err == nil {
+		t.Fatal("expected an error for a wrong private key")
+	}
+	if err = ReencryptLegacySecrets(ctx, storage, legacy, key, privateKey); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := storage.GetSecret(ctx, "client", old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := crypter.Open(key, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain.Value) != "hello" || plain.Type != "text" || plain.Description != "note" || plain.Legacy {
+		t.Errorf("the secret was not sealed with the vault key: %+v", plain)
+	}
+	if legacy, _, err = LegacySecrets(ctx, storage, "client", key); err != nil || len(legacy) != 0 {
+		t.Errorf("LegacySecrets() after re-encryption = %v, %v", legacy, err)
+	}
+}
+
+func TestUnlockVault_RefusesParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Params of a compromised server asking for 4 TiB of memory
+	params := models.KDFParams{Salt: bytes.Repeat([]byte{1}, 16), Time: 3, Memory: 0xFFFFFFFF, Threads: 4, KeyLen: 32,
+		Verifier: []byte("verifier")}
+	vault := mock.NewMockVaulter(ctrl)
+	vault.EXPECT().GetKDFParams().Return(params, nil)
+	if _, _, err := UnlockVault(vault, "master"); !errors.Is(err, utils.ErrInvalidKDFParams) {
+		t.Fatalf("UnlockVault() error = %v, want %v", err, utils.ErrInvalidKDFParams)
+	}
+}
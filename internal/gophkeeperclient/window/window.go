@@ -2,17 +2,21 @@ package window
 
 import (
 	"context"
-	"crypto/aes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/conflict"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/constatns"
+	"yudinsv/gophkeeper/internal/gophkeeperclient/crypter"
 	clientmodels "yudinsv/gophkeeper/internal/gophkeeperclient/models"
 	"yudinsv/gophkeeper/internal/gophkeeperclient/service"
 	"yudinsv/gophkeeper/internal/keeperstorage"
 	"yudinsv/gophkeeper/internal/models"
-	"yudinsv/gophkeeper/internal/utils"
 
 	"github.com/google/uuid"
 	"github.com/pterm/pterm"
@@ -22,6 +26,9 @@ import (
 func RunWindow(serviceClient service.ClientService, storage keeperstorage.KeeperStorage) {
 	addSecret := "add secret"
 	viewSecret := "view secrets"
+	sessions := "sessions"
+	logout := "logout"
+	twoFactor := "enable two-factor authentication"
 	registration := "registration"
 	authorization := "authorization"
 	var options []string
@@ -31,44 +38,55 @@ func RunWindow(serviceClient service.ClientService, storage keeperstorage.Keeper
 	selectedOption, _ := pterm.DefaultInteractiveSelect.WithOptions(options).Show()
 	pterm.Info.Printfln("Selected: %s", pterm.Green(selectedOption))
 	var user models.User
-	var secretKey string
+	var masterPassword string
 	if selectedOption == registration {
-		user = registrationWindow()
-		err := serviceClient.RegistryService.Register(user)
+		var err error
+		user, masterPassword, err = registrationWindow()
 		if err != nil {
 			pterm.Error.Println(err)
 			return
 		}
-		pterm.Info.Printfln("Registration successful")
-		secretKey = uuid.New().String()
-		pterm.Info.Printfln("Your private key to encrypt and decrypt data: %s", secretKey)
-
-	} else if selectedOption == authorization {
-		user = authorizationWindow()
-		err := serviceClient.AuthService.Authorization(user)
+		err = serviceClient.RegistryService.Register(user)
 		if err != nil {
 			pterm.Error.Println(err)
 			return
 		}
-		secretKey, _ = pterm.DefaultInteractiveTextInput.WithDefaultText("Enter private key: ").WithMultiLine(false).Show()
-		_, err = uuid.Parse(secretKey)
+		pterm.Info.Printfln("Registration successful")
+	} else if selectedOption == authorization {
+		user, masterPassword = authorizationWindow()
+		err := serviceClient.AuthService.Authorization(user)
+		if errors.Is(err, constants.ErrMFARequired) {
+			err = serviceClient.AuthService.VerifyTOTP(secondFactorWindow())
+		}
 		if err != nil {
-			pterm.Error.Println("Invalid private key")
+			pterm.Error.Println(err)
 			return
 		}
 		pterm.Info.Printfln("Authorization successful")
 	} else {
 		pterm.Error.Println("Invalid option")
+		return
+	}
+	secretKey, _, err := service.UnlockVault(serviceClient.VaultService, masterPassword)
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+	if err = reencryptLegacyWindow(storage, user.Login, secretKey); err != nil {
+		pterm.Error.Println(err)
 	}
 	go serviceClient.SyncService.StartSync(user.Login)
 	for {
 		var optionsMenu []string
 		optionsMenu = append(optionsMenu, addSecret)
 		optionsMenu = append(optionsMenu, viewSecret)
+		optionsMenu = append(optionsMenu, sessions)
+		optionsMenu = append(optionsMenu, twoFactor)
+		optionsMenu = append(optionsMenu, logout)
 		selectedMenu, _ := pterm.DefaultInteractiveSelect.WithOptions(optionsMenu).Show()
 		pterm.Info.Printfln("Selected: %s", pterm.Green(selectedMenu))
 		if selectedMenu == addSecret {
-			secret, err := addSecretWindow(secretKey)
+			secret, err := addSecretWindow(serviceClient.BlobService, secretKey)
 			if err != nil {
 				pterm.Error.Println(err)
 				return
@@ -85,7 +103,7 @@ func RunWindow(serviceClient service.ClientService, storage keeperstorage.Keeper
 				return
 			}
 			for _, liteSecret := range syncSecret {
-				secret, err := storage.GetSecret(context.Background(), liteSecret.ID)
+				secret, err := storage.GetSecret(context.Background(), user.Login, liteSecret.ID)
 				if err != nil {
 					pterm.Error.Println(err)
 					return
@@ -94,74 +112,121 @@ func RunWindow(serviceClient service.ClientService, storage keeperstorage.Keeper
 					secrets = append(secrets, secret)
 				}
 			}
-			viewSecretWindow(storage, secretKey, secrets)
+			viewSecretWindow(storage, serviceClient.SyncService, serviceClient.BlobService, secretKey, secrets)
+		} else if selectedMenu == sessions {
+			if err := sessionsWindow(serviceClient.SessionService); err != nil {
+				pterm.Error.Println(err)
+			}
+		} else if selectedMenu == twoFactor {
+			if err := enrollTOTPWindow(serviceClient.AuthService); err != nil {
+				pterm.Error.Println(err)
+			}
+		} else if selectedMenu == logout {
+			if err := serviceClient.SessionService.Logout(); err != nil {
+				pterm.Error.Println(err)
+				continue
+			}
+			pterm.Info.Println("Logged out")
+			return
 		}
 	}
 }
 
 // registrationWindow register user registration rendering
-func registrationWindow() models.User {
+// The master password is asked twice, it cannot be recovered if mistyped.
+func registrationWindow() (models.User, string, error) {
 	username, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter username").WithMultiLine(false).Show()
 	password, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter password").WithMultiLine(false).Show()
-	return models.User{Login: username, Password: password}
+	masterPassword, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter master password").WithMultiLine(false).Show()
+	confirm, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Repeat master password").WithMultiLine(false).Show()
+	if masterPassword == "" {
+		return models.User{}, "", errors.New("master password is empty")
+	}
+	if masterPassword != confirm {
+		return models.User{}, "", errors.New("master passwords do not match")
+	}
+	return models.User{Login: username, Password: password}, masterPassword, nil
 }
 
 // register user authorization rendering
-func authorizationWindow() models.User {
+func authorizationWindow() (models.User, string) {
 	username, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter username").WithMultiLine(false).Show()
 	password, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter password").WithMultiLine(false).Show()
-	return models.User{Login: username, Password: password}
+	masterPassword, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter master password").WithMultiLine(false).Show()
+	return models.User{Login: username, Password: password}, masterPassword
 }
 
-// addSecretWindow adding new models.Secret rendering
-func addSecretWindow(secretKey string) (models.Secret, error) {
-	typeCards := "bank_cards"
-	typePassword := "login_password"
-	typeBinary := "binary"
-	typeText := "text"
+// secondFactorWindow asks for the code completing the login of a user with two-factor authentication.
+func secondFactorWindow() string {
+	code, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter the code from the authenticator app or a recovery code").WithMultiLine(false).Show()
+	return code
+}
 
+// enrollTOTPWindow enables two-factor authentication: it shows the secret to add to an authenticator app,
+// confirms it with a code and shows the recovery codes.
+func enrollTOTPWindow(auth service.Authorizationer) error {
+	enrollment, err := auth.EnrollTOTP()
+	if err != nil {
+		return err
+	}
+	pterm.Info.Println("Add the account to your authenticator app with the link or the secret:")
+	pterm.Println(enrollment.URI)
+	pterm.Println(enrollment.Secret)
+	code, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter the code from the authenticator app").WithMultiLine(false).Show()
+	codes, err := auth.ConfirmTOTP(code)
+	if err != nil {
+		return err
+	}
+	pterm.Info.Println("Two-factor authentication enabled. Keep the recovery codes in a safe place, each of them works once:")
+	for _, recovery := range codes.Codes {
+		pterm.Println(recovery)
+	}
+	return nil
+}
+
+// addSecretWindow adding new models.Secret rendering
+// The content of a file is uploaded in encrypted chunks, the secret holds the sealed manifest of the file.
+func addSecretWindow(blober service.Blober, secretKey []byte) (models.Secret, error) {
 	var options []string
-	options = append(options, typeCards)
-	options = append(options, typePassword)
-	options = append(options, typeBinary)
-	options = append(options, typeText)
+	options = append(options, constatns.TypeBankCards)
+	options = append(options, constatns.TypeLoginPassword)
+	options = append(options, constatns.TypeBinary)
+	options = append(options, constatns.TypeText)
+	options = append(options, constatns.TypeFile)
 
 	selectedOption, _ := pterm.DefaultInteractiveSelect.WithDefaultText("Please secret type secret").WithOptions(options).Show()
 	pterm.Info.Printfln("Selected: %s", pterm.Green(selectedOption))
 	var data []byte
 	var err error
-	if selectedOption == typePassword {
+	var blobID uuid.UUID
+	if selectedOption == constatns.TypeLoginPassword {
 		data, err = addLoginPasswordWindow()
 		if err != nil {
 			return models.Secret{}, err
 		}
-	} else if selectedOption == typeCards {
+	} else if selectedOption == constatns.TypeBankCards {
 		data, err = addBankCardsWindow()
 		if err != nil {
 			return models.Secret{}, err
 		}
-	} else if selectedOption == typeText {
+	} else if selectedOption == constatns.TypeText {
 		data = addTextWindow()
-	} else if selectedOption == typeBinary {
+	} else if selectedOption == constatns.TypeBinary {
 		data = addBinaryWindow()
+	} else if selectedOption == constatns.TypeFile {
+		blobID, data, err = addFileWindow(blober, secretKey)
+		if err != nil {
+			return models.Secret{}, err
+		}
 	} else {
 		return models.Secret{}, errors.New("invalid option")
 	}
 	description, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter Description:").WithMultiLine(false).Show()
-	keyStr := []byte(secretKey)
-	iv := keyStr[len(keyStr)-aes.BlockSize:]
-	key := keyStr[:aes.BlockSize]
-	decrypt, err := utils.Encrypt(data, key, iv)
-	if err != nil {
-		return models.Secret{}, err
-	}
-	return models.Secret{
-		ID:          uuid.New(),
-		Value:       decrypt,
-		Description: description,
+	return crypter.Seal(secretKey, models.Secret{ID: uuid.New(), Ver: time.Now(), BlobID: blobID}, crypter.PlainSecret{
 		Type:        selectedOption,
-		Ver:         time.Now(),
-	}, nil
+		Description: description,
+		Value:       data,
+	})
 }
 
 // addLoginPasswordWindow adding new LoginPassword rendering
@@ -199,11 +264,57 @@ func addBinaryWindow() []byte {
 	return []byte(text)
 }
 
+// addFileWindow uploads the file from the entered path and returns its blob ID and manifest
+func addFileWindow(blober service.Blober, secretKey []byte) (uuid.UUID, []byte, error) {
+	path, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter file path").WithMultiLine(false).Show()
+	spinner, _ := pterm.DefaultSpinner.Start("Uploading " + path)
+	blobID, file, err := blober.Upload(context.Background(), crypter.Key(secretKey), path)
+	if err != nil {
+		spinner.Fail(err)
+		return uuid.Nil, nil, err
+	}
+	spinner.Success(fmt.Sprintf("Uploaded %s, %d bytes", file.Name, file.Size))
+	data, err := json.Marshal(file)
+	return blobID, data, err
+}
+
+// saveFileWindow downloads the file of the secret to the entered path
+func saveFileWindow(blober service.Blober, secretKey []byte, secret models.Secret, plain crypter.PlainSecret) error {
+	var file clientmodels.File
+	if err := json.Unmarshal(plain.Value, &file); err != nil {
+		return err
+	}
+	path, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter path to save " + file.Name).WithMultiLine(false).Show()
+	spinner, _ := pterm.DefaultSpinner.Start("Downloading " + file.Name)
+	if err := blober.Download(context.Background(), crypter.Key(secretKey), secret.BlobID, file, path); err != nil {
+		spinner.Fail(err)
+		return err
+	}
+	spinner.Success("Saved to " + path)
+	return nil
+}
+
 // getSecret get secret from secret store
-func viewSecretWindow(storage keeperstorage.KeeperStorage, secretKey string, secrets []models.Secret) {
+// The metadata of the secrets is decrypted locally for display.
+func viewSecretWindow(storage keeperstorage.KeeperStorage, syncer service.Syncer, blober service.Blober, secretKey []byte, secrets []models.Secret) {
 	var viewSecrets []string
+	plainSecrets := make(map[string]crypter.PlainSecret)
 	for _, v := range secrets {
-		viewSecrets = append(viewSecrets, v.ID.String()+"\t"+v.Description)
+		plain, err := crypter.Open(secretKey, v)
+		if err != nil {
+			pterm.Error.Printfln("%s: %s", v.ID, err)
+			continue
+		}
+		plainSecrets[v.ID.String()] = plain
+		label := v.ID.String() + "\t" + plain.Type + "\t" + plain.Description
+		if v.ConflictOf != uuid.Nil {
+			label += "\t(conflicted copy of " + v.ConflictOf.String() + ")"
+		}
+		viewSecrets = append(viewSecrets, label)
+	}
+	if len(viewSecrets) == 0 {
+		pterm.Info.Println("No secrets")
+		return
 	}
 
 	selectedOption, _ := pterm.DefaultInteractiveSelect.WithDefaultText("Please select a secret").WithOptions(viewSecrets).Show()
@@ -211,44 +322,283 @@ func viewSecretWindow(storage keeperstorage.KeeperStorage, secretKey string, sec
 	uuidStr := strings.Split(selectedOption, "\t")[0]
 	for _, s := range secrets {
 		if uuidStr == s.ID.String() {
-			keyStr := []byte(secretKey)
-			iv := keyStr[len(keyStr)-aes.BlockSize:]
-			key := keyStr[:aes.BlockSize]
-			data, err := utils.Decrypt(s.Value, key, iv)
-			if err != nil {
-				pterm.Error.Println(err)
+			plain := plainSecrets[uuidStr]
+			if plain.Type == constatns.TypeFile {
+				var file clientmodels.File
+				if err := json.Unmarshal(plain.Value, &file); err == nil {
+					pterm.Info.Printfln("File: %s, %d bytes", file.Name, file.Size)
+				}
+			} else {
+				pterm.Info.Printfln("Secret: %s", string(plain.Value))
 			}
-			pterm.Info.Printfln("Secret: %s", string(data))
-			oneSecretWindow(storage, s)
+			oneSecretWindow(storage, syncer, blober, secretKey, s, plain)
 			return
 		}
 	}
 }
 
+// reencryptLegacyWindow asks for the private key of the secrets stored before the vault key
+// and seals them with the vault key. It asks again on the next unlock if the secrets are left as they are.
+func reencryptLegacyWindow(storage keeperstorage.KeeperStorage, login string, secretKey []byte) error {
+	legacy, corrupted, err := service.LegacySecrets(context.Background(), storage, login, secretKey)
+	if err != nil {
+		return err
+	}
+	for _, secret := range corrupted {
+		pterm.Warning.Printfln("secret %s is corrupted, it cannot be decrypted with the master password", secret.ID)
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	pterm.Info.Printfln("%d secrets are encrypted with your private key, they will be encrypted with the master password", len(legacy))
+	privateKey, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter private key: ").WithMultiLine(false).Show()
+	if err = service.ReencryptLegacySecrets(context.Background(), storage, legacy, secretKey, privateKey); err != nil {
+		return err
+	}
+	pterm.Info.Printfln("%d secrets encrypted with the master password", len(legacy))
+	return nil
+}
+
 // oneSecretWindow selected option models.Secret
-func oneSecretWindow(storage keeperstorage.KeeperStorage, secret models.Secret) {
+// An edited secret is sealed again, so legacy values and plaintext metadata are moved to the envelope format.
+// A conflicted copy can be resolved against its original. The history of the secret is kept on the server.
+func oneSecretWindow(storage keeperstorage.KeeperStorage, syncer service.Syncer, blober service.Blober, secretKey []byte, secret models.Secret, plain crypter.PlainSecret) {
 	closeOp := "close"
 	changeOp := "change description"
 	deleteOp := "delete"
+	historyOp := "history"
+	resolveOp := "resolve conflict"
+	saveOp := "save to path"
 	var options []string
 	options = append(options, closeOp)
+	if plain.Type == constatns.TypeFile {
+		options = append(options, saveOp)
+	}
 	options = append(options, changeOp)
 	options = append(options, deleteOp)
+	options = append(options, historyOp)
+	if secret.ConflictOf != uuid.Nil {
+		options = append(options, resolveOp)
+	}
 	selectedOption, _ := pterm.DefaultInteractiveSelect.WithOptions(options).Show()
 	if selectedOption == closeOp {
 		return
+	} else if selectedOption == saveOp {
+		err := saveFileWindow(blober, secretKey, secret, plain)
+		if err != nil {
+			pterm.Error.Println(err)
+		}
+	} else if selectedOption == historyOp {
+		err := historyWindow(syncer, secretKey, secret)
+		if err != nil {
+			pterm.Error.Println(err)
+		}
+	} else if selectedOption == resolveOp {
+		err := resolveConflictWindow(storage, secretKey, secret, plain)
+		if err != nil {
+			pterm.Error.Println(err)
+		}
 	} else if selectedOption == changeOp {
 		description, _ := pterm.DefaultInteractiveTextInput.WithDefaultText("Enter new description: ").WithMultiLine(false).Show()
-		secret.Description = description
+		plain.Description = description
 		secret.Ver = time.Now()
-		err := storage.PutSecret(context.Background(), secret)
+		secret, err := crypter.Seal(secretKey, secret, plain)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		err = storage.PutSecret(context.Background(), secret)
 		if err != nil {
 			pterm.Error.Println(err)
 		}
 	} else if selectedOption == deleteOp {
-		err := storage.DeleteSecret(context.Background(), secret.ID)
+		err := storage.DeleteSecret(context.Background(), secret.OwnerID, secret.ID)
 		if err != nil {
 			pterm.Error.Println(err)
 		}
 	}
 }
+
+// historyWindow browses the revisions of the secret kept on the server and restores the selected one.
+func historyWindow(syncer service.Syncer, secretKey []byte, secret models.Secret) error {
+	versions, err := syncer.VersionsService(secret.ID)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		pterm.Info.Println("No history, the secret is not synced yet")
+		return nil
+	}
+	var options []string
+	for _, v := range versions {
+		option := fmt.Sprintf("%d\t%s", v.Revision, v.Ver.Format(time.RFC3339))
+		if v.IsDeleted {
+			option += "\tdeleted"
+		} else if plain, err := crypter.Open(secretKey, v); err != nil {
+			option += "\t" + err.Error()
+		} else {
+			option += "\t" + plain.Description + "\t" + string(plain.Value)
+		}
+		options = append(options, option)
+	}
+	closeOp := "close"
+	options = append(options, closeOp)
+	selectedOption, _ := pterm.DefaultInteractiveSelect.WithDefaultText("Please select a revision to restore").WithOptions(options).Show()
+	if selectedOption == closeOp {
+		return nil
+	}
+	revision, err := strconv.ParseInt(strings.Split(selectedOption, "\t")[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	confirm, _ := pterm.DefaultInteractiveConfirm.WithDefaultText(fmt.Sprintf("Restore revision %d?", revision)).Show()
+	if !confirm {
+		return nil
+	}
+	if err = syncer.RestoreService(context.Background(), secret.ID, revision); err != nil {
+		return err
+	}
+	pterm.Info.Printfln("Revision %d restored", revision)
+	return nil
+}
+
+// sessionsWindow lists the sessions of the user and revokes the selected one or all the others,
+// for example after a device has been lost.
+func sessionsWindow(sessioner service.Sessioner) error {
+	sessions, err := sessioner.List()
+	if err != nil {
+		return err
+	}
+	var options []string
+	for _, session := range sessions {
+		option := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", session.ID, session.Device, session.IP,
+			session.LastSeen.Format(time.RFC3339), session.UserAgent)
+		if session.Current {
+			option += "\tcurrent"
+		}
+		if session.Revoked {
+			option += "\trevoked"
+		}
+		options = append(options, option)
+	}
+	revokeOthers := "revoke all other sessions"
+	closeOp := "close"
+	options = append(options, revokeOthers, closeOp)
+	selectedOption, _ := pterm.DefaultInteractiveSelect.WithDefaultText("Please select a session to revoke").WithOptions(options).Show()
+	switch selectedOption {
+	case closeOp:
+		return nil
+	case revokeOthers:
+		confirm, _ := pterm.DefaultInteractiveConfirm.WithDefaultText("Revoke all other sessions?").Show()
+		if !confirm {
+			return nil
+		}
+		if err = sessioner.RevokeOthers(); err != nil {
+			return err
+		}
+		pterm.Info.Println("All other sessions revoked")
+		return nil
+	}
+	sessionID := strings.Split(selectedOption, "\t")[0]
+	for _, session := range sessions {
+		if session.ID == sessionID && session.Current {
+			pterm.Info.Println("Use logout to end the current session")
+			return nil
+		}
+	}
+	confirm, _ := pterm.DefaultInteractiveConfirm.WithDefaultText(fmt.Sprintf("Revoke session %s?", sessionID)).Show()
+	if !confirm {
+		return nil
+	}
+	if err = sessioner.Revoke(sessionID); err != nil {
+		return err
+	}
+	pterm.Info.Printfln("Session %s revoked", sessionID)
+	return nil
+}
+
+// resolveConflictWindow resolves a conflicted copy against its original.
+// The user keeps the original, keeps the conflicted copy or merges them field by field.
+// The chosen content is stored into the original and the conflicted copy is deleted.
+func resolveConflictWindow(storage keeperstorage.KeeperStorage, secretKey []byte, conflicted models.Secret, conflictedPlain crypter.PlainSecret) error {
+	original, err := storage.GetSecret(context.Background(), conflicted.OwnerID, conflicted.ConflictOf)
+	if err != nil {
+		return err
+	}
+	originalPlain, err := crypter.Open(secretKey, original)
+	if err != nil {
+		return err
+	}
+	pterm.Info.Printfln("Original:\t%s\t%s\t%s", originalPlain.Type, originalPlain.Description, string(originalPlain.Value))
+	if original.IsDeleted {
+		pterm.Warning.Println("The original is deleted")
+	}
+	pterm.Info.Printfln("Conflicted copy:\t%s\t%s\t%s", conflictedPlain.Type, conflictedPlain.Description, string(conflictedPlain.Value))
+
+	keepOriginal := "keep original"
+	keepConflicted := "keep conflicted copy"
+	merge := "merge fields"
+	options := []string{keepOriginal, keepConflicted}
+	if originalPlain.Type == conflictedPlain.Type {
+		options = append(options, merge)
+	}
+	selectedOption, _ := pterm.DefaultInteractiveSelect.WithOptions(options).Show()
+	plain := originalPlain
+	if selectedOption == keepOriginal {
+		// the original stays as it is
+		return storage.DeleteSecret(context.Background(), conflicted.OwnerID, conflicted.ID)
+	} else if selectedOption == keepConflicted {
+		plain = conflictedPlain
+	} else if selectedOption == merge {
+		plain, err = mergeConflictWindow(originalPlain, conflictedPlain)
+		if err != nil {
+			return err
+		}
+	} else {
+		return errors.New("invalid option")
+	}
+	return conflict.Resolve(context.Background(), storage, secretKey, original, plain, conflicted)
+}
+
+// mergeConflictWindow asks which version of every differing field to keep.
+// Values that are not JSON objects of string fields are picked as a whole.
+func mergeConflictWindow(original crypter.PlainSecret, conflicted crypter.PlainSecret) (crypter.PlainSecret, error) {
+	merged := original
+	if original.Description != conflicted.Description && pickConflictedWindow("description", original.Description, conflicted.Description) {
+		merged.Description = conflicted.Description
+	}
+	fields, ok := conflict.DiffFields(original.Value, conflicted.Value)
+	if !ok {
+		if pickConflictedWindow("value", string(original.Value), string(conflicted.Value)) {
+			merged.Value = conflicted.Value
+		}
+		return merged, nil
+	}
+	originalFields, conflictedFields := make(map[string]string), make(map[string]string)
+	if err := json.Unmarshal(original.Value, &originalFields); err != nil {
+		return crypter.PlainSecret{}, err
+	}
+	if err := json.Unmarshal(conflicted.Value, &conflictedFields); err != nil {
+		return crypter.PlainSecret{}, err
+	}
+	var fromConflicted []string
+	for _, field := range fields {
+		if pickConflictedWindow(field, originalFields[field], conflictedFields[field]) {
+			fromConflicted = append(fromConflicted, field)
+		}
+	}
+	value, err := conflict.MergeFields(original.Value, conflicted.Value, fromConflicted)
+	if err != nil {
+		return crypter.PlainSecret{}, err
+	}
+	merged.Value = value
+	return merged, nil
+}
+
+// pickConflictedWindow asks which of two versions of a field to keep, it reports whether the conflicted copy was picked.
+func pickConflictedWindow(field string, original string, conflicted string) bool {
+	originalOption := "original: " + original
+	conflictedOption := "conflicted copy: " + conflicted
+	selectedOption, _ := pterm.DefaultInteractiveSelect.WithDefaultText("Select " + field).WithOptions([]string{originalOption, conflictedOption}).Show()
+	return selectedOption == conflictedOption
+}
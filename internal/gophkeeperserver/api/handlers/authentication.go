@@ -6,17 +6,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
+
 	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
-	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/utils"
 	"yudinsv/gophkeeper/internal/models"
 
-	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/gin-gonic/gin"
 )
 
@@ -36,11 +37,33 @@ import (
 // "password": "<password>"
 // }
 //
+// Every login starts a new session recording the device name from the X-Device-Name header,
+// the IP address and the user agent of the client.
+// The response contains a short-lived access token and a refresh token that is exchanged
+// for a new pair at /api/v1/token/refresh. The access token is also set in the Authorization header.
+//
+// Response format:
+//
+// {
+// "access_token": "<access token>",
+// "refresh_token": "<refresh token>",
+// "expires_in": <seconds until the access token expires>
+// }
+//
+// If the user has enabled the second factor, no session is started yet: the response only contains
+// "mfa_required": true and a restricted access token to send the code to /api/v1/2fa/verify.
+//
+// Failed logins are counted per login and per IP address. After a few failures every further attempt
+// has to wait twice as long as the previous one, up to a temporary lockout; such an attempt is rejected
+// with 429 and the Retry-After header without checking the password. Every attempt is counted before
+// the password is checked and taken back if it does not fail, so concurrent attempts cannot skip the wait.
+//
 // Possible response codes:
 //
 // 200 - user successfully authenticated;
 // 400 - invalid request format;
 // 401 - invalid login/password pair;
+// 429 - too many failed logins, retry after the Retry-After seconds;
 // 500 - internal server error.
 func authenticationHandler(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
@@ -55,25 +78,77 @@ func authenticationHandler(c *gin.Context) {
 		c.String(http.StatusBadRequest, "password or username is not correct")
 		return
 	}
+	retry, err := beginLoginAttempt(ctx, user.Login, c.ClientIP())
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if retry > 0 {
+		tooManyAttempts(c, retry)
+		return
+	}
 	authenticationUser, err := storage.AuthenticationUser(ctx, user)
 	if err != nil {
 		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
 		return
 	}
 	if !authenticationUser {
+		recordLoginFailure(failedPassword)
 		c.String(http.StatusUnauthorized, "password or username is not correct")
 		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &serverModels.Claims{
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: jwt.At(time.Now().Add(time.Hour * 100)),
-			IssuedAt:  jwt.At(time.Now())},
-		Login: user.Login,
-	})
-	accessToken, err := token.SignedString([]byte(container.GetConfig().SecretKey))
+	totp, err := storage.GetTOTP(ctx, user.Login)
+	if err != nil && !errors.Is(err, constans.ErrTOTPNotFound) {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if totp.Enabled {
+		if err = releaseLoginAttempt(ctx, user.Login, c.ClientIP()); err != nil {
+			log.Println(err)
+		}
+		tokens, err := issueMFAToken(user.Login)
+		if err != nil {
+			log.Println(err)
+			c.String(http.StatusInternalServerError, "error token generation")
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+		return
+	}
+	if err = resetLoginFailures(ctx, user.Login, c.ClientIP()); err != nil {
+		log.Println(err)
+	}
+	startSession(c, ctx, user.Login)
+}
+
+// startSession records a new session of the user, issues its tokens and writes them to the response.
+func startSession(c *gin.Context, ctx context.Context, login string) {
+	tokens, err := newSession(ctx, login, c.GetHeader(constants.DeviceNameHeader), c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.String(http.StatusInternalServerError, "error token generation")
+		log.Println(err)
+		c.String(http.StatusInternalServerError, "error starting the session")
 		return
 	}
-	c.Header("Authorization", "Bearer "+accessToken)
+	c.Header("Authorization", "Bearer "+tokens.AccessToken)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// newSession records a new session of the user from the device and issues its tokens.
+func newSession(ctx context.Context, login string, device string, ip string, userAgent string) (models.Tokens, error) {
+	now := time.Now()
+	session := models.Session{
+		ID:        utils.GeneratorStringUUID(),
+		Login:     login,
+		Device:    device,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+	if err := container.GetUserStorage().AddSession(ctx, session); err != nil {
+		return models.Tokens{}, err
+	}
+	return issueTokens(ctx, login, session.ID)
 }
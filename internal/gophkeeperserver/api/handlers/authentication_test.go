@@ -122,10 +122,14 @@ func TestAuthenticationHandler(t *testing.T) {
 			// Check response status code
 			assert.Equal(t, tc.expectedCode, w.Code)
 
-			// Check response body
-			assert.Equal(t, tc.expectedBody, w.Body.String())
-			// Check Authorization header if authentication was successful
-			if tc.expectedCode == http.StatusOK {
+			// Check Authorization header and tokens if authentication was successful
+			if tc.expectedCode != http.StatusOK {
+				assert.Equal(t, tc.expectedBody, w.Body.String())
+			} else {
+				var tokens models.Tokens
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+				assert.NotEmpty(t, tokens.RefreshToken)
+				assert.Equal(t, w.Header().Get("Authorization"), "Bearer "+tokens.AccessToken)
 				authHeader := w.Header().Get("Authorization")
 				assert.NotEmpty(t, authHeader, "authorization header should not be empty")
 				token := strings.TrimPrefix(authHeader, "Bearer ")
@@ -0,0 +1,250 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// createBlobHandler registers the blob of a file secret before its chunks are uploaded.
+// Handler: POST /api/v1/blobs
+//
+// Request format:
+//
+//	{
+//		"id": "<blob id chosen by the client>",
+//		"chunks": <number of chunks>
+//	}
+//
+// Creating a blob again returns its status, so an interrupted upload sends only the chunks missing from "uploaded".
+//
+// Response format:
+//
+//	{
+//		"id": "<blob id>",
+//		"chunks": <number of chunks>,
+//		"size": <bytes stored>,
+//		"complete": <all chunks stored and the blob completed>,
+//		"uploaded": [<indexes of the stored chunks>]
+//	}
+//
+// Possible response codes:
+//
+// 200 - blob created or already existing;
+// 400 - bad request;
+// 409 - another blob with the id exists;
+// 500 - internal server error.
+func createBlobHandler(c *gin.Context) {
+	var blob models.Blob
+	if err := c.ShouldBindJSON(&blob); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if blob.ID == uuid.Nil || blob.Chunks < 1 || blob.Chunks > constans.MaxBlobChunks {
+		c.String(http.StatusBadRequest, "invalid blob id or number of chunks")
+		return
+	}
+	blob.OwnerID = c.Param(constans.CookeUserIDName)
+	blob, err := container.GetBlobStorage().CreateBlob(c.Request.Context(), blob)
+	if err != nil {
+		if errors.Is(err, constans.ErrorNoUNIQUE) {
+			c.String(http.StatusConflict, err.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, blob)
+}
+
+// getBlobHandler returns the status of the blob in the createBlobHandler format.
+// Handler: GET /api/v1/blobs/:id
+//
+// Possible response codes:
+//
+// 200 - status retrieved;
+// 400 - invalid blob id;
+// 404 - blob not found;
+// 500 - internal server error.
+func getBlobHandler(c *gin.Context) {
+	blobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blob, err := container.GetBlobStorage().GetBlob(c.Request.Context(), c.Param(constans.CookeUserIDName), blobID)
+	if err != nil {
+		blobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, blob)
+}
+
+// putChunkHandler stores a chunk of the blob.
+// Handler: PUT /api/v1/blobs/:id/chunks/:index
+//
+// The body is the encrypted chunk (application/octet-stream) of at most constans.MaxChunkSize bytes,
+// the X-Chunk-SHA256 header is its hex SHA-256 digest. A chunk already stored is replaced.
+//
+// Possible response codes:
+//
+// 204 - chunk stored;
+// 400 - invalid blob id, index or digest header;
+// 404 - blob not found;
+// 409 - the blob is already completed;
+// 413 - the chunk is too large;
+// 422 - the chunk does not match the digest;
+// 500 - internal server error.
+func putChunkHandler(c *gin.Context) {
+	blobID, index, ok := chunkParams(c)
+	if !ok {
+		return
+	}
+	hash := strings.ToLower(c.GetHeader(constants.ChunkHashHeader))
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		c.String(http.StatusBadRequest, "invalid "+constants.ChunkHashHeader+" header")
+		return
+	}
+	ownerID := c.Param(constans.CookeUserIDName)
+	storage := container.GetBlobStorage()
+	blob, err := storage.GetBlob(c.Request.Context(), ownerID, blobID)
+	if err != nil {
+		blobError(c, err)
+		return
+	}
+	if index >= blob.Chunks {
+		c.String(http.StatusBadRequest, "chunk index out of range")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, constans.MaxChunkSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.String(http.StatusRequestEntityTooLarge, "chunk too large")
+			return
+		}
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+		c.String(http.StatusUnprocessableEntity, "chunk does not match "+constants.ChunkHashHeader)
+		return
+	}
+	err = storage.PutChunk(c.Request.Context(), ownerID, blobID, servermodels.Chunk{Index: index, Data: data, Hash: hash})
+	if err != nil {
+		blobError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// getChunkHandler returns a chunk of the blob with its digest in the X-Chunk-SHA256 header.
+// Handler: GET /api/v1/blobs/:id/chunks/:index
+//
+// Possible response codes:
+//
+// 200 - chunk retrieved;
+// 400 - invalid blob id or index;
+// 404 - blob or chunk not found;
+// 500 - internal server error.
+func getChunkHandler(c *gin.Context) {
+	blobID, index, ok := chunkParams(c)
+	if !ok {
+		return
+	}
+	chunk, err := container.GetBlobStorage().GetChunk(c.Request.Context(), c.Param(constans.CookeUserIDName), blobID, index)
+	if err != nil {
+		blobError(c, err)
+		return
+	}
+	c.Header(constants.ChunkHashHeader, chunk.Hash)
+	c.Data(http.StatusOK, "application/octet-stream", chunk.Data)
+}
+
+// completeBlobHandler completes the blob after all its chunks are uploaded,
+// only a complete blob can be referred to by a secret and it is not changed anymore.
+// Handler: POST /api/v1/blobs/:id/complete
+//
+// Possible response codes:
+//
+// 204 - blob completed;
+// 400 - invalid blob id;
+// 404 - blob not found;
+// 409 - some chunks are missing;
+// 500 - internal server error.
+func completeBlobHandler(c *gin.Context) {
+	blobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = container.GetBlobStorage().CompleteBlob(c.Request.Context(), c.Param(constans.CookeUserIDName), blobID); err != nil {
+		blobError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// chunkParams parses the blob id and the chunk index, on error it responds 400.
+func chunkParams(c *gin.Context) (uuid.UUID, int, bool) {
+	blobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return uuid.Nil, 0, false
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.String(http.StatusBadRequest, "invalid chunk index")
+		return uuid.Nil, 0, false
+	}
+	return blobID, index, true
+}
+
+// blobError responds with the status of the blob storage error.
+func blobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, constants.ErrBlobNotFound), errors.Is(err, constants.ErrChunkNotFound):
+		c.String(http.StatusNotFound, err.Error())
+	case errors.Is(err, constants.ErrBlobComplete), errors.Is(err, constants.ErrBlobIncomplete):
+		c.String(http.StatusConflict, err.Error())
+	default:
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+	}
+}
+
+// checkBlob checks that the blob the secret refers to is complete and owned by the owner of the secret,
+// so a synced secret never points to chunks the other devices cannot download.
+// It returns constants.ErrBlobNotFound or constants.ErrBlobIncomplete otherwise.
+func checkBlob(ctx context.Context, secret models.Secret) error {
+	if secret.BlobID == uuid.Nil || secret.IsDeleted {
+		return nil
+	}
+	blob, err := container.GetBlobStorage().GetBlob(ctx, secret.OwnerID, secret.BlobID)
+	if err != nil {
+		return err
+	}
+	if !blob.Complete {
+		return constants.ErrBlobIncomplete
+	}
+	return nil
+}
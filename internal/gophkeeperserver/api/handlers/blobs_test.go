@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobHandlers(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/:"+constans.CookeUserIDName+"/blobs", createBlobHandler)
+	router.GET("/:"+constans.CookeUserIDName+"/blobs/:id", getBlobHandler)
+	router.PUT("/:"+constans.CookeUserIDName+"/blobs/:id/chunks/:index", putChunkHandler)
+	router.GET("/:"+constans.CookeUserIDName+"/blobs/:id/chunks/:index", getChunkHandler)
+	router.POST("/:"+constans.CookeUserIDName+"/blobs/:id/complete", completeBlobHandler)
+	router.PUT("/:"+constans.CookeUserIDName+"/", putDataHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	do := func(method string, url string, body []byte, hash string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		if hash != "" {
+			req.Header.Set(constants.ChunkHashHeader, hash)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	digest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	blobID := uuid.New()
+	blobURL := "/owner/blobs/" + blobID.String()
+	chunks := [][]byte{[]byte("first chunk"), []byte("second chunk")}
+	request, _ := json.Marshal(models.Blob{ID: blobID, Chunks: len(chunks)})
+
+	w := do(http.MethodPost, "/owner/blobs", request, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do(http.MethodPost, "/other/blobs", request, "")
+	assert.Equal(t, http.StatusConflict, w.Code, "the id of a blob of another user")
+
+	// Uploading and the checks of a chunk
+	w = do(http.MethodPut, blobURL+"/chunks/1", chunks[1], digest(chunks[1]))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodPut, blobURL+"/chunks/0", chunks[0], digest(chunks[1]))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "corrupted chunk")
+	w = do(http.MethodPut, blobURL+"/chunks/0", chunks[0], "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "missing digest")
+	w = do(http.MethodPut, blobURL+"/chunks/2", chunks[0], digest(chunks[0]))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "index out of range")
+	large := bytes.Repeat([]byte{1}, constans.MaxChunkSize+1)
+	w = do(http.MethodPut, blobURL+"/chunks/0", large, digest(large))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	w = do(http.MethodPut, "/other/blobs/"+blobID.String()+"/chunks/0", chunks[0], digest(chunks[0]))
+	assert.Equal(t, http.StatusNotFound, w.Code, "blob of another user")
+
+	// The incomplete blob cannot be completed or referred to by a secret
+	w = do(http.MethodPost, blobURL+"/complete", nil, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	secret, _ := json.Marshal(models.Secret{ID: uuid.New(), Type: "file", Value: []byte("manifest"), BlobID: blobID})
+	w = do(http.MethodPut, "/owner/", secret, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Resuming lists the uploaded chunks
+	w = do(http.MethodPost, "/owner/blobs", request, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var blob models.Blob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &blob))
+	assert.Equal(t, []int{1}, blob.Uploaded)
+	assert.False(t, blob.Complete)
+
+	w = do(http.MethodPut, blobURL+"/chunks/0", chunks[0], strings.ToUpper(digest(chunks[0])))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodPost, blobURL+"/complete", nil, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodPut, blobURL+"/chunks/0", chunks[0], digest(chunks[0]))
+	assert.Equal(t, http.StatusConflict, w.Code, "the complete blob is immutable")
+
+	w = do(http.MethodGet, blobURL, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &blob))
+	assert.True(t, blob.Complete)
+	assert.Equal(t, int64(len(chunks[0])+len(chunks[1])), blob.Size)
+
+	w = do(http.MethodGet, blobURL+"/chunks/1", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, chunks[1], w.Body.Bytes())
+	assert.Equal(t, digest(chunks[1]), w.Header().Get(constants.ChunkHashHeader))
+	w = do(http.MethodGet, "/other/blobs/"+blobID.String()+"/chunks/1", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodPut, "/owner/", secret, "")
+	assert.Equal(t, http.StatusOK, w.Code, "the secret refers to the complete blob")
+}
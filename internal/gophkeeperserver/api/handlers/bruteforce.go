@@ -0,0 +1,126 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reasons of the failed logins counted by failedLogins.
+const (
+	failedPassword     = "password"
+	failedSecondFactor = "second_factor"
+	failedLocked       = "locked"
+)
+
+// failedLogins counts the failed logins by reason, exported on /metrics.
+var failedLogins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gophkeeper_failed_logins_total",
+	Help: "Failed logins by reason: wrong password, wrong second factor or rejected while locked.",
+}, []string{"reason"})
+
+// beginLoginAttempt counts the attempt with the login from the IP address as a failure before the password
+// is checked and returns how long it has to wait, zero if it is allowed now. The back-off is decided on the
+// failures before this attempt, so the concurrent attempts are counted one by one and cannot all pass it.
+// An attempt that has to wait is taken back at once, it does not extend the delay.
+// An allowed attempt that does not fail is taken back with releaseLoginAttempt or resetLoginFailures.
+func beginLoginAttempt(ctx context.Context, login string, ip string) (time.Duration, error) {
+	storage := container.GetAttemptStorage()
+	now := time.Now()
+	byLogin, err := storage.AddFailure(ctx, loginAttemptKey(login), now, constans.LoginAttemptsWindow)
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := storage.AddFailure(ctx, ipAttemptKey(ip), now, constans.LoginAttemptsWindow)
+	if err != nil {
+		return 0, err
+	}
+	retry := backoff(beforeLast(byLogin), constans.LoginFreeAttempts, now)
+	if ipRetry := backoff(beforeLast(byIP), constans.IPFreeAttempts, now); ipRetry > retry {
+		retry = ipRetry
+	}
+	if retry > 0 {
+		return retry, releaseLoginAttempt(ctx, login, ip)
+	}
+	return 0, nil
+}
+
+// beforeLast returns the attempts as they were before the last failure.
+func beforeLast(attempts serverModels.LoginAttempts) serverModels.LoginAttempts {
+	return serverModels.LoginAttempts{Failures: attempts.Failures - 1, LastFailure: attempts.PreviousFailure}
+}
+
+// backoff returns the time left until the next attempt is allowed after the failures.
+// Every failure over the free attempts doubles the delay up to the lockout of constans.LoginBackoffMax.
+func backoff(attempts serverModels.LoginAttempts, free int, now time.Time) time.Duration {
+	if attempts.Failures < free || now.Sub(attempts.LastFailure) > constans.LoginAttemptsWindow {
+		return 0
+	}
+	delay := constans.LoginBackoffBase
+	for i := free; i < attempts.Failures && delay < constans.LoginBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > constans.LoginBackoffMax {
+		delay = constans.LoginBackoffMax
+	}
+	if left := attempts.LastFailure.Add(delay).Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// recordLoginFailure counts a failed login on /metrics, the failure itself was counted by beginLoginAttempt.
+func recordLoginFailure(reason string) {
+	failedLogins.WithLabelValues(reason).Inc()
+}
+
+// releaseLoginAttempt takes back the attempt counted by beginLoginAttempt, for example when the password
+// is right but the second factor is still to be checked.
+func releaseLoginAttempt(ctx context.Context, login string, ip string) error {
+	storage := container.GetAttemptStorage()
+	if err := storage.ReleaseFailure(ctx, loginAttemptKey(login)); err != nil {
+		return err
+	}
+	return storage.ReleaseFailure(ctx, ipAttemptKey(ip))
+}
+
+// resetLoginFailures forgets the failed logins with the login after a successful login.
+// The failures from the IP address are kept: a successful login to another account does not excuse them,
+// only the attempt counted for this login is taken back.
+func resetLoginFailures(ctx context.Context, login string, ip string) error {
+	storage := container.GetAttemptStorage()
+	if err := storage.ResetAttempts(ctx, loginAttemptKey(login)); err != nil {
+		return err
+	}
+	return storage.ReleaseFailure(ctx, ipAttemptKey(ip))
+}
+
+// tooManyAttempts rejects the login with 429 and the Retry-After header in whole seconds.
+func tooManyAttempts(c *gin.Context, retry time.Duration) {
+	failedLogins.WithLabelValues(failedLocked).Inc()
+	seconds := int64((retry + time.Second - 1) / time.Second)
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.String(http.StatusTooManyRequests, "too many login attempts, retry after %d seconds", seconds)
+}
+
+// loginAttemptKey is the key of the failed logins with the login.
+func loginAttemptKey(login string) string {
+	return "login:" + login
+}
+
+// ipAttemptKey is the key of the failed logins from the IP address.
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		attempts serverModels.LoginAttempts
+		want     time.Duration
+	}{
+		{"free attempts", serverModels.LoginAttempts{Failures: 4, LastFailure: now}, 0},
+		{"first delay", serverModels.LoginAttempts{Failures: 5, LastFailure: now}, time.Second},
+		{"doubled delay", serverModels.LoginAttempts{Failures: 7, LastFailure: now}, 4 * time.Second},
+		{"partly waited", serverModels.LoginAttempts{Failures: 7, LastFailure: now.Add(-time.Second)}, 3 * time.Second},
+		{"lockout", serverModels.LoginAttempts{Failures: 100, LastFailure: now}, constans.LoginBackoffMax},
+		{"forgotten", serverModels.LoginAttempts{Failures: 100, LastFailure: now.Add(-2 * constans.LoginAttemptsWindow)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, backoff(tt.attempts, constans.LoginFreeAttempts, now))
+		})
+	}
+}
+
+func TestAuthenticationHandlerLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/auth", authenticationHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	user := models.User{Login: "locked-user", Password: "testpass"}
+	if err = container.GetUserStorage().AddUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	login := func(user models.User) *httptest.ResponseRecorder {
+		jsonData, err := json.Marshal(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, "/auth", bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	failed := testutil.ToFloat64(failedLogins.WithLabelValues(failedPassword))
+	locked := testutil.ToFloat64(failedLogins.WithLabelValues(failedLocked))
+	wrong := models.User{Login: user.Login, Password: "wrongpass"}
+	for i := 0; i < constans.LoginFreeAttempts; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(wrong).Code)
+	}
+	assert.Equal(t, failed+constans.LoginFreeAttempts, testutil.ToFloat64(failedLogins.WithLabelValues(failedPassword)))
+
+	// Even the right password waits for the delay.
+	w := login(user)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, locked+1, testutil.ToFloat64(failedLogins.WithLabelValues(failedLocked)))
+
+	time.Sleep(constans.LoginBackoffBase)
+	assert.Equal(t, http.StatusOK, login(user).Code)
+	attempts, err := container.GetAttemptStorage().GetAttempts(context.Background(), loginAttemptKey(user.Login))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, attempts.Failures, "a successful login forgets the failures")
+}
+
+func TestAuthenticationHandlerConcurrentAttempts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/auth", authenticationHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	user := models.User{Login: "guessed-user", Password: "testpass"}
+	if err = container.GetUserStorage().AddUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(models.User{Login: user.Login, Password: "wrongpass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Guesses sent at once are counted one by one, only the free attempts check the password.
+	const guesses = 3 * constans.LoginFreeAttempts
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodPost, "/auth", bytes.NewReader(jsonData))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, constans.LoginFreeAttempts, counts[http.StatusUnauthorized])
+	assert.Equal(t, guesses-constans.LoginFreeAttempts, counts[http.StatusTooManyRequests])
+	attempts, err := container.GetAttemptStorage().GetAttempts(context.Background(), loginAttemptKey(user.Login))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, constans.LoginFreeAttempts, attempts.Failures, "the rejected attempts are not counted")
+}
+
+func TestAuthenticationHandlerForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	login := func(router *gin.Engine, forwardedFor string) {
+		jsonData, err := json.Marshal(models.User{Login: "unknown-user", Password: "wrongpass"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "192.0.2.1:4321"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	failures := func(ip string) int {
+		attempts, err := container.GetAttemptStorage().GetAttempts(context.Background(), ipAttemptKey(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attempts.Failures
+	}
+
+	// Without trusted proxies a forged header does not change the IP the attempts are counted by
+	router, err := Router(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login(router, "198.51.100.1")
+	login(router, "198.51.100.2")
+	assert.Equal(t, 2, failures("192.0.2.1"))
+	assert.Zero(t, failures("198.51.100.1"))
+
+	// Behind a trusted proxy the forwarded IP is the client
+	router, err = Router([]string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	login(router, "198.51.100.3")
+	assert.Equal(t, 1, failures("198.51.100.3"))
+	assert.Equal(t, 2, failures("192.0.2.1"))
+}
@@ -0,0 +1,72 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// changesHandler handles delta sync requests.
+// Handler: GET /api/v1/changes?since=<cursor>.
+//
+// The handler returns the secrets of the authenticated user changed or deleted after the cursor,
+// together with the cursor for the next request. A missing since asks for all secrets.
+// At most constans.ChangesPageSize secrets are returned, has_more asks to request again with the cursor.
+//
+// Response format:
+//
+//	{
+//		"secrets": [<models.LiteSecret>, ...],
+//		"cursor": <cursor>,
+//		"has_more": <more changes follow>
+//	}
+//
+// Possible response codes:
+//
+// 200 - changes successfully retrieved;
+// 400 - invalid cursor;
+// 500 - internal server error.
+func changesHandler(c *gin.Context) {
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		c.String(http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	userID := c.Param(constans.CookeUserIDName)
+	changes, err := changesPage(c.Request.Context(), userID, since)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// changesPage reads a page of the changes of the user after the cursor.
+// One secret more than the page is read to tell whether more changes follow.
+func changesPage(ctx context.Context, userID string, since int64) (models.Changes, error) {
+	liteSecrets, cursor, err := container.GetKeeperStorage().Changes(ctx, userID, since, constans.ChangesPageSize+1)
+	if err != nil {
+		return models.Changes{}, err
+	}
+	changes := models.Changes{Secrets: liteSecrets, Cursor: cursor}
+	if len(liteSecrets) > constans.ChangesPageSize {
+		changes.Secrets = liteSecrets[:constans.ChangesPageSize]
+		changes.Cursor = changes.Secrets[constans.ChangesPageSize-1].Seq
+		changes.HasMore = true
+	}
+	if changes.Secrets == nil {
+		changes.Secrets = []models.LiteSecret{}
+	}
+	return changes, nil
+}
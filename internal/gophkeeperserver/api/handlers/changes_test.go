@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangesHandler(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:"+constans.CookeUserIDName, changesHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store two secrets of a test user and delete the first one
+	first := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("first"), Type: "text"}
+	second := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("second"), Type: "text"}
+	for _, secret := range []models.Secret{first, second} {
+		if err = container.GetKeeperStorage().PutSecret(context.Background(), secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = container.GetKeeperStorage().DeleteSecret(context.Background(), "owner", first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expectedIDs  []uuid.UUID
+		expectedCur  int64
+	}{
+		{
+			name:         "All changes",
+			url:          "/owner",
+			expectedCode: http.StatusOK,
+			expectedIDs:  []uuid.UUID{second.ID, first.ID},
+			expectedCur:  3,
+		},
+		{
+			name:         "Changes after cursor",
+			url:          "/owner?since=2",
+			expectedCode: http.StatusOK,
+			expectedIDs:  []uuid.UUID{first.ID},
+			expectedCur:  3,
+		},
+		{
+			name:         "No changes",
+			url:          "/owner?since=3",
+			expectedCode: http.StatusOK,
+			expectedIDs:  []uuid.UUID{},
+			expectedCur:  3,
+		},
+		{
+			name:         "Other owner",
+			url:          "/intruder",
+			expectedCode: http.StatusOK,
+			expectedIDs:  []uuid.UUID{},
+			expectedCur:  0,
+		},
+		{
+			name:         "Invalid cursor",
+			url:          "/owner?since=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Negative cursor",
+			url:          "/owner?since=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			var changes models.Changes
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+			assert.Equal(t, tc.expectedCur, changes.Cursor)
+			ids := []uuid.UUID{}
+			for _, liteSecret := range changes.Secrets {
+				ids = append(ids, liteSecret.ID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+}
+
+func TestChangesHandler_Pages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:"+constans.CookeUserIDName, changesHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store one secret more than fits a page
+	for i := 0; i <= constans.ChangesPageSize; i++ {
+		secret := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("value"), Type: "text"}
+		if err = container.GetKeeperStorage().PutSecret(context.Background(), secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(url string) models.Changes {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var changes models.Changes
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+		return changes
+	}
+
+	changes := get("/owner")
+	assert.Len(t, changes.Secrets, constans.ChangesPageSize)
+	assert.True(t, changes.HasMore)
+	assert.Equal(t, int64(constans.ChangesPageSize), changes.Cursor)
+
+	changes = get(fmt.Sprintf("/owner?since=%d", changes.Cursor))
+	assert.Len(t, changes.Secrets, 1)
+	assert.False(t, changes.HasMore)
+	assert.Equal(t, int64(constans.ChangesPageSize+1), changes.Cursor)
+}
@@ -1,5 +1,111 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
 
-func deleteDataHandler(c *gin.Context) {}
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// deleteDataHandler handles requests for deleting a secret.
+// Handler: DELETE /api/v1/
+//
+// The handler retrieves the secretID from the request body and marks the secret
+// of the authenticated user as deleted, so the tombstone reaches the other devices on sync.
+// With the If-Match header the secret is deleted only if the server copy is still at the base revision
+// in the header, an edit made meanwhile on another device is never deleted unseen.
+// Without it the current revision is deleted. The revision of the tombstone is returned in the ETag header.
+// Secrets owned by other users are reported as not found.
+//
+// Request format:
+//
+//	"<secret ID>"
+//
+// Possible response codes:
+//
+// 204 - secret successfully deleted;
+// 400 - invalid request body or If-Match header;
+// 404 - secret not found or already deleted;
+// 412 - the secret was changed after the base revision;
+// 500 - internal server error.
+func deleteDataHandler(c *gin.Context) {
+	var secretID uuid.UUID
+	if err := c.ShouldBindJSON(&secretID); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	storage := container.GetKeeperStorage()
+	userID := c.Param(constans.CookeUserIDName)
+	baseRevision, conditional, err := ifMatchRevision(c.GetHeader("If-Match"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !conditional {
+		// An unconditional delete is a delete of the current revision
+		current, err := storage.GetSecret(c.Request.Context(), userID, secretID)
+		if err == nil && current.IsDeleted {
+			err = constants.ErrSecretNotFound
+		}
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+			return
+		}
+		baseRevision = current.Revision
+	}
+	revision, err := storage.CompareAndDeleteSecret(c.Request.Context(), userID, secretID, baseRevision)
+	if err != nil {
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
+			return
+		}
+		if errors.Is(err, constants.ErrRevisionConflict) {
+			c.String(http.StatusPreconditionFailed, constants.ErrRevisionConflict.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	publishChange(c.Request.Context(), models.ChangeEvent{OwnerID: userID, SecretID: secretID, Revision: revision, Deleted: true})
+	c.Header("ETag", revisionETag(revision))
+	c.Status(http.StatusNoContent)
+}
+
+// revisionETag formats the revision of a secret as an entity tag.
+func revisionETag(revision int64) string {
+	return strconv.Quote(strconv.FormatInt(revision, 10))
+}
+
+// ifMatchRevision parses the revision of the If-Match header, an empty header is no condition.
+func ifMatchRevision(header string) (int64, bool, error) {
+	if header == "" {
+		return 0, false, nil
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid If-Match %s", header)
+	}
+	revision, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, false, fmt.Errorf("invalid If-Match %s", header)
+	}
+	return revision, true, nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteDataHandler(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.DELETE("/:"+constans.CookeUserIDName, deleteDataHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store a secret of a test user at revision 2
+	secret := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("value"), Type: "text"}
+	for i := int64(0); i < 2; i++ {
+		if _, err = container.GetKeeperStorage().CompareAndPutSecret(context.Background(), secret, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		userID       string
+		body         interface{}
+		ifMatch      string
+		expectedCode int
+		expectedETag string
+	}{
+		{
+			name:         "Invalid body",
+			userID:       "owner",
+			body:         "not-a-uuid",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid If-Match",
+			userID:       "owner",
+			body:         secret.ID,
+			ifMatch:      "*",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Another user deletes the secret",
+			userID:       "intruder",
+			body:         secret.ID,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Owner deletes a stale revision",
+			userID:       "owner",
+			body:         secret.ID,
+			ifMatch:      `"1"`,
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "Owner deletes the secret",
+			userID:       "owner",
+			body:         secret.ID,
+			ifMatch:      `"2"`,
+			expectedCode: http.StatusNoContent,
+			expectedETag: `"3"`,
+		},
+		{
+			name:         "Secret already deleted",
+			userID:       "owner",
+			body:         secret.ID,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonData, err := json.Marshal(tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(http.MethodDelete, "/"+tc.userID, bytes.NewBuffer(jsonData))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedETag, w.Header().Get("ETag"))
+		})
+	}
+
+	// The secret must stay as a tombstone
+	stored, err := container.GetKeeperStorage().GetSecret(context.Background(), "owner", secret.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.IsDeleted)
+	assert.Equal(t, int64(3), stored.Revision)
+
+	// A client without the base revision deletes the current revision
+	other := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("value"), Type: "text"}
+	if _, err = container.GetKeeperStorage().CompareAndPutSecret(context.Background(), other, 0); err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodDelete, "/owner", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,72 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventsHandler streams the change events of the authenticated user as server-sent events.
+// Handler: GET /api/v1/events
+//
+// The stream starts with a "ready" event, the device pulls the changes it may have missed while disconnected.
+// Every change of a secret of the user on any server instance is sent as a "change" event:
+//
+//	event: change
+//	data: {"secret_id": "<secret id>", "revision": <new revision>, "deleted": <deleted>}
+//
+// An idle stream gets a comment every constans.EventsHeartbeat. The stream is closed after
+// constans.EventsMaxDuration, the device reconnects with a fresh access token.
+//
+// Possible response codes:
+//
+// 200 - the stream is open;
+// 401 - the access token is invalid.
+func eventsHandler(c *gin.Context) {
+	events, unsubscribe := container.GetBroker().Subscribe(c.Param(constans.CookeUserIDName))
+	defer unsubscribe()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent("ready", "{}")
+	c.Writer.Flush()
+	heartbeat := time.NewTicker(constans.EventsHeartbeat)
+	defer heartbeat.Stop()
+	expire := time.NewTimer(constans.EventsMaxDuration)
+	defer expire.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-expire.C:
+			return
+		case event := <-events:
+			c.SSEvent("change", event)
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// publishChange notifies the devices of the owner of the change, the change is stored already,
+// so a failure is only logged and the devices find the change by polling.
+func publishChange(ctx context.Context, event models.ChangeEvent) {
+	if err := container.GetBroker().Publish(ctx, event); err != nil {
+		log.Println("error publishing the change event", err)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsHandler(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/:"+constans.CookeUserIDName+"/events", eventsHandler)
+	router.PUT("/:"+constans.CookeUserIDName+"/", putDataHandler)
+	router.DELETE("/:"+constans.CookeUserIDName+"/", deleteDataHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/owner/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	next := func() (string, string) {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimRight(line, "\n")
+			if line == "" && name != "" {
+				return name, data
+			}
+			if strings.HasPrefix(line, "event:") {
+				name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			}
+			if strings.HasPrefix(line, "data:") {
+				data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			}
+		}
+	}
+	name, _ := next()
+	assert.Equal(t, "ready", name)
+
+	request := func(method string, user string, body interface{}) {
+		marshal, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, server.URL+"/"+user+"/", bytes.NewReader(marshal))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// The change of another user is not sent
+	request(http.MethodPut, "other", models.Secret{ID: uuid.New(), Value: []byte("value"), Type: "text"})
+	secret := models.Secret{ID: uuid.New(), Value: []byte("value"), Type: "text"}
+	request(http.MethodPut, "owner", secret)
+	name, data := next()
+	assert.Equal(t, "change", name)
+	var event models.ChangeEvent
+	assert.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Equal(t, models.ChangeEvent{SecretID: secret.ID, Revision: 1}, event)
+
+	request(http.MethodDelete, "owner", secret.ID)
+	name, data = next()
+	assert.Equal(t, "change", name)
+	assert.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Equal(t, models.ChangeEvent{SecretID: secret.ID, Revision: 2, Deleted: true}, event)
+}
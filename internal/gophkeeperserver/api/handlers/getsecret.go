@@ -5,6 +5,7 @@ package handlers
 
 import (
 	"errors"
+	"log"
 	"net/http"
 
 	"yudinsv/gophkeeper/internal/constants"
@@ -18,14 +19,15 @@ import (
 // getDataHandler handles requests for retrieving a secret.
 // Handler: POST /api/v1/
 //
-// The handler retrieves the secretID from the request body and uses it to get the secret from the storage.
-// If the secret is not found, a 204 No Content response is returned.
+// The handler retrieves the secretID from the request body and uses it to get the secret
+// of the authenticated user from the storage.
+// Secrets owned by other users are reported as not found.
 //
 // Possible response codes:
 //
 // 200 - secret successfully retrieved;
-// 204 - secret not found;
 // 400 - invalid request body;
+// 404 - secret not found;
 // 500 - internal server error.
 func getDataHandler(c *gin.Context) {
 	var secretID uuid.UUID
@@ -34,13 +36,15 @@ func getDataHandler(c *gin.Context) {
 		return
 	}
 	storage := container.GetKeeperStorage()
-	secret, err := storage.GetSecret(c.Request.Context(), secretID)
+	userID := c.Param(constans.CookeUserIDName)
+	secret, err := storage.GetSecret(c.Request.Context(), userID, secretID)
 	if err != nil {
-		if errors.Is(constants.ErrSecretNotFound, err) {
-			c.String(http.StatusNoContent, "")
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
 			return
 		}
-		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase, err.Error())
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
 		return
 	}
 	c.JSON(http.StatusOK, secret)
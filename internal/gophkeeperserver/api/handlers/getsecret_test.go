@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDataHandler(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/:"+constans.CookeUserIDName, getDataHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store a secret of a test user
+	secret := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("value"), Type: "text"}
+	if err = container.GetKeeperStorage().PutSecret(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		userID       string
+		secretID     uuid.UUID
+		expectedCode int
+	}{
+		{
+			name:         "Owner reads the secret",
+			userID:       "owner",
+			secretID:     secret.ID,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Another user reads the secret",
+			userID:       "intruder",
+			secretID:     secret.ID,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Non-existent secret",
+			userID:       "owner",
+			secretID:     uuid.New(),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonData, err := json.Marshal(tc.secretID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(http.MethodPost, "/"+tc.userID, bytes.NewBuffer(jsonData))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode == http.StatusOK {
+				var got models.Secret
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, secret.Value, got.Value)
+			}
+		})
+	}
+}
+
+func TestPutDataHandler(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PUT("/:"+constans.CookeUserIDName, putDataHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store a secret of a test user
+	secret := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("value"), Type: "text"}
+	if err = container.GetKeeperStorage().PutSecret(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		userID       string
+		secret       models.Secret
+		expectedCode int
+	}{
+		{
+			name:         "Another user overwrites the secret",
+			userID:       "intruder",
+			secret:       models.Secret{ID: secret.ID, OwnerID: "owner", Value: []byte("stolen"), Type: "text"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Owner updates the secret",
+			userID:       "owner",
+			secret:       models.Secret{ID: secret.ID, Value: []byte("updated"), Type: "text"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Owner updates a stale revision",
+			userID:       "owner",
+			secret:       models.Secret{ID: secret.ID, Value: []byte("stale"), Type: "text"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Owner updates the current revision",
+			userID:       "owner",
+			secret:       models.Secret{ID: secret.ID, Value: []byte("updated"), Type: "text", Revision: 1},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	// Run tests
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonData, err := json.Marshal(tc.secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(http.MethodPut, "/"+tc.userID, bytes.NewBuffer(jsonData))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+
+	// The owner must see the update and the owner_id from the body must be ignored
+	stored, err := container.GetKeeperStorage().GetSecret(context.Background(), "owner", secret.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("updated"), stored.Value)
+	assert.Equal(t, "owner", stored.OwnerID)
+	assert.Equal(t, int64(2), stored.Revision)
+}
@@ -0,0 +1,266 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests,
+// the gRPC Keeper service serves the same storages to the gRPC clients.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/middleware"
+	"yudinsv/gophkeeper/internal/models"
+	pb "yudinsv/gophkeeper/internal/proto"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// retryAfterMetadata is the trailer with the seconds to wait after a login rejected with RESOURCE_EXHAUSTED.
+const retryAfterMetadata = "retry-after"
+
+// GRPCServer creates the gRPC server of the Keeper service, the counterpart of Router.
+// The calls are authenticated by the JWT interceptors of the middleware.
+func GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(middleware.JwtUnaryInterceptor()),
+		grpc.ChainStreamInterceptor(middleware.JwtStreamInterceptor()))
+	srv := grpc.NewServer(opts...)
+	pb.RegisterKeeperServer(srv, &keeperServer{})
+	return srv
+}
+
+// keeperServer implements the Keeper service with the storages of the container,
+// it behaves like the REST handlers of the same operations.
+type keeperServer struct {
+	pb.UnimplementedKeeperServer
+}
+
+// Ping answers when the server is up.
+func (s *keeperServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+// Register creates the user and starts a session.
+//
+// Possible status codes: INVALID_ARGUMENT - empty login or password; ALREADY_EXISTS - the login is taken.
+func (s *keeperServer) Register(ctx context.Context, credentials *pb.Credentials) (*pb.Tokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, constans.TimeOutRequest)
+	defer cancel()
+	if credentials.GetLogin() == "" || credentials.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "login or password is empty")
+	}
+	err := container.GetUserStorage().AddUser(ctx, models.User{Login: credentials.GetLogin(), Password: credentials.GetPassword()})
+	if err != nil {
+		if errors.Is(err, constans.ErrorNoUNIQUE) {
+			return nil, status.Error(codes.AlreadyExists, "there is already a user with this login")
+		}
+		log.Println(err)
+		return nil, status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+	return s.startSession(ctx, credentials.GetLogin())
+}
+
+// Login authenticates the user like authenticationHandler, including the throttling of the failed logins.
+//
+// Possible status codes: UNAUTHENTICATED - invalid login/password pair;
+// RESOURCE_EXHAUSTED - too many failed logins, retry after the seconds of the "retry-after" trailer.
+func (s *keeperServer) Login(ctx context.Context, credentials *pb.Credentials) (*pb.Tokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, constans.TimeOutRequest)
+	defer cancel()
+	ip := middleware.PeerIP(ctx)
+	retry, err := beginLoginAttempt(ctx, credentials.GetLogin(), ip)
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+	if retry > 0 {
+		failedLogins.WithLabelValues(failedLocked).Inc()
+		seconds := int64((retry + time.Second - 1) / time.Second)
+		if err = grpc.SetTrailer(ctx, metadata.Pairs(retryAfterMetadata, fmt.Sprint(seconds))); err != nil {
+			log.Println(err)
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "too many login attempts, retry after %d seconds", seconds)
+	}
+	storage := container.GetUserStorage()
+	authenticated, err := storage.AuthenticationUser(ctx, models.User{Login: credentials.GetLogin(), Password: credentials.GetPassword()})
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+	if !authenticated {
+		recordLoginFailure(failedPassword)
+		return nil, status.Error(codes.Unauthenticated, "password or username is not correct")
+	}
+	totp, err := storage.GetTOTP(ctx, credentials.GetLogin())
+	if err != nil && !errors.Is(err, constans.ErrTOTPNotFound) {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+	if totp.Enabled {
+		if err = releaseLoginAttempt(ctx, credentials.GetLogin(), ip); err != nil {
+			log.Println(err)
+		}
+		tokens, err := issueMFAToken(credentials.GetLogin())
+		if err != nil {
+			log.Println(err)
+			return nil, status.Error(codes.Internal, "error token generation")
+		}
+		return pb.FromTokens(tokens), nil
+	}
+	if err = resetLoginFailures(ctx, credentials.GetLogin(), ip); err != nil {
+		log.Println(err)
+	}
+	return s.startSession(ctx, credentials.GetLogin())
+}
+
+// PutSecret stores the secret of the authenticated user like putDataHandler.
+//
+// Possible status codes: INVALID_ARGUMENT - invalid secret; NOT_FOUND - secret not found;
+// ABORTED - the secret was changed after the base revision;
+// FAILED_PRECONDITION - the blob of the file secret is not uploaded.
+func (s *keeperServer) PutSecret(ctx context.Context, message *pb.Secret) (*pb.Revision, error) {
+	claims, _ := middleware.ClaimsFromContext(ctx)
+	secret, err := pb.ToSecret(message, claims.Login)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = checkBlob(ctx, secret); err != nil {
+		if errors.Is(err, constants.ErrBlobNotFound) || errors.Is(err, constants.ErrBlobIncomplete) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, secretStatus(err)
+	}
+	revision, err := container.GetKeeperStorage().CompareAndPutSecret(ctx, secret, secret.Revision)
+	if err != nil {
+		return nil, secretStatus(err)
+	}
+	publishChange(ctx, models.ChangeEvent{OwnerID: secret.OwnerID, SecretID: secret.ID, Revision: revision})
+	return &pb.Revision{Revision: revision}, nil
+}
+
+// GetSecret returns the secret of the authenticated user like getDataHandler.
+//
+// Possible status codes: INVALID_ARGUMENT - invalid secret ID; NOT_FOUND - secret not found.
+func (s *keeperServer) GetSecret(ctx context.Context, message *pb.SecretID) (*pb.Secret, error) {
+	claims, _ := middleware.ClaimsFromContext(ctx)
+	secretID, err := uuid.Parse(message.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	secret, err := container.GetKeeperStorage().GetSecret(ctx, claims.Login, secretID)
+	if err != nil {
+		return nil, secretStatus(err)
+	}
+	return pb.FromSecret(secret), nil
+}
+
+// DeleteSecret turns the secret of the authenticated user into a tombstone if the server copy is still
+// at the base revision of the request, like deleteDataHandler.
+//
+// Possible status codes: INVALID_ARGUMENT - invalid secret ID; NOT_FOUND - secret not found or already deleted;
+// ABORTED - the secret was changed after the base revision.
+func (s *keeperServer) DeleteSecret(ctx context.Context, message *pb.DeleteRequest) (*pb.Revision, error) {
+	claims, _ := middleware.ClaimsFromContext(ctx)
+	secretID, err := uuid.Parse(message.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	revision, err := container.GetKeeperStorage().CompareAndDeleteSecret(ctx, claims.Login, secretID, message.GetRevision())
+	if err != nil {
+		return nil, secretStatus(err)
+	}
+	publishChange(ctx, models.ChangeEvent{OwnerID: claims.Login, SecretID: secretID, Revision: revision, Deleted: true})
+	return &pb.Revision{Revision: revision}, nil
+}
+
+// Changes streams the secrets of the authenticated user changed after the cursor like changesHandler.
+// The first batch is always sent, so the client gets the current cursor. The changes are sent in batches
+// of at most constans.ChangesPageSize secrets until all of them are sent. A followed feed then checks
+// the storage on every change event of the user, and every constans.ChangesPollInterval in case an event
+// is missed, and sends the new changes until the client cancels.
+//
+// Possible status codes: INVALID_ARGUMENT - invalid cursor.
+func (s *keeperServer) Changes(request *pb.ChangesRequest, stream pb.Keeper_ChangesServer) error {
+	ctx := stream.Context()
+	claims, _ := middleware.ClaimsFromContext(ctx)
+	if request.GetSince() < 0 {
+		return status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+	cursor := request.GetSince()
+	var events <-chan models.ChangeEvent
+	if request.GetFollow() {
+		var unsubscribe func()
+		events, unsubscribe = container.GetBroker().Subscribe(claims.Login)
+		defer unsubscribe()
+	}
+	ticker := time.NewTicker(constans.ChangesPollInterval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		changes, err := changesPage(ctx, claims.Login, cursor)
+		if err != nil {
+			log.Println(err)
+			return status.Error(codes.Internal, constans.ErrorWorkDataBase)
+		}
+		if first || len(changes.Secrets) != 0 {
+			if err = stream.Send(pb.FromChanges(changes)); err != nil {
+				return err
+			}
+		}
+		cursor = changes.Cursor
+		if changes.HasMore {
+			continue
+		}
+		if !request.GetFollow() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
+
+// startSession starts a session of the user with the device name and user agent of the call metadata.
+func (s *keeperServer) startSession(ctx context.Context, login string) (*pb.Tokens, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens, err := newSession(ctx, login, firstValue(md, constants.DeviceNameHeader), middleware.PeerIP(ctx), firstValue(md, "user-agent"))
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, "error starting the session")
+	}
+	return pb.FromTokens(tokens), nil
+}
+
+// secretStatus converts an error of the keeper storage to the gRPC status.
+func secretStatus(err error) error {
+	switch {
+	case errors.Is(err, constants.ErrSecretNotFound):
+		return status.Error(codes.NotFound, constants.ErrSecretNotFound.Error())
+	case errors.Is(err, constants.ErrRevisionConflict):
+		return status.Error(codes.Aborted, constants.ErrRevisionConflict.Error())
+	default:
+		log.Println(err)
+		return status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+}
+
+// firstValue returns the first value of the metadata key, metadata keys are lowercase.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
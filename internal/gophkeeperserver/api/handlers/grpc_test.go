@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	pb "yudinsv/gophkeeper/internal/proto"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func newGRPCClient(t *testing.T) pb.KeeperClient {
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	require.NoError(t, err)
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	require.NoError(t, err)
+	require.NoError(t, container.BuildContainer(cfg, userStorage, keeperStorage))
+
+	listener := bufconn.Listen(1 << 20)
+	srv := GRPCServer()
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewKeeperClient(conn)
+}
+
+func TestKeeperServer(t *testing.T) {
+	client := newGRPCClient(t)
+	ctx := context.Background()
+
+	_, err := client.Ping(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+
+	_, err = client.Register(ctx, &pb.Credentials{Login: "user"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	tokens, err := client.Register(ctx, &pb.Credentials{Login: "user", Password: "password"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.GetAccessToken())
+	_, err = client.Register(ctx, &pb.Credentials{Login: "user", Password: "password"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Login(ctx, &pb.Credentials{Login: "user", Password: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	tokens, err = client.Login(metadata.AppendToOutgoingContext(ctx, "x-device-name", "laptop"),
+		&pb.Credentials{Login: "user", Password: "password"})
+	require.NoError(t, err)
+	sessions, err := container.GetUserStorage().ListSessions(ctx, "user")
+	require.NoError(t, err)
+	devices := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		devices = append(devices, session.Device)
+	}
+	assert.Contains(t, devices, "laptop")
+
+	secretID := uuid.New()
+	_, err = client.GetSecret(ctx, &pb.SecretID{Id: secretID.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "a call without a token is rejected")
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tokens.GetAccessToken())
+	_, err = client.GetSecret(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tokens.GetAccessToken()+"x"),
+		&pb.SecretID{Id: secretID.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "a token with a wrong signature is rejected")
+
+	revision, err := client.PutSecret(authCtx, &pb.Secret{Id: secretID.String(), Value: []byte("value"), Description: "card"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revision.GetRevision())
+	_, err = client.PutSecret(authCtx, &pb.Secret{Id: secretID.String(), Value: []byte("stale")})
+	assert.Equal(t, codes.Aborted, status.Code(err), "a stale base revision is rejected")
+
+	secret, err := client.GetSecret(authCtx, &pb.SecretID{Id: secretID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), secret.GetValue())
+	assert.Equal(t, "card", secret.GetDescription())
+	_, err = client.GetSecret(authCtx, &pb.SecretID{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err := client.Changes(authCtx, &pb.ChangesRequest{})
+	require.NoError(t, err)
+	batch, err := stream.Recv()
+	require.NoError(t, err)
+	if assert.Len(t, batch.GetSecrets(), 1) {
+		assert.Equal(t, secretID.String(), batch.GetSecrets()[0].GetId())
+	}
+	_, err = stream.Recv()
+	assert.Error(t, err, "the feed without follow ends after the first batch")
+
+	followCtx, cancel := context.WithTimeout(authCtx, 10*time.Second)
+	defer cancel()
+	feed, err := client.Changes(followCtx, &pb.ChangesRequest{Since: batch.GetCursor(), Follow: true})
+	require.NoError(t, err)
+	batch, err = feed.Recv()
+	require.NoError(t, err)
+	assert.Empty(t, batch.GetSecrets())
+
+	_, err = client.DeleteSecret(authCtx, &pb.DeleteRequest{Id: secretID.String()})
+	assert.Equal(t, codes.Aborted, status.Code(err), "a delete from a stale base revision is rejected")
+	revision, err = client.DeleteSecret(authCtx, &pb.DeleteRequest{Id: secretID.String(), Revision: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revision.GetRevision())
+	batch, err = feed.Recv()
+	require.NoError(t, err, "the followed feed sends the deletion")
+	if assert.Len(t, batch.GetSecrets(), 1) {
+		assert.True(t, batch.GetSecrets()[0].GetIsDeleted())
+	}
+
+	_, err = client.DeleteSecret(authCtx, &pb.DeleteRequest{Id: uuid.NewString()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
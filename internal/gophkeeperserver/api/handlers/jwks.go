@@ -0,0 +1,25 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"net/http"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksHandler returns the public keys verifying the access tokens as a JSON Web Key Set.
+// Handler: GET /.well-known/jwks.json.
+//
+// The "kid" header of a token selects its key. During a rotation the set holds the old and the new keys.
+// The set is empty if the tokens are signed with the shared HMAC secret.
+//
+// Possible response codes:
+//
+// 200 - the key set.
+func jwksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, container.GetKeySet().JWKS())
+}
@@ -0,0 +1,101 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/utils"
+	"yudinsv/gophkeeper/internal/models"
+	keyutils "yudinsv/gophkeeper/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getKDFHandler returns the params used to derive the vault key of the authenticated user.
+// Handler: GET /api/v1/kdf.
+//
+// Every device of the user derives the same vault key from the master password with these params.
+//
+// Possible response codes:
+//
+// 200 - params successfully retrieved;
+// 404 - params have not been stored yet;
+// 500 - internal server error.
+func getKDFHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	storage := container.GetUserStorage()
+	params, err := storage.GetKDFParams(ctx, c.Param(constans.CookeUserIDName))
+	if err != nil {
+		if errors.Is(err, constants.ErrKDFParamsNotFound) {
+			c.String(http.StatusNotFound, constants.ErrKDFParamsNotFound.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, params)
+}
+
+// putKDFHandler stores the params used to derive the vault key of the authenticated user.
+// Handler: PUT /api/v1/kdf.
+//
+// The params are stored once: replacing them would make the stored secrets unreadable.
+//
+// Request format:
+//
+// PUT /api/v1/kdf HTTP/1.1
+// Content-Type: application/json
+// ...
+//
+//	{
+//		"salt": "<base64>",
+//		"time": 3,
+//		"memory": 65536,
+//		"threads": 4,
+//		"key_len": 32,
+//		"verifier": "<base64>"
+//	}
+//
+// Possible response codes:
+//
+// 200 - params successfully stored;
+// 400 - wrong request format or weak params;
+// 409 - params are already stored;
+// 500 - internal server error.
+func putKDFHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	if !utils.ValidContentType(c, "application/json") {
+		return
+	}
+	var params models.KDFParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.String(http.StatusBadRequest, constans.ErrorUnmarshalBody)
+		return
+	}
+	if err := keyutils.ValidKDFParams(params); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	storage := container.GetUserStorage()
+	err := storage.PutKDFParams(ctx, c.Param(constans.CookeUserIDName), params)
+	if err != nil {
+		if errors.Is(err, constans.ErrorNoUNIQUE) {
+			c.String(http.StatusConflict, "kdf params are already stored")
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKDFHandlers(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:"+constans.CookeUserIDName, getKDFHandler)
+	router.PUT("/:"+constans.CookeUserIDName, putKDFHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	params, err := utils.NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak := params
+	utils.SetKeyVerifier(&params, []byte("key"))
+
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		method       string
+		params       models.KDFParams
+		expectedCode int
+	}{
+		{
+			name:         "Params not stored yet",
+			method:       http.MethodGet,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Params without verifier",
+			method:       http.MethodPut,
+			params:       weak,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Store params",
+			method:       http.MethodPut,
+			params:       params,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Params are already stored",
+			method:       http.MethodPut,
+			params:       params,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Get params",
+			method:       http.MethodGet,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	// Run tests
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonData, err := json.Marshal(tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(tc.method, "/user", bytes.NewBuffer(jsonData))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.method == http.MethodGet && tc.expectedCode == http.StatusOK {
+				var got models.KDFParams
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, params, got)
+			}
+		})
+	}
+}
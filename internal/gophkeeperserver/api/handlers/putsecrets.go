@@ -4,9 +4,11 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
 	"yudinsv/gophkeeper/internal/models"
@@ -18,11 +20,25 @@ import (
 // Handler: PUT /api/v1/secret.
 //
 // The handler retrieves the secret from the request body and stores it in the database.
+// The owner of the secret is always the authenticated user, the owner_id from the body is ignored.
+// Secrets owned by other users are reported as not found.
+// The revision from the body is the base revision the secret was edited from (0 for a new secret),
+// the secret is stored only if the server copy is still at that revision.
+// A file secret is stored only after its blob, blob_id, is completed with completeBlobHandler.
+//
+// Response format:
+//
+//	{
+//		"revision": <new revision>
+//	}
 //
 // Possible response codes:
 //
 // 200 - data successfully stored;
 // 400 - bad request;
+// 404 - secret not found;
+// 409 - the secret was changed after the base revision;
+// 422 - the blob of the file secret is not uploaded;
 // 500 - internal server error.
 func putDataHandler(c *gin.Context) {
 	var secret models.Secret
@@ -30,11 +46,31 @@ func putDataHandler(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
+	secret.OwnerID = c.Param(constans.CookeUserIDName)
+	if err := checkBlob(c.Request.Context(), secret); err != nil {
+		if errors.Is(err, constants.ErrBlobNotFound) || errors.Is(err, constants.ErrBlobIncomplete) {
+			c.String(http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
 	storage := container.GetKeeperStorage()
-	err := storage.PutSecret(c.Request.Context(), secret)
+	revision, err := storage.CompareAndPutSecret(c.Request.Context(), secret, secret.Revision)
 	if err != nil {
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
+			return
+		}
+		if errors.Is(err, constants.ErrRevisionConflict) {
+			c.String(http.StatusConflict, constants.ErrRevisionConflict.Error())
+			return
+		}
 		log.Println(err)
 		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
 		return
 	}
+	publishChange(c.Request.Context(), models.ChangeEvent{OwnerID: secret.OwnerID, SecretID: secret.ID, Revision: revision})
+	c.JSON(http.StatusOK, models.Revision{Revision: revision})
 }
@@ -10,8 +10,13 @@ import (
 )
 
 // Router указание маршрутов севера
-func Router() *gin.Engine {
+// The client IP is taken from the X-Forwarded-For header only behind the trusted proxies, by default none,
+// so a client cannot pick the IP the login attempts are counted by.
+func Router(trustedProxies []string) (*gin.Engine, error) {
 	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
 	r.Use(gin.Logger())
 	api := r.Group("/api")
 	api.Use(middleware.JwtValid())
@@ -20,15 +25,35 @@ func Router() *gin.Engine {
 	{
 		v1.POST("/register", registerHandler)
 		v1.POST("/login", authenticationHandler)
+		v1.POST("/token/refresh", refreshTokenHandler)
+		v1.GET("/sessions", listSessionsHandler)
+		v1.POST("/logout", logoutHandler)
+		v1.DELETE("/sessions/:id", revokeSessionHandler)
+		v1.POST("/sessions/revoke-others", revokeOtherSessionsHandler)
+		v1.POST("/2fa/enroll", enrollTOTPHandler)
+		v1.POST("/2fa/confirm", confirmTOTPHandler)
+		v1.POST("/2fa/verify", verifyTOTPHandler)
+		v1.GET("/kdf", getKDFHandler)
+		v1.PUT("/kdf", putKDFHandler)
 
 		v1.GET("/sync", syncDataHandler)
+		v1.GET("/changes", changesHandler)
+		v1.GET("/events", eventsHandler)
 		v1.PUT("/", putDataHandler)
 		v1.POST("/", getDataHandler)
 		v1.DELETE("/", deleteDataHandler)
+		v1.GET("/secrets/:id/versions", getVersionsHandler)
+		v1.POST("/secrets/:id/restore", restoreVersionHandler)
+		v1.POST("/blobs", createBlobHandler)
+		v1.GET("/blobs/:id", getBlobHandler)
+		v1.PUT("/blobs/:id/chunks/:index", putChunkHandler)
+		v1.GET("/blobs/:id/chunks/:index", getChunkHandler)
+		v1.POST("/blobs/:id/complete", completeBlobHandler)
 	}
 	r.GET("/ping", func(context *gin.Context) {
 		context.String(http.StatusOK, "pong")
 	})
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	return r
+	r.GET("/.well-known/jwks.json", jwksHandler)
+	return r, nil
 }
@@ -0,0 +1,103 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listSessionsHandler returns the sessions of the authenticated user.
+// Handler: GET /api/v1/sessions.
+//
+// The session of the request is marked as current.
+//
+// Possible response codes:
+//
+// 200 - sessions successfully retrieved;
+// 500 - internal server error.
+func listSessionsHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	sessions, err := container.GetUserStorage().ListSessions(ctx, c.Param(constans.CookeUserIDName))
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == c.Param(constans.SessionIDName)
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// logoutHandler revokes the session of the request.
+// Handler: POST /api/v1/logout.
+//
+// The access and refresh tokens of the session stop working.
+//
+// Possible response codes:
+//
+// 200 - session successfully revoked;
+// 500 - internal server error.
+func logoutHandler(c *gin.Context) {
+	revokeSession(c, c.Param(constans.SessionIDName))
+}
+
+// revokeSessionHandler revokes a session of the authenticated user, for example one on a lost device.
+// Handler: DELETE /api/v1/sessions/:id.
+//
+// Possible response codes:
+//
+// 200 - session successfully revoked;
+// 404 - the user has no session with the ID;
+// 500 - internal server error.
+func revokeSessionHandler(c *gin.Context) {
+	revokeSession(c, c.Param("id"))
+}
+
+// revokeOtherSessionsHandler revokes all the sessions of the authenticated user except the session of the request.
+// Handler: POST /api/v1/sessions/revoke-others.
+//
+// Possible response codes:
+//
+// 200 - sessions successfully revoked;
+// 500 - internal server error.
+func revokeOtherSessionsHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	err := container.GetUserStorage().RevokeOtherSessions(ctx,
+		c.Param(constans.CookeUserIDName), c.Param(constans.SessionIDName))
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// revokeSession revokes the session of the authenticated user and writes the response.
+func revokeSession(c *gin.Context, sessionID string) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	err := container.GetUserStorage().RevokeSession(ctx, c.Param(constans.CookeUserIDName), sessionID)
+	if err != nil {
+		if errors.Is(err, constants.ErrSessionNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSessionNotFound.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.Status(http.StatusOK)
+}
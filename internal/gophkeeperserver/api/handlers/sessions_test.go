@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	prefix := "/:" + constans.CookeUserIDName + "/:" + constans.SessionIDName
+	router.GET(prefix+"/sessions", listSessionsHandler)
+	router.POST(prefix+"/logout", logoutHandler)
+	router.DELETE(prefix+"/sessions/:id", revokeSessionHandler)
+	router.POST(prefix+"/sessions/revoke-others", revokeOtherSessionsHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for _, session := range []models.Session{
+		{ID: "laptop", Login: "user", Device: "laptop", LastSeen: now.Add(-time.Hour)},
+		{ID: "phone", Login: "user", Device: "phone", LastSeen: now},
+		{ID: "other", Login: "another-user", Device: "desktop", LastSeen: now},
+	} {
+		if err = container.GetUserStorage().AddSession(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func() []models.Session {
+		w := serve(http.MethodGet, "/user/phone/sessions")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var sessions []models.Session
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+		return sessions
+	}
+
+	sessions := list()
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, "phone", sessions[0].ID)
+		assert.True(t, sessions[0].Current)
+		assert.Equal(t, "laptop", sessions[1].Device)
+		assert.False(t, sessions[1].Current)
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/user/phone/sessions/other").Code,
+		"a session of another user cannot be revoked")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/user/phone/sessions/revoke-others").Code)
+	sessions = list()
+	if assert.Len(t, sessions, 2) {
+		assert.False(t, sessions[0].Revoked)
+		assert.True(t, sessions[1].Revoked)
+	}
+	other, err := container.GetUserStorage().GetSession(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, other.Revoked)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/user/phone/logout").Code)
+	sessions = list()
+	if assert.Len(t, sessions, 2) {
+		assert.True(t, sessions[0].Revoked)
+	}
+}
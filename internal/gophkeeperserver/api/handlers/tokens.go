@@ -0,0 +1,167 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests,
+// and the jwt-go library for generating and verifying JSON Web Tokens (JWTs) used for user authentication.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/utils"
+	"yudinsv/gophkeeper/internal/models"
+	keyutils "yudinsv/gophkeeper/internal/utils"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gin-gonic/gin"
+)
+
+// refreshTokenSize is the number of random bytes in a refresh token.
+const refreshTokenSize = 32
+
+// refreshTokenHandler exchanges a refresh token for a new pair of tokens.
+// Handler: POST /api/v1/token/refresh.
+//
+// Every refresh token can be used once. Using a token a second time means it has leaked,
+// so the session of the token is revoked and the user has to log in again.
+//
+// Request format:
+//
+// POST /api/v1/token/refresh HTTP/1.1
+// Content-Type: application/json
+// ...
+//
+// {
+// "refresh_token": "<refresh token>"
+// }
+//
+// Possible response codes:
+//
+// 200 - tokens successfully refreshed;
+// 400 - invalid request format;
+// 401 - the refresh token is unknown, expired, revoked or reused;
+// 500 - internal server error.
+func refreshTokenHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	if !utils.ValidContentType(c, "application/json") {
+		return
+	}
+	var request models.Tokens
+	if err := c.BindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.String(http.StatusBadRequest, constans.ErrorUnmarshalBody)
+		return
+	}
+	storage := container.GetUserStorage()
+	token, err := storage.UseRefreshToken(ctx, keyutils.GetSHA256Hash([]byte(request.RefreshToken)))
+	if err != nil {
+		switch {
+		case errors.Is(err, constans.ErrTokenNotFound):
+			c.String(http.StatusUnauthorized, err.Error())
+		case errors.Is(err, constans.ErrTokenReused):
+			if err = storage.RevokeSession(ctx, token.Login, token.FamilyID); err != nil {
+				log.Println(err)
+				c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+				return
+			}
+			c.String(http.StatusUnauthorized, constans.ErrTokenReused.Error())
+		default:
+			log.Println(err)
+			c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		}
+		return
+	}
+	if token.Revoked || time.Now().After(token.ExpiresAt) {
+		c.String(http.StatusUnauthorized, "refresh token expired")
+		return
+	}
+	session, err := storage.GetSession(ctx, token.FamilyID)
+	if err != nil && !errors.Is(err, constants.ErrSessionNotFound) {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if err != nil || session.Revoked {
+		c.String(http.StatusUnauthorized, "session revoked")
+		return
+	}
+	tokens, err := issueTokens(ctx, token.Login, token.FamilyID)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, "error token generation")
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// issueTokens signs a new access token with the current key of the keyset for the session and stores a new refresh token in the family of the session.
+func issueTokens(ctx context.Context, login string, sessionID string) (models.Tokens, error) {
+	cfg := container.GetConfig()
+	accessTTL := cfg.AccessTokenTTL
+	if accessTTL <= 0 {
+		accessTTL = constans.AccessTokenTTL
+	}
+	refreshTTL := cfg.RefreshTokenTTL
+	if refreshTTL <= 0 {
+		refreshTTL = constans.RefreshTokenTTL
+	}
+	now := time.Now()
+	accessToken, err := container.GetKeySet().Sign(&serverModels.Claims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: jwt.At(now.Add(accessTTL)),
+			IssuedAt:  jwt.At(now)},
+		Login:     login,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	raw := make([]byte, refreshTokenSize)
+	if _, err = rand.Read(raw); err != nil {
+		return models.Tokens{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+	err = container.GetUserStorage().AddRefreshToken(ctx, serverModels.RefreshToken{
+		Hash:      keyutils.GetSHA256Hash([]byte(refreshToken)),
+		Login:     login,
+		FamilyID:  sessionID,
+		ExpiresAt: now.Add(refreshTTL),
+	})
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	return models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTTL / time.Second),
+	}, nil
+}
+
+// issueMFAToken signs a restricted access token completing the login of the user with the second factor.
+func issueMFAToken(login string) (models.Tokens, error) {
+	now := time.Now()
+	accessToken, err := container.GetKeySet().Sign(&serverModels.Claims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: jwt.At(now.Add(constans.MFATokenTTL)),
+			IssuedAt:  jwt.At(now)},
+		Login: login,
+		Scope: constans.ScopeMFA,
+	})
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	return models.Tokens{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(constans.MFATokenTTL / time.Second),
+		MFARequired: true,
+	}, nil
+}
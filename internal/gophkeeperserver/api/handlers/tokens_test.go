@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", authenticationHandler)
+	router.POST("/refresh", refreshTokenHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	user := models.User{Login: "refresh-user", Password: "testpass"}
+	if err = container.GetUserStorage().AddUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	refresh := func(token string) (models.Tokens, int) {
+		w := post("/refresh", models.Tokens{RefreshToken: token})
+		var tokens models.Tokens
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+		}
+		return tokens, w.Code
+	}
+
+	w := post("/login", user)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var first models.Tokens
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+
+	second, code := refresh(first.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, second.AccessToken)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	third, code := refresh(second.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Reusing a rotated token revokes the whole family, including the latest token.
+	_, code = refresh(first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, code = refresh(third.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// A revoked session cannot be refreshed.
+	w = post("/login", user)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var fourth models.Tokens
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fourth))
+	sessions, err := container.GetUserStorage().ListSessions(context.Background(), user.Login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		if err = container.GetUserStorage().RevokeSession(context.Background(), user.Login, session.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, code = refresh(fourth.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	_, code = refresh("unknown")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, code = refresh("")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,249 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/totp"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/utils"
+	"yudinsv/gophkeeper/internal/models"
+	keyutils "yudinsv/gophkeeper/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCodeSize is the number of random bytes in a recovery code.
+const recoveryCodeSize = 10
+
+// enrollTOTPHandler starts the enrollment of the second factor of the authenticated user.
+// Handler: POST /api/v1/2fa/enroll.
+//
+// A new secret replaces a pending one, the second factor is enabled after confirming it with a code.
+//
+// Response format:
+//
+// {
+// "secret": "<base32 secret>",
+// "uri": "otpauth://totp/..."
+// }
+//
+// Possible response codes:
+//
+// 200 - enrollment started;
+// 409 - the second factor is already enabled;
+// 500 - internal server error.
+func enrollTOTPHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	login := c.Param(constans.CookeUserIDName)
+	secret, err := totp.NewSecret()
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, "error secret generation")
+		return
+	}
+	if err = container.GetUserStorage().SetTOTPSecret(ctx, login, secret); err != nil {
+		if errors.Is(err, constans.ErrorNoUNIQUE) {
+			c.String(http.StatusConflict, "second factor already enabled")
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, models.TOTPEnrollment{Secret: secret, URI: totp.URI(constans.TOTPIssuer, login, secret)})
+}
+
+// confirmTOTPHandler enables the pending second factor of the authenticated user.
+// Handler: POST /api/v1/2fa/confirm.
+//
+// The code proves the secret has been added to the authenticator app.
+// The response contains the recovery codes, they are shown once and each of them replaces a code once.
+//
+// Request format:
+//
+// POST /api/v1/2fa/confirm HTTP/1.1
+// Content-Type: application/json
+// ...
+//
+// {
+// "code": "<code from the authenticator app>"
+// }
+//
+// Possible response codes:
+//
+// 200 - second factor enabled;
+// 400 - invalid request format;
+// 404 - the enrollment has not been started;
+// 409 - the second factor is already enabled;
+// 422 - the code is wrong;
+// 500 - internal server error.
+func confirmTOTPHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	if !utils.ValidContentType(c, "application/json") {
+		return
+	}
+	var code models.TOTPCode
+	if err := c.BindJSON(&code); err != nil {
+		c.String(http.StatusBadRequest, constans.ErrorUnmarshalBody)
+		return
+	}
+	login := c.Param(constans.CookeUserIDName)
+	storage := container.GetUserStorage()
+	pending, err := storage.GetTOTP(ctx, login)
+	if err != nil {
+		if errors.Is(err, constans.ErrTOTPNotFound) {
+			c.String(http.StatusNotFound, constans.ErrTOTPNotFound.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if pending.Enabled {
+		c.String(http.StatusConflict, "second factor already enabled")
+		return
+	}
+	step, ok := totp.Validate(pending.Secret, code.Code, time.Now())
+	if !ok {
+		c.String(http.StatusUnprocessableEntity, "code is not correct")
+		return
+	}
+	if _, err = storage.UseTOTPStep(ctx, login, step); err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, "error recovery codes generation")
+		return
+	}
+	if err = storage.EnableTOTP(ctx, login, hashes); err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+// verifyTOTPHandler completes the login with the second factor.
+// Handler: POST /api/v1/2fa/verify.
+//
+// The request is authorized with the restricted token returned by the login.
+// The code is a code from the authenticator app, each of them is accepted once, or an unused recovery code.
+// The response is the same as the response of a login without the second factor.
+// Wrong codes count as failed logins, like wrong passwords.
+//
+// Request format:
+//
+// POST /api/v1/2fa/verify HTTP/1.1
+// Content-Type: application/json
+// Authorization: Bearer <restricted token>
+// ...
+//
+// {
+// "code": "<code or recovery code>"
+// }
+//
+// Possible response codes:
+//
+// 200 - user successfully authenticated;
+// 400 - invalid request format;
+// 401 - the code is wrong or was used before;
+// 429 - too many failed attempts, retry after the Retry-After seconds;
+// 500 - internal server error.
+func verifyTOTPHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), constans.TimeOutRequest)
+	defer cancel()
+	if !utils.ValidContentType(c, "application/json") {
+		return
+	}
+	var code models.TOTPCode
+	if err := c.BindJSON(&code); err != nil {
+		c.String(http.StatusBadRequest, constans.ErrorUnmarshalBody)
+		return
+	}
+	login := c.Param(constans.CookeUserIDName)
+	retry, err := beginLoginAttempt(ctx, login, c.ClientIP())
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if retry > 0 {
+		tooManyAttempts(c, retry)
+		return
+	}
+	ok, err := verifySecondFactor(ctx, login, code.Code)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	if !ok {
+		recordLoginFailure(failedSecondFactor)
+		c.String(http.StatusUnauthorized, "code is not correct")
+		return
+	}
+	if err = resetLoginFailures(ctx, login, c.ClientIP()); err != nil {
+		log.Println(err)
+	}
+	startSession(c, ctx, login)
+}
+
+// verifySecondFactor checks the code from the authenticator app or the recovery code of the user
+// and marks it as used.
+func verifySecondFactor(ctx context.Context, login string, code string) (bool, error) {
+	storage := container.GetUserStorage()
+	enrolled, err := storage.GetTOTP(ctx, login)
+	if err != nil {
+		if errors.Is(err, constans.ErrTOTPNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !enrolled.Enabled {
+		return false, nil
+	}
+	if step, ok := totp.Validate(enrolled.Secret, code, time.Now()); ok {
+		return storage.UseTOTPStep(ctx, login, step)
+	}
+	return storage.UseRecoveryCode(ctx, login, keyutils.GetSHA256Hash([]byte(normalizeRecoveryCode(code))))
+}
+
+// newRecoveryCodes generates the recovery codes and returns them with their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, constans.RecoveryCodesCount)
+	hashes := make([]string, 0, constans.RecoveryCodesCount)
+	for i := 0; i < constans.RecoveryCodesCount; i++ {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))
+		codes = append(codes, code[:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:])
+		hashes = append(hashes, keyutils.GetSHA256Hash([]byte(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes the separators and the case of a recovery code typed by the user.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
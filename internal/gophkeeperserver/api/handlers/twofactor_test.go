@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/totp"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", authenticationHandler)
+	router.POST("/:"+constans.CookeUserIDName+"/2fa/enroll", enrollTOTPHandler)
+	router.POST("/:"+constans.CookeUserIDName+"/2fa/confirm", confirmTOTPHandler)
+	router.POST("/:"+constans.CookeUserIDName+"/2fa/verify", verifyTOTPHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+	user := models.User{Login: "mfa-user", Password: "testpass"}
+	if err = container.GetUserStorage().AddUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, post("/mfa-user/2fa/confirm", models.TOTPCode{Code: "000000"}).Code)
+
+	w := post("/mfa-user/2fa/enroll", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var enrollment models.TOTPEnrollment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/mfa-user/2fa/confirm", models.TOTPCode{Code: "abcdef"}).Code)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = post("/mfa-user/2fa/confirm", models.TOTPCode{Code: code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var recovery models.RecoveryCodes
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recovery))
+	assert.Len(t, recovery.Codes, constans.RecoveryCodesCount)
+	assert.Equal(t, http.StatusConflict, post("/mfa-user/2fa/enroll", nil).Code)
+
+	// The login stops before the session until the second factor is verified.
+	w = post("/login", user)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restricted models.Tokens
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &restricted))
+	assert.True(t, restricted.MFARequired)
+	assert.Empty(t, restricted.RefreshToken)
+	assert.Empty(t, w.Header().Get("Authorization"))
+
+	assert.Equal(t, http.StatusUnauthorized, post("/mfa-user/2fa/verify", models.TOTPCode{Code: code}).Code,
+		"a code is accepted once")
+	w = post("/mfa-user/2fa/verify", models.TOTPCode{Code: recovery.Codes[0]})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens models.Tokens
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.False(t, tokens.MFARequired)
+	assert.Equal(t, http.StatusUnauthorized, post("/mfa-user/2fa/verify", models.TOTPCode{Code: recovery.Codes[0]}).Code,
+		"a recovery code is accepted once")
+	assert.Equal(t, http.StatusUnauthorized, post("/other/2fa/verify", models.TOTPCode{Code: recovery.Codes[1]}).Code)
+}
@@ -0,0 +1,133 @@
+// Package handlers
+// The package uses the Gin web framework for handling HTTP requests.
+// The package also relies on other internal packages and models defined in the project.
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// getVersionsHandler handles requests for the history of a secret.
+// Handler: GET /api/v1/secrets/:id/versions
+//
+// The handler returns the last revisions of the secret of the authenticated user, newest first.
+// The revisions are returned encrypted as models.Secret, the ver field is the time of the revision.
+// Secrets owned by other users are reported as not found.
+//
+// Possible response codes:
+//
+// 200 - history successfully retrieved;
+// 400 - invalid secret ID;
+// 404 - secret not found;
+// 500 - internal server error.
+func getVersionsHandler(c *gin.Context) {
+	secretID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	storage := container.GetKeeperStorage()
+	userID := c.Param(constans.CookeUserIDName)
+	versions, err := storage.GetVersions(c.Request.Context(), userID, secretID)
+	if err != nil {
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// restoreVersionHandler handles requests for restoring a secret from its history.
+// Handler: POST /api/v1/secrets/:id/restore
+//
+// Request format:
+//
+//	{
+//		"revision": <revision to restore>
+//	}
+//
+// The handler stores the content of the revision as the new revision of the secret,
+// so the restore itself is kept in the history and reaches the other devices on sync.
+//
+// Response format:
+//
+//	{
+//		"revision": <new revision>
+//	}
+//
+// Possible response codes:
+//
+// 200 - secret successfully restored;
+// 400 - invalid secret ID or request body;
+// 404 - secret or revision not found;
+// 409 - the secret was changed during the restore;
+// 500 - internal server error.
+func restoreVersionHandler(c *gin.Context) {
+	secretID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	var revision models.Revision
+	if err = c.ShouldBindJSON(&revision); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	storage := container.GetKeeperStorage()
+	userID := c.Param(constans.CookeUserIDName)
+	current, err := storage.GetSecret(c.Request.Context(), userID, secretID)
+	if err != nil {
+		if errors.Is(err, constants.ErrSecretNotFound) {
+			c.String(http.StatusNotFound, constants.ErrSecretNotFound.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	versions, err := storage.GetVersions(c.Request.Context(), userID, secretID)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	var restored *models.Secret
+	for i := range versions {
+		if versions[i].Revision == revision.Revision {
+			restored = &versions[i]
+			break
+		}
+	}
+	if restored == nil {
+		c.String(http.StatusNotFound, constants.ErrVersionNotFound.Error())
+		return
+	}
+	restored.Ver = time.Now()
+	newRevision, err := storage.CompareAndPutSecret(c.Request.Context(), *restored, current.Revision)
+	if err != nil {
+		if errors.Is(err, constants.ErrRevisionConflict) {
+			c.String(http.StatusConflict, constants.ErrRevisionConflict.Error())
+			return
+		}
+		log.Println(err)
+		c.String(http.StatusInternalServerError, constans.ErrorWorkDataBase)
+		return
+	}
+	publishChange(c.Request.Context(), models.ChangeEvent{OwnerID: userID, SecretID: restored.ID, Revision: newRevision})
+	c.JSON(http.StatusOK, models.Revision{Revision: newRevision})
+}
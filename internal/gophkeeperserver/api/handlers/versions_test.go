@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	serverModels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
+	"yudinsv/gophkeeper/internal/keeperstorage"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionsHandlers(t *testing.T) {
+	// Setup test data
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:"+constans.CookeUserIDName+"/secrets/:id/versions", getVersionsHandler)
+	router.POST("/:"+constans.CookeUserIDName+"/secrets/:id/restore", restoreVersionHandler)
+
+	cfg := serverModels.Config{}
+	userStorage, err := userstorage.NewUserStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeperStorage, err := keeperstorage.NewKeeperStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = container.BuildContainer(cfg, userStorage, keeperStorage); err != nil {
+		t.Fatal("error starting container", err)
+	}
+
+	// Store two revisions of a secret of a test user
+	secret := models.Secret{ID: uuid.New(), OwnerID: "owner", Value: []byte("first"), Type: "text"}
+	for i, value := range []string{"first", "second"} {
+		secret.Value = []byte(value)
+		if _, err = container.GetKeeperStorage().CompareAndPutSecret(context.Background(), secret, int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The history is listed newest first
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/owner/secrets/"+secret.ID.String()+"/versions", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var versions []models.Secret
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, int64(2), versions[0].Revision)
+		assert.Equal(t, []byte("second"), versions[0].Value)
+		assert.Equal(t, int64(1), versions[1].Revision)
+		assert.Equal(t, []byte("first"), versions[1].Value)
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		url          string
+		body         interface{}
+		expectedCode int
+	}{
+		{
+			name:         "Invalid secret ID",
+			method:       http.MethodGet,
+			url:          "/owner/secrets/abc/versions",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "History of another user",
+			method:       http.MethodGet,
+			url:          "/intruder/secrets/" + secret.ID.String() + "/versions",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Restore by another user",
+			method:       http.MethodPost,
+			url:          "/intruder/secrets/" + secret.ID.String() + "/restore",
+			body:         models.Revision{Revision: 1},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Restore unknown revision",
+			method:       http.MethodPost,
+			url:          "/owner/secrets/" + secret.ID.String() + "/restore",
+			body:         models.Revision{Revision: 42},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Restore invalid body",
+			method:       http.MethodPost,
+			url:          "/owner/secrets/" + secret.ID.String() + "/restore",
+			body:         "revision",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Restore first revision",
+			method:       http.MethodPost,
+			url:          "/owner/secrets/" + secret.ID.String() + "/restore",
+			body:         models.Revision{Revision: 1},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body bytes.Buffer
+			if tc.body != nil {
+				assert.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			req, _ := http.NewRequest(tc.method, tc.url, &body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+
+	// The restore is a new revision with the content of the restored one
+	stored, err := container.GetKeeperStorage().GetSecret(context.Background(), "owner", secret.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stored.Revision)
+	assert.Equal(t, []byte("first"), stored.Value)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	grpcMu      sync.Mutex
+	grpcServers []*grpc.Server
+)
+
+// StartGRPC serves the gRPC server on the address in the background.
+// It is stopped together with the HTTP server started by NewServer or NewServerTLS.
+func StartGRPC(srv *grpc.Server, addressService string) error {
+	listener, err := net.Listen("tcp", addressService)
+	if err != nil {
+		return err
+	}
+	grpcMu.Lock()
+	grpcServers = append(grpcServers, srv)
+	grpcMu.Unlock()
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			log.Fatalf("error: %s\n\n", err)
+		}
+	}()
+	return nil
+}
+
+// stopGRPC stops the gRPC servers gracefully, the calls still running when ctx is done are cancelled,
+// so the followed change feeds do not hold the shutdown.
+func stopGRPC(ctx context.Context) {
+	grpcMu.Lock()
+	servers := grpcServers
+	grpcServers = nil
+	grpcMu.Unlock()
+	for _, srv := range servers {
+		stopped := make(chan struct{})
+		go func(srv *grpc.Server) {
+			srv.GracefulStop()
+			close(stopped)
+		}(srv)
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			srv.Stop()
+		}
+	}
+}
@@ -39,16 +39,52 @@ func NewServer(r *gin.Engine, addressService string) {
 		Addr:    addressService,
 		Handler: r,
 	}
+	serve(srv, srv.ListenAndServe, nil)
+}
+
+// NewServerTLS is NewServer serving HTTPS with the certificates of the reloader.
+// On SIGHUP the certificate, key and client CA files are read again, the connections already open are kept.
+func NewServerTLS(r *gin.Engine, addressService string, certs *CertReloader) {
+	srv := &http.Server{
+		Addr:      addressService,
+		Handler:   r,
+		TLSConfig: certs.TLSConfig(),
+	}
+	serve(srv, func() error { return srv.ListenAndServeTLS("", "") }, func() {
+		if err := certs.Reload(); err != nil {
+			log.Println("error reloading the certificates", err)
+			return
+		}
+		log.Println("certificates reloaded")
+	})
+}
+
+// serve runs the server until SIGINT or SIGTERM and shuts it down gracefully together with the gRPC servers.
+// SIGHUP calls reload if it is set.
+func serve(srv *http.Server, listen func() error, reload func()) {
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := listen(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("error: %s\n\n", err)
 		}
 	}()
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	hangup := make(chan os.Signal, 1)
+	if reload != nil {
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
+	}
+	for waiting := true; waiting; {
+		select {
+		case <-hangup:
+			reload()
+		case <-quit:
+			waiting = false
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeOutShutdownService)
 	defer cancel()
+	stopGRPC(ctx)
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalln("emergency shutdown of the service", err)
 	}
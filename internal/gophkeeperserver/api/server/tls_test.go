@@ -0,0 +1,94 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTLS(t *testing.T, config *tls.Config) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go func() { _ = srv.Serve(tls.NewListener(listener, config)) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + listener.Addr().String()
+}
+
+func clientFor(t *testing.T, caFile string, certs ...tls.Certificate) *http.Client {
+	pool, err := LoadCertPool(caFile)
+	require.NoError(t, err)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+}
+
+func TestGenerateSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, GenerateSelfSigned(certFile, keyFile, []string{"localhost", "127.0.0.1"}))
+	first, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	require.NoError(t, GenerateSelfSigned(certFile, keyFile, []string{"localhost"}))
+	second, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, first.Certificate, second.Certificate, "the existing certificate is kept")
+}
+
+func TestCertReloader_TLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, GenerateSelfSigned(certFile, keyFile, []string{"127.0.0.1"}))
+	certs, err := NewCertReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+	address := startTLS(t, certs.TLSConfig())
+
+	response, err := clientFor(t, certFile).Get(address)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	_, err = http.Get(address)
+	assert.Error(t, err, "the self-signed certificate is not trusted without the CA bundle")
+}
+
+func TestCertReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	require.NoError(t, GenerateSelfSigned(certFile, keyFile, []string{"127.0.0.1"}))
+	require.NoError(t, GenerateSelfSigned(clientCertFile, clientKeyFile, []string{"device"}))
+	certs, err := NewCertReloader(certFile, keyFile, clientCertFile)
+	require.NoError(t, err)
+	address := startTLS(t, certs.TLSConfig())
+
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	response, err := clientFor(t, certFile, clientCert).Get(address)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	_, err = clientFor(t, certFile).Get(address)
+	assert.Error(t, err, "a client without a certificate is rejected")
+
+	otherCertFile, otherKeyFile := filepath.Join(dir, "other.pem"), filepath.Join(dir, "other-key.pem")
+	require.NoError(t, GenerateSelfSigned(otherCertFile, otherKeyFile, []string{"device"}))
+	otherCert, err := tls.LoadX509KeyPair(otherCertFile, otherKeyFile)
+	require.NoError(t, err)
+	_, err = clientFor(t, certFile, otherCert).Get(address)
+	assert.Error(t, err, "a certificate of an unknown CA is rejected")
+
+	certs.clientCAFile = otherCertFile
+	require.NoError(t, certs.Reload())
+	response, err = clientFor(t, certFile, otherCert).Get(address)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode, "the reloaded client CA is used for new connections")
+}
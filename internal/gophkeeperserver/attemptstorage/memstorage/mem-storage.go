@@ -0,0 +1,100 @@
+package memstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+)
+
+// MemStorage keeps the failed logins in memory, they are lost on restart.
+// The attempts older than the window are pruned once a window, so the keys of past attacks do not pile up.
+type MemStorage struct {
+	attemptCash map[string]servermodels.LoginAttempts
+	lastPrune   time.Time
+	mu          *sync.Mutex
+}
+
+// New creates an empty storage.
+func New() (*MemStorage, error) {
+	return &MemStorage{
+		attemptCash: make(map[string]servermodels.LoginAttempts),
+		mu:          new(sync.Mutex),
+	}, nil
+}
+
+// Ping always succeeds, the storage has nothing to connect to.
+func (MS *MemStorage) Ping() error {
+	return nil
+}
+
+// Close does nothing.
+func (MS *MemStorage) Close() error {
+	return nil
+}
+
+// AddFailure counts a failed login with the key.
+func (MS *MemStorage) AddFailure(_ context.Context, key string, now time.Time, window time.Duration) (servermodels.LoginAttempts, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	MS.prune(now, window)
+	attempts := MS.attemptCash[key]
+	if now.Sub(attempts.LastFailure) > window {
+		attempts = servermodels.LoginAttempts{}
+	}
+	attempts.Failures++
+	attempts.PreviousFailure = attempts.LastFailure
+	attempts.LastFailure = now
+	MS.attemptCash[key] = attempts
+	return attempts, nil
+}
+
+// prune deletes the attempts without a failure within the window, at most once a window.
+func (MS *MemStorage) prune(now time.Time, window time.Duration) {
+	if now.Sub(MS.lastPrune) < window {
+		return
+	}
+	MS.lastPrune = now
+	for key, attempts := range MS.attemptCash {
+		if now.Sub(attempts.LastFailure) > window {
+			delete(MS.attemptCash, key)
+		}
+	}
+}
+
+// ReleaseFailure takes back the last failure counted with the key.
+func (MS *MemStorage) ReleaseFailure(_ context.Context, key string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	attempts, ok := MS.attemptCash[key]
+	if !ok {
+		return nil
+	}
+	attempts.Failures--
+	if attempts.Failures <= 0 {
+		delete(MS.attemptCash, key)
+		return nil
+	}
+	if !attempts.PreviousFailure.IsZero() {
+		attempts.LastFailure = attempts.PreviousFailure
+	}
+	attempts.PreviousFailure = time.Time{}
+	MS.attemptCash[key] = attempts
+	return nil
+}
+
+// GetAttempts returns the failed logins with the key.
+func (MS *MemStorage) GetAttempts(_ context.Context, key string) (servermodels.LoginAttempts, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	return MS.attemptCash[key], nil
+}
+
+// ResetAttempts forgets the failed logins with the key.
+func (MS *MemStorage) ResetAttempts(_ context.Context, key string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	delete(MS.attemptCash, key)
+	return nil
+}
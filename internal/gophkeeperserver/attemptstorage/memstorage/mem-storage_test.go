@@ -0,0 +1,102 @@
+package memstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemStorage_AddFailure(t *testing.T) {
+	storage, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		attempts, err := storage.AddFailure(ctx, "login:user", now, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, i, attempts.Failures)
+	}
+	attempts, err := storage.AddFailure(ctx, "login:user", now.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, attempts.Failures, "old failures are forgotten")
+
+	if err = storage.ResetAttempts(ctx, "login:user"); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err = storage.GetAttempts(ctx, "login:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, attempts.Failures)
+}
+
+func TestMemStorage_ReleaseFailure(t *testing.T) {
+	storage, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	if _, err = storage.AddFailure(ctx, "ip:127.0.0.1", now, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := storage.AddFailure(ctx, "ip:127.0.0.1", now.Add(time.Second), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, now, attempts.PreviousFailure)
+
+	if err = storage.ReleaseFailure(ctx, "ip:127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err = storage.GetAttempts(ctx, "ip:127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, attempts.Failures)
+	assert.Equal(t, now, attempts.LastFailure, "the failure before the released one is the last again")
+
+	if err = storage.ReleaseFailure(ctx, "ip:127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.ReleaseFailure(ctx, "ip:127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err = storage.GetAttempts(ctx, "ip:127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, attempts.Failures)
+}
+
+func TestMemStorage_Prune(t *testing.T) {
+	storage, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for _, key := range []string{"ip:127.0.0.1", "ip:127.0.0.2"} {
+		if _, err = storage.AddFailure(ctx, key, now, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = storage.AddFailure(ctx, "login:user", now.Add(30*time.Minute), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, storage.attemptCash, 3, "the attempts within the window are kept")
+
+	if _, err = storage.AddFailure(ctx, "ip:127.0.0.3", now.Add(2*time.Hour), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, storage.attemptCash, 1, "the attempts older than the window are pruned")
+	assert.Contains(t, storage.attemptCash, "ip:127.0.0.3")
+}
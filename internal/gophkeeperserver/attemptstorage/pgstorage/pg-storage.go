@@ -0,0 +1,121 @@
+package pgstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+
+	_ "github.com/lib/pq"
+)
+
+// PgStorage keeps the failed logins in Postgres, so they are shared by the server instances.
+// The attempts older than the window are pruned once a window by the instance counting a failure.
+type PgStorage struct {
+	connect   *sql.DB
+	lastPrune time.Time
+	mu        *sync.Mutex
+}
+
+// New opens the database, the tables are created by Ping.
+func New(uri string) (*PgStorage, error) {
+	connect, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, err
+	}
+	return &PgStorage{connect: connect, mu: new(sync.Mutex)}, nil
+}
+
+// Ping checks the connection and creates the tables.
+func (PS *PgStorage) Ping() error {
+	if err := PS.connect.Ping(); err != nil {
+		return err
+	}
+	return createTables(PS.connect)
+}
+
+// Close closes the database.
+func (PS *PgStorage) Close() error {
+	return PS.connect.Close()
+}
+
+func createTables(connect *sql.DB) error {
+	_, err := connect.Exec(`
+	create table if not exists public.login_attempts(
+		attempt_key text primary key,
+		failures integer not null,
+		last_failure timestamptz not null
+	);
+	alter table public.login_attempts add column if not exists previous_failure timestamptz;
+	create index if not exists login_attempts_last_failure on public.login_attempts (last_failure);
+	`)
+	return err
+}
+
+// AddFailure counts a failed login with the key.
+// The count is updated by a single upsert, so concurrent failures are all counted.
+func (PS *PgStorage) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (servermodels.LoginAttempts, error) {
+	if err := PS.prune(ctx, now, window); err != nil {
+		return servermodels.LoginAttempts{}, err
+	}
+	var attempts servermodels.LoginAttempts
+	var previous sql.NullTime
+	err := PS.connect.QueryRowContext(ctx,
+		`insert into public.login_attempts (attempt_key, failures, last_failure) values ($1, 1, $2)
+		on conflict (attempt_key) do update set
+			failures = case when public.login_attempts.last_failure < $3 then 1 else public.login_attempts.failures + 1 end,
+			previous_failure = case when public.login_attempts.last_failure < $3 then null else public.login_attempts.last_failure end,
+			last_failure = excluded.last_failure
+		returning failures, last_failure, previous_failure`,
+		key, now, now.Add(-window)).Scan(&attempts.Failures, &attempts.LastFailure, &previous)
+	if err != nil {
+		return servermodels.LoginAttempts{}, err
+	}
+	attempts.PreviousFailure = previous.Time
+	return attempts, nil
+}
+
+// prune deletes the attempts without a failure within the window, at most once a window.
+func (PS *PgStorage) prune(ctx context.Context, now time.Time, window time.Duration) error {
+	PS.mu.Lock()
+	if now.Sub(PS.lastPrune) < window {
+		PS.mu.Unlock()
+		return nil
+	}
+	PS.lastPrune = now
+	PS.mu.Unlock()
+	_, err := PS.connect.ExecContext(ctx, `delete from public.login_attempts where last_failure < $1`, now.Add(-window))
+	return err
+}
+
+// ReleaseFailure takes back the last failure counted with the key.
+func (PS *PgStorage) ReleaseFailure(ctx context.Context, key string) error {
+	_, err := PS.connect.ExecContext(ctx,
+		`update public.login_attempts set
+			failures = failures - 1,
+			last_failure = coalesce(previous_failure, last_failure),
+			previous_failure = null
+		where attempt_key = $1 and failures > 0`, key)
+	return err
+}
+
+// GetAttempts returns the failed logins with the key.
+func (PS *PgStorage) GetAttempts(ctx context.Context, key string) (servermodels.LoginAttempts, error) {
+	var attempts servermodels.LoginAttempts
+	err := PS.connect.QueryRowContext(ctx,
+		`select failures, last_failure from public.login_attempts where attempt_key = $1`,
+		key).Scan(&attempts.Failures, &attempts.LastFailure)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return servermodels.LoginAttempts{}, err
+	}
+	return attempts, nil
+}
+
+// ResetAttempts forgets the failed logins with the key.
+func (PS *PgStorage) ResetAttempts(ctx context.Context, key string) error {
+	_, err := PS.connect.ExecContext(ctx, `delete from public.login_attempts where attempt_key = $1`, key)
+	return err
+}
@@ -0,0 +1,41 @@
+package attemptstorage
+
+import (
+	"context"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/attemptstorage/memstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/attemptstorage/pgstorage"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+)
+
+// AttemptStorage tracks the failed logins by key, a key is a login or an IP address.
+// AddFailure counts a failure atomically and returns the updated attempts; failures older than
+// the window are forgotten, so the count starts over. ReleaseFailure takes back the last failure
+// counted for an attempt that did not fail. GetAttempts returns zero attempts for an unknown key.
+type AttemptStorage interface {
+	Ping() error
+	Close() error
+	AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (servermodels.LoginAttempts, error)
+	ReleaseFailure(ctx context.Context, key string) error
+	GetAttempts(ctx context.Context, key string) (servermodels.LoginAttempts, error)
+	ResetAttempts(ctx context.Context, key string) error
+}
+
+// NewAttemptStorage creates the Postgres storage of the config, or the in-memory one without a database.
+func NewAttemptStorage(cfg servermodels.Config) (AttemptStorage, error) {
+	var attemptStorage AttemptStorage
+	var err error
+	if cfg.DataBaseURI != "" {
+		attemptStorage, err = pgstorage.New(cfg.DataBaseURI)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		attemptStorage, err = memstorage.New()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attemptStorage, nil
+}
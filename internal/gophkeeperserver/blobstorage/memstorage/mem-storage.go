@@ -0,0 +1,130 @@
+package memstorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type MemStorage struct {
+	blobCash  map[uuid.UUID]models.Blob
+	chunkCash map[uuid.UUID]map[int]servermodels.Chunk
+	mu        *sync.Mutex
+}
+
+func New() (*MemStorage, error) {
+	return &MemStorage{
+		blobCash:  make(map[uuid.UUID]models.Blob),
+		chunkCash: make(map[uuid.UUID]map[int]servermodels.Chunk),
+		mu:        new(sync.Mutex),
+	}, nil
+}
+
+func (MS *MemStorage) Ping() error {
+	return nil
+}
+
+func (MS *MemStorage) Close() error {
+	return nil
+}
+
+// CreateBlob registers a new blob or returns the blob the owner has already created.
+func (MS *MemStorage) CreateBlob(_ context.Context, blob models.Blob) (models.Blob, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if stored, ok := MS.blobCash[blob.ID]; ok {
+		if stored.OwnerID != blob.OwnerID || stored.Chunks != blob.Chunks {
+			return models.Blob{}, constans.ErrorNoUNIQUE
+		}
+		return MS.status(stored), nil
+	}
+	blob.Complete = false
+	MS.blobCash[blob.ID] = blob
+	MS.chunkCash[blob.ID] = make(map[int]servermodels.Chunk)
+	return MS.status(blob), nil
+}
+
+// GetBlob returns the blob with the stored chunks.
+func (MS *MemStorage) GetBlob(_ context.Context, ownerID string, blobID uuid.UUID) (models.Blob, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	blob, err := MS.owned(ownerID, blobID)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	return MS.status(blob), nil
+}
+
+// PutChunk stores the chunk of an incomplete blob.
+func (MS *MemStorage) PutChunk(_ context.Context, ownerID string, blobID uuid.UUID, chunk servermodels.Chunk) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	blob, err := MS.owned(ownerID, blobID)
+	if err != nil {
+		return err
+	}
+	if blob.Complete {
+		return constants.ErrBlobComplete
+	}
+	chunk.Data = append([]byte(nil), chunk.Data...)
+	MS.chunkCash[blobID][chunk.Index] = chunk
+	return nil
+}
+
+// GetChunk returns the chunk of the blob.
+func (MS *MemStorage) GetChunk(_ context.Context, ownerID string, blobID uuid.UUID, index int) (servermodels.Chunk, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if _, err := MS.owned(ownerID, blobID); err != nil {
+		return servermodels.Chunk{}, err
+	}
+	chunk, ok := MS.chunkCash[blobID][index]
+	if !ok {
+		return servermodels.Chunk{}, constants.ErrChunkNotFound
+	}
+	return chunk, nil
+}
+
+// CompleteBlob marks the blob complete if all its chunks are stored.
+func (MS *MemStorage) CompleteBlob(_ context.Context, ownerID string, blobID uuid.UUID) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	blob, err := MS.owned(ownerID, blobID)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < blob.Chunks; i++ {
+		if _, ok := MS.chunkCash[blobID][i]; !ok {
+			return constants.ErrBlobIncomplete
+		}
+	}
+	blob.Complete = true
+	MS.blobCash[blobID] = blob
+	return nil
+}
+
+func (MS *MemStorage) owned(ownerID string, blobID uuid.UUID) (models.Blob, error) {
+	blob, ok := MS.blobCash[blobID]
+	if !ok || blob.OwnerID != ownerID {
+		return models.Blob{}, constants.ErrBlobNotFound
+	}
+	return blob, nil
+}
+
+func (MS *MemStorage) status(blob models.Blob) models.Blob {
+	blob.Size = 0
+	blob.Uploaded = make([]int, 0, len(MS.chunkCash[blob.ID]))
+	for index, chunk := range MS.chunkCash[blob.ID] {
+		blob.Uploaded = append(blob.Uploaded, index)
+		blob.Size += int64(len(chunk.Data))
+	}
+	sort.Ints(blob.Uploaded)
+	return blob
+}
@@ -0,0 +1,67 @@
+package memstorage
+
+import (
+	"context"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemStorage_Blob(t *testing.T) {
+	storage, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	blobID := uuid.New()
+	blob, err := storage.CreateBlob(ctx, models.Blob{ID: blobID, OwnerID: "owner", Chunks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, blob.Uploaded)
+
+	_, err = storage.CreateBlob(ctx, models.Blob{ID: blobID, OwnerID: "other", Chunks: 2})
+	assert.ErrorIs(t, err, constans.ErrorNoUNIQUE)
+	err = storage.PutChunk(ctx, "other", blobID, servermodels.Chunk{Index: 0, Data: []byte("a")})
+	assert.ErrorIs(t, err, constants.ErrBlobNotFound)
+
+	if err = storage.PutChunk(ctx, "owner", blobID, servermodels.Chunk{Index: 1, Data: []byte("second"), Hash: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.ErrorIs(t, storage.CompleteBlob(ctx, "owner", blobID), constants.ErrBlobIncomplete)
+	_, err = storage.GetChunk(ctx, "owner", blobID, 0)
+	assert.ErrorIs(t, err, constants.ErrChunkNotFound)
+
+	blob, err = storage.CreateBlob(ctx, models.Blob{ID: blobID, OwnerID: "owner", Chunks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1}, blob.Uploaded, "creating the blob again resumes the upload")
+
+	if err = storage.PutChunk(ctx, "owner", blobID, servermodels.Chunk{Index: 0, Data: []byte("first"), Hash: "h0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.CompleteBlob(ctx, "owner", blobID); err != nil {
+		t.Fatal(err)
+	}
+	blob, err = storage.GetBlob(ctx, "owner", blobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, blob.Complete)
+	assert.Equal(t, []int{0, 1}, blob.Uploaded)
+	assert.Equal(t, int64(len("first")+len("second")), blob.Size)
+	assert.ErrorIs(t, storage.PutChunk(ctx, "owner", blobID, servermodels.Chunk{Index: 0}), constants.ErrBlobComplete)
+
+	chunk, err := storage.GetChunk(ctx, "owner", blobID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, servermodels.Chunk{Index: 1, Data: []byte("second"), Hash: "h1"}, chunk)
+}
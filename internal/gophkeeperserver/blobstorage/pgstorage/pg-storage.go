@@ -0,0 +1,206 @@
+package pgstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"yudinsv/gophkeeper/internal/blobstore"
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// PgStorage keeps the chunks larger than threshold bytes in the blob store, if it is set,
+// their rows are marked external and keep only the hash and size.
+type PgStorage struct {
+	connect   *sql.DB
+	blobs     blobstore.BlobStore
+	threshold int
+}
+
+func New(uri string, blobs blobstore.BlobStore, threshold int) (*PgStorage, error) {
+	connect, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, err
+	}
+	return &PgStorage{connect: connect, blobs: blobs, threshold: threshold}, nil
+}
+
+func (PS *PgStorage) Ping() error {
+	if err := PS.connect.Ping(); err != nil {
+		return err
+	}
+	return createTables(PS.connect)
+}
+
+func (PS *PgStorage) Close() error {
+	return PS.connect.Close()
+}
+
+func createTables(connect *sql.DB) error {
+	_, err := connect.Exec(`
+	create table if not exists public.blobs(
+		id uuid primary key,
+		owner_id text not null,
+		chunks integer not null,
+		complete boolean not null default false,
+		created_at timestamptz not null
+	);
+	create table if not exists public.blob_chunks(
+		blob_id uuid not null references public.blobs(id) on delete cascade,
+		chunk_index integer not null,
+		data bytea not null,
+		hash text not null,
+		primary key (blob_id, chunk_index)
+	);
+	alter table public.blob_chunks add column if not exists size bigint not null default 0;
+	alter table public.blob_chunks add column if not exists external boolean not null default false;
+	`)
+	return err
+}
+
+// CreateBlob registers a new blob or returns the blob the owner has already created.
+func (PS *PgStorage) CreateBlob(ctx context.Context, blob models.Blob) (models.Blob, error) {
+	_, err := PS.connect.ExecContext(ctx,
+		`insert into public.blobs (id, owner_id, chunks, created_at) values ($1, $2, $3, $4)
+		on conflict (id) do nothing`,
+		blob.ID, blob.OwnerID, blob.Chunks, time.Now())
+	if err != nil {
+		return models.Blob{}, err
+	}
+	stored, err := PS.GetBlob(ctx, blob.OwnerID, blob.ID)
+	if errors.Is(err, constants.ErrBlobNotFound) || err == nil && stored.Chunks != blob.Chunks {
+		return models.Blob{}, constans.ErrorNoUNIQUE
+	}
+	return stored, err
+}
+
+// GetBlob returns the blob with the stored chunks.
+func (PS *PgStorage) GetBlob(ctx context.Context, ownerID string, blobID uuid.UUID) (models.Blob, error) {
+	blob := models.Blob{ID: blobID, OwnerID: ownerID}
+	err := PS.connect.QueryRowContext(ctx,
+		`select chunks, complete from public.blobs where id = $1 and owner_id = $2`,
+		blobID, ownerID).Scan(&blob.Chunks, &blob.Complete)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Blob{}, constants.ErrBlobNotFound
+	}
+	if err != nil {
+		return models.Blob{}, err
+	}
+	rows, err := PS.connect.QueryContext(ctx,
+		`select chunk_index, case when external then size else length(data) end
+		from public.blob_chunks where blob_id = $1 order by chunk_index`,
+		blobID)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	defer rows.Close()
+	blob.Uploaded = []int{}
+	for rows.Next() {
+		var index int
+		var size int64
+		if err = rows.Scan(&index, &size); err != nil {
+			return models.Blob{}, err
+		}
+		blob.Uploaded = append(blob.Uploaded, index)
+		blob.Size += size
+	}
+	return blob, rows.Err()
+}
+
+// PutChunk stores the chunk of an incomplete blob.
+func (PS *PgStorage) PutChunk(ctx context.Context, ownerID string, blobID uuid.UUID, chunk servermodels.Chunk) error {
+	var complete bool
+	err := PS.connect.QueryRowContext(ctx,
+		`select complete from public.blobs where id = $1 and owner_id = $2`,
+		blobID, ownerID).Scan(&complete)
+	if errors.Is(err, sql.ErrNoRows) {
+		return constants.ErrBlobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if complete {
+		return constants.ErrBlobComplete
+	}
+	data, hash, err := blobstore.Offload(ctx, PS.blobs, PS.threshold, chunk.Data)
+	if err != nil {
+		return err
+	}
+	_, err = PS.connect.ExecContext(ctx,
+		`insert into public.blob_chunks (blob_id, chunk_index, data, hash, size, external) values ($1, $2, $3, $4, $5, $6)
+		on conflict (blob_id, chunk_index) do update set
+			data = excluded.data, hash = excluded.hash, size = excluded.size, external = excluded.external`,
+		blobID, chunk.Index, data, chunk.Hash, len(chunk.Data), hash != "")
+	return err
+}
+
+// GetChunk returns the chunk of the blob.
+func (PS *PgStorage) GetChunk(ctx context.Context, ownerID string, blobID uuid.UUID, index int) (servermodels.Chunk, error) {
+	chunk := servermodels.Chunk{Index: index}
+	var external bool
+	err := PS.connect.QueryRowContext(ctx,
+		`select c.data, c.hash, c.external from public.blob_chunks c join public.blobs b on b.id = c.blob_id
+		where b.id = $1 and b.owner_id = $2 and c.chunk_index = $3`,
+		blobID, ownerID, index).Scan(&chunk.Data, &chunk.Hash, &external)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err = PS.GetBlob(ctx, ownerID, blobID); err != nil {
+			return servermodels.Chunk{}, err
+		}
+		return servermodels.Chunk{}, constants.ErrChunkNotFound
+	}
+	if err != nil {
+		return servermodels.Chunk{}, err
+	}
+	if external {
+		if chunk.Data, err = blobstore.Load(ctx, PS.blobs, nil, chunk.Hash); err != nil {
+			return servermodels.Chunk{}, err
+		}
+	}
+	return chunk, nil
+}
+
+// Referenced returns the hashes of the chunks kept in the blob store.
+func (PS *PgStorage) Referenced(ctx context.Context) (map[string]bool, error) {
+	rows, err := PS.connect.QueryContext(ctx, `select distinct hash from public.blob_chunks where external`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		referenced[hash] = true
+	}
+	return referenced, rows.Err()
+}
+
+// CompleteBlob marks the blob complete if all its chunks are stored.
+// The check and the update are one statement, so a blob is never completed with missing chunks.
+func (PS *PgStorage) CompleteBlob(ctx context.Context, ownerID string, blobID uuid.UUID) error {
+	result, err := PS.connect.ExecContext(ctx,
+		`update public.blobs set complete = true where id = $1 and owner_id = $2
+		and chunks = (select count(*) from public.blob_chunks where blob_id = $1 and chunk_index < public.blobs.chunks)`,
+		blobID, ownerID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		if _, err = PS.GetBlob(ctx, ownerID, blobID); err != nil {
+			return err
+		}
+		return constants.ErrBlobIncomplete
+	}
+	return nil
+}
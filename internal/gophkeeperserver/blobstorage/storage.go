@@ -0,0 +1,51 @@
+package blobstorage
+
+import (
+	"context"
+
+	"yudinsv/gophkeeper/internal/blobstore"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/blobstorage/memstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/blobstorage/pgstorage"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// BlobStorage keeps the encrypted chunks of the file secrets, a secret refers to its blob by BlobID.
+// The blobs of other owners are reported as constants.ErrBlobNotFound.
+// CreateBlob returns the stored blob if the owner has already created it with the same number of chunks,
+// so an interrupted upload is resumed; a different blob with the ID is constans.ErrorNoUNIQUE.
+// PutChunk stores or replaces a chunk until CompleteBlob, which fails with constants.ErrBlobIncomplete
+// while chunks are missing; a complete blob is not changed anymore (constants.ErrBlobComplete).
+// GetBlob lists the indexes of the stored chunks in Uploaded and sums their sizes in Size.
+type BlobStorage interface {
+	Ping() error
+	Close() error
+	CreateBlob(ctx context.Context, blob models.Blob) (models.Blob, error)
+	GetBlob(ctx context.Context, ownerID string, blobID uuid.UUID) (models.Blob, error)
+	PutChunk(ctx context.Context, ownerID string, blobID uuid.UUID, chunk servermodels.Chunk) error
+	GetChunk(ctx context.Context, ownerID string, blobID uuid.UUID, index int) (servermodels.Chunk, error)
+	CompleteBlob(ctx context.Context, ownerID string, blobID uuid.UUID) error
+}
+
+func NewBlobStorage(cfg servermodels.Config) (BlobStorage, error) {
+	var blobStorage BlobStorage
+	var err error
+	if cfg.DataBaseURI != "" {
+		blobs, err := blobstore.NewBlobStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		blobStorage, err = pgstorage.New(cfg.DataBaseURI, blobs, cfg.BlobThreshold)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		blobStorage, err = memstorage.New()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blobStorage, nil
+}
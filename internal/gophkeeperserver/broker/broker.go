@@ -0,0 +1,40 @@
+package broker
+
+import (
+	"context"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/broker/membroker"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/broker/pgbroker"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// Broker delivers the change events of a user to the event streams of the user on all server instances.
+// Subscribe returns the channel of the events of the user and the function ending the subscription.
+// A subscriber that does not take an event in time misses it: an event only tells the device to pull
+// the changes, so the next event or the polling of the device catches up.
+type Broker interface {
+	Ping() error
+	Close() error
+	Publish(ctx context.Context, event models.ChangeEvent) error
+	Subscribe(userID string) (<-chan models.ChangeEvent, func())
+}
+
+// NewBroker creates the broker of the server: with a database the events are sent by Postgres LISTEN/NOTIFY,
+// so they reach the subscribers of every server instance, otherwise only the subscribers of this instance.
+func NewBroker(cfg servermodels.Config) (Broker, error) {
+	var broker Broker
+	var err error
+	if cfg.DataBaseURI != "" {
+		broker, err = pgbroker.New(cfg.DataBaseURI)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		broker, err = membroker.New()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return broker, nil
+}
@@ -0,0 +1,66 @@
+package membroker
+
+import (
+	"context"
+	"sync"
+
+	"yudinsv/gophkeeper/internal/models"
+)
+
+// subscriberBuffer is the number of the events a subscriber can fall behind.
+const subscriberBuffer = 16
+
+type MemBroker struct {
+	subscribers map[string]map[chan models.ChangeEvent]struct{}
+	mu          *sync.Mutex
+}
+
+func New() (*MemBroker, error) {
+	return &MemBroker{
+		subscribers: make(map[string]map[chan models.ChangeEvent]struct{}),
+		mu:          new(sync.Mutex),
+	}, nil
+}
+
+func (MB *MemBroker) Ping() error {
+	return nil
+}
+
+func (MB *MemBroker) Close() error {
+	return nil
+}
+
+// Publish sends the event to the subscribers of its owner without waiting for them.
+func (MB *MemBroker) Publish(_ context.Context, event models.ChangeEvent) error {
+	MB.mu.Lock()
+	defer MB.mu.Unlock()
+	for events := range MB.subscribers[event.OwnerID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the events of the user.
+func (MB *MemBroker) Subscribe(userID string) (<-chan models.ChangeEvent, func()) {
+	events := make(chan models.ChangeEvent, subscriberBuffer)
+	MB.mu.Lock()
+	defer MB.mu.Unlock()
+	if MB.subscribers[userID] == nil {
+		MB.subscribers[userID] = make(map[chan models.ChangeEvent]struct{})
+	}
+	MB.subscribers[userID][events] = struct{}{}
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			MB.mu.Lock()
+			defer MB.mu.Unlock()
+			delete(MB.subscribers[userID], events)
+			if len(MB.subscribers[userID]) == 0 {
+				delete(MB.subscribers, userID)
+			}
+		})
+	}
+}
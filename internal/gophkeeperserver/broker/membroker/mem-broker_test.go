@@ -0,0 +1,42 @@
+package membroker
+
+import (
+	"context"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemBroker(t *testing.T) {
+	broker, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	first, unsubscribeFirst := broker.Subscribe("user")
+	second, unsubscribeSecond := broker.Subscribe("user")
+	other, unsubscribeOther := broker.Subscribe("other")
+	defer unsubscribeOther()
+
+	event := models.ChangeEvent{OwnerID: "user", SecretID: uuid.New(), Revision: 2}
+	assert.NoError(t, broker.Publish(ctx, event))
+	assert.Equal(t, event, <-first)
+	assert.Equal(t, event, <-second)
+	assert.Empty(t, other, "the events of other users are not delivered")
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	assert.NoError(t, broker.Publish(ctx, event))
+	assert.Empty(t, first, "no events after unsubscribing")
+	assert.Equal(t, event, <-second)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		assert.NoError(t, broker.Publish(ctx, event), "a slow subscriber does not block publishing")
+	}
+	assert.Len(t, second, subscriberBuffer)
+	unsubscribeSecond()
+	assert.Empty(t, broker.subscribers["user"])
+}
@@ -0,0 +1,105 @@
+package pgbroker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/broker/membroker"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// channel is the notification channel of the change events.
+const channel = "gophkeeper_changes"
+
+// notification is the payload of the notification, the owner is not part of the event sent to the devices.
+type notification struct {
+	OwnerID string             `json:"owner_id"`
+	Event   models.ChangeEvent `json:"event"`
+}
+
+// PgBroker sends the events with NOTIFY and delivers the notifications received with LISTEN
+// to the subscribers of this instance, so an event published by any instance reaches all of them.
+type PgBroker struct {
+	connect  *sql.DB
+	listener *pq.Listener
+	local    *membroker.MemBroker
+	listen   sync.Once
+}
+
+func New(uri string) (*PgBroker, error) {
+	connect, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, err
+	}
+	local, err := membroker.New()
+	if err != nil {
+		return nil, err
+	}
+	listener := pq.NewListener(uri, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("change events listener", err)
+		}
+	})
+	return &PgBroker{connect: connect, listener: listener, local: local}, nil
+}
+
+// Ping checks the database and starts listening to the notifications.
+func (PB *PgBroker) Ping() error {
+	if err := PB.connect.Ping(); err != nil {
+		return err
+	}
+	if err := PB.listener.Listen(channel); err != nil && !errors.Is(err, pq.ErrChannelAlreadyOpen) {
+		return err
+	}
+	PB.listen.Do(func() { go PB.deliver() })
+	return nil
+}
+
+func (PB *PgBroker) Close() error {
+	if err := PB.listener.Close(); err != nil {
+		log.Println(err)
+	}
+	return PB.connect.Close()
+}
+
+// Publish notifies all instances of the event.
+func (PB *PgBroker) Publish(ctx context.Context, event models.ChangeEvent) error {
+	payload, err := json.Marshal(notification{OwnerID: event.OwnerID, Event: event})
+	if err != nil {
+		return err
+	}
+	_, err = PB.connect.ExecContext(ctx, `select pg_notify($1, $2)`, channel, string(payload))
+	return err
+}
+
+// Subscribe subscribes to the events of the user received by this instance.
+func (PB *PgBroker) Subscribe(userID string) (<-chan models.ChangeEvent, func()) {
+	return PB.local.Subscribe(userID)
+}
+
+// deliver passes the notifications to the subscribers until the listener is closed.
+// The notifications sent while the connection was lost are missed, the devices poll meanwhile.
+func (PB *PgBroker) deliver() {
+	for received := range PB.listener.Notify {
+		if received == nil {
+			// The connection was reestablished.
+			continue
+		}
+		var message notification
+		if err := json.Unmarshal([]byte(received.Extra), &message); err != nil {
+			log.Println("invalid change notification", err)
+			continue
+		}
+		message.Event.OwnerID = message.OwnerID
+		if err := PB.local.Publish(context.Background(), message.Event); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -7,6 +7,53 @@ import (
 
 const CookeUserIDName = "UserID" // Name of the user ID.
 
+const SessionIDName = "SessionID" // Name of the session ID of the request.
+
+// SessionTouchInterval is how often the last-seen time of a session is updated.
+const SessionTouchInterval = time.Minute
+
 const (
 	TimeOutRequest = time.Duration(5 * time.Second) //Duration to wait for a request to complete before timing out.
 )
+
+// Token lifetimes used when the config does not set them.
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Brute-force protection of the login. After the free failed attempts every further failure doubles
+// the delay before the next attempt, starting from LoginBackoffBase up to the LoginBackoffMax lockout.
+// The failures are forgotten after LoginAttemptsWindow without a failure.
+const (
+	LoginFreeAttempts   = 5                // Failed logins with one login before the delay starts.
+	IPFreeAttempts      = 20               // Failed logins from one IP address before the delay starts.
+	LoginBackoffBase    = time.Second      // Delay after the first failure over the free attempts.
+	LoginBackoffMax     = 15 * time.Minute // Longest delay, a temporary lockout.
+	LoginAttemptsWindow = time.Hour        // Time without failures after which they are forgotten.
+)
+
+// Second factor of the login.
+const (
+	ScopeMFA           = "mfa"           // Scope of the restricted token issued before the second factor is verified.
+	MFATokenTTL        = 5 * time.Minute // Lifetime of the restricted token.
+	TOTPIssuer         = "GophKeeper"    // Issuer shown by the authenticator apps.
+	RecoveryCodesCount = 10              // Number of the recovery codes issued on enrollment.
+	MFAVerifyPath      = "/2fa/verify"   // Path suffix of the endpoint accepting the restricted token.
+)
+
+// ChangesPollInterval is how often a followed gRPC change feed checks the storage for new changes.
+const ChangesPollInterval = time.Second
+
+// ChangesPageSize is the maximum number of secrets in one answer to a delta sync request.
+const ChangesPageSize = 500
+
+const (
+	MaxChunkSize  = 8 << 20 // Largest blob chunk accepted, the clients send 4 MiB of data plus the encryption overhead.
+	MaxBlobChunks = 1 << 16 // Most chunks of one blob.
+)
+
+const (
+	EventsHeartbeat   = 15 * time.Second // Interval of the comments keeping an idle event stream open.
+	EventsMaxDuration = 15 * time.Minute // Lifetime of an event stream, the device reconnects with a fresh access token.
+)
@@ -11,3 +11,12 @@ const (
 
 // ErrorNoUNIQUE occurs when a value is not unique.
 var ErrorNoUNIQUE = errors.New("value is not unique")
+
+// ErrTokenNotFound occurs when a refresh token is unknown.
+var ErrTokenNotFound = errors.New("refresh token not found")
+
+// ErrTOTPNotFound occurs when the user has not enrolled the second factor.
+var ErrTOTPNotFound = errors.New("second factor not enrolled")
+
+// ErrTokenReused occurs when a refresh token that has already been rotated is used again.
+var ErrTokenReused = errors.New("refresh token reused")
@@ -2,6 +2,10 @@
 package container
 
 import (
+	"yudinsv/gophkeeper/internal/gophkeeperserver/attemptstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/blobstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/broker"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/keyset"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
 	"yudinsv/gophkeeper/internal/keeperstorage"
@@ -15,6 +19,8 @@ var DiContainer di.Container
 // BuildContainer creates a new dependency injection container and initializes it
 // with the necessary dependencies used throughout the code. It assigns the result
 // to the DiContainer variable.
+// The storages of the failed logins and of the file chunks and the broker of the change events are created from the config, in Postgres if the database is configured,
+// and so is the keyset signing the tokens.
 func BuildContainer(cfg models.Config, storage userstorage.UserStorage, keeperStorage keeperstorage.KeeperStorage) error {
 	builder, err := di.NewBuilder()
 	if err != nil {
@@ -26,6 +32,31 @@ func BuildContainer(cfg models.Config, storage userstorage.UserStorage, keeperSt
 	if err = keeperStorage.Ping(); err != nil {
 		return err
 	}
+	attemptStorage, err := attemptstorage.NewAttemptStorage(cfg)
+	if err != nil {
+		return err
+	}
+	if err = attemptStorage.Ping(); err != nil {
+		return err
+	}
+	blobStorage, err := blobstorage.NewBlobStorage(cfg)
+	if err != nil {
+		return err
+	}
+	if err = blobStorage.Ping(); err != nil {
+		return err
+	}
+	changes, err := broker.NewBroker(cfg)
+	if err != nil {
+		return err
+	}
+	if err = changes.Ping(); err != nil {
+		return err
+	}
+	keys, err := keyset.New(cfg)
+	if err != nil {
+		return err
+	}
 	if err = builder.Add(di.Def{
 		Name:  "server-config",
 		Build: func(ctn di.Container) (interface{}, error) { return cfg, nil }}); err != nil {
@@ -41,6 +72,29 @@ func BuildContainer(cfg models.Config, storage userstorage.UserStorage, keeperSt
 		Build: func(ctn di.Container) (interface{}, error) { return keeperStorage, nil }}); err != nil {
 		return err
 	}
+	if err = builder.Add(di.Def{
+		Name:  "attemptstorage",
+		Build: func(ctn di.Container) (interface{}, error) { return attemptStorage, nil },
+		Close: func(obj interface{}) error { return obj.(attemptstorage.AttemptStorage).Close() }}); err != nil {
+		return err
+	}
+	if err = builder.Add(di.Def{
+		Name:  "blobstorage",
+		Build: func(ctn di.Container) (interface{}, error) { return blobStorage, nil },
+		Close: func(obj interface{}) error { return obj.(blobstorage.BlobStorage).Close() }}); err != nil {
+		return err
+	}
+	if err = builder.Add(di.Def{
+		Name:  "broker",
+		Build: func(ctn di.Container) (interface{}, error) { return changes, nil },
+		Close: func(obj interface{}) error { return obj.(broker.Broker).Close() }}); err != nil {
+		return err
+	}
+	if err = builder.Add(di.Def{
+		Name:  "keyset",
+		Build: func(ctn di.Container) (interface{}, error) { return keys, nil }}); err != nil {
+		return err
+	}
 	DiContainer = builder.Build()
 	return nil
 }
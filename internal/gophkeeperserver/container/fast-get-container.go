@@ -1,6 +1,10 @@
 package container
 
 import (
+	"yudinsv/gophkeeper/internal/gophkeeperserver/attemptstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/blobstorage"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/broker"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/keyset"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage"
 	"yudinsv/gophkeeper/internal/keeperstorage"
@@ -14,6 +18,22 @@ func GetKeeperStorage() keeperstorage.KeeperStorage {
 	return DiContainer.Get("keeperstorage").(keeperstorage.KeeperStorage)
 }
 
+func GetAttemptStorage() attemptstorage.AttemptStorage {
+	return DiContainer.Get("attemptstorage").(attemptstorage.AttemptStorage)
+}
+
+func GetBlobStorage() blobstorage.BlobStorage {
+	return DiContainer.Get("blobstorage").(blobstorage.BlobStorage)
+}
+
+func GetBroker() broker.Broker {
+	return DiContainer.Get("broker").(broker.Broker)
+}
+
+func GetKeySet() *keyset.KeySet {
+	return DiContainer.Get("keyset").(*keyset.KeySet)
+}
+
 func GetConfig() models.Config {
 	return DiContainer.Get("server-config").(models.Config)
 }
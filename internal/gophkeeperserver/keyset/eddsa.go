@@ -0,0 +1,61 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/ed25519"
+
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+// SigningMethodEdDSA signs the tokens with Ed25519 keys, the "EdDSA" algorithm of RFC 8037.
+// It expects an ed25519.PrivateKey for signing and an ed25519.PublicKey for verification.
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+type signingMethodEdDSA struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+// Alg implements the Alg method from jwt.SigningMethod.
+func (m *signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+// Verify implements the Verify method from jwt.SigningMethod.
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	var publicKey ed25519.PublicKey
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		publicKey = k
+	case crypto.Signer:
+		var ok bool
+		if publicKey, ok = k.Public().(ed25519.PublicKey); !ok {
+			return jwt.NewInvalidKeyTypeError("ed25519.PublicKey", key)
+		}
+	default:
+		return jwt.NewInvalidKeyTypeError("ed25519.PublicKey", key)
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return jwt.NewInvalidKeyTypeError("ed25519.PublicKey", key)
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+// Sign implements the Sign method from jwt.SigningMethod.
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.NewInvalidKeyTypeError("ed25519.PrivateKey", key)
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
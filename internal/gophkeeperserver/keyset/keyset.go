@@ -0,0 +1,223 @@
+// Package keyset holds the keys signing and verifying the access tokens.
+//
+// The keys are PEM files in a directory, the key ID (the "kid" header of the tokens) is the file name
+// without the ".pem" extension. A file holds an Ed25519 or RSA private key, signing with EdDSA or RS256,
+// or only the public key of a retired key. Every key verifies tokens; the configured key or, by default,
+// the private key with the greatest ID signs them. To rotate, add a new private key named after the old ones
+// and remove the old key once the tokens it signed have expired; the directory is reloaded periodically.
+//
+// Without a directory the tokens are signed with HS256 and the shared secret, the keyset then has no public keys.
+package keyset
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
+
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+// keyExt is the extension of the key files.
+const keyExt = ".pem"
+
+// ErrUnknownKey occurs when a token is signed with a key missing from the keyset.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// key is a key of the keyset, private is nil for a verification-only key.
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// KeySet signs the tokens with the current key and verifies them with any key of the set.
+// It is safe for concurrent use, Reload replaces the keys while tokens are signed and verified.
+type KeySet struct {
+	dir       string
+	signingID string
+	mu        sync.RWMutex
+	signing   key
+	keys      map[string]key
+}
+
+// New creates the keyset of the config: the keys of JWTKeysDir, or the HMAC secret if no directory is set.
+func New(cfg models.Config) (*KeySet, error) {
+	if cfg.JWTKeysDir == "" {
+		return NewHMAC([]byte(cfg.SecretKey)), nil
+	}
+	return Load(cfg.JWTKeysDir, cfg.JWTSigningKeyID)
+}
+
+// NewHMAC creates a keyset signing and verifying the tokens with HS256 and the shared secret.
+func NewHMAC(secret []byte) *KeySet {
+	hmacKey := key{method: jwt.SigningMethodHS256, private: secret, public: secret}
+	return &KeySet{signing: hmacKey, keys: map[string]key{"": hmacKey}}
+}
+
+// Load creates a keyset from the key files of the directory.
+// signingID selects the signing key, an empty one selects the private key with the greatest ID.
+func Load(dir string, signingID string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, signingID: signingID}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload reads the key files of the directory again. On error the keys are kept unchanged.
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*"+keyExt))
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]key, len(paths))
+	var signingIDs []string
+	for _, path := range paths {
+		k, err := readKey(path)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", path, err)
+		}
+		keys[k.id] = k
+		if k.private != nil {
+			signingIDs = append(signingIDs, k.id)
+		}
+	}
+	signingID := ks.signingID
+	if signingID == "" && len(signingIDs) > 0 {
+		sort.Strings(signingIDs)
+		signingID = signingIDs[len(signingIDs)-1]
+	}
+	signing, ok := keys[signingID]
+	if !ok || signing.private == nil {
+		return fmt.Errorf("no private key %q in %s", signingID, ks.dir)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	ks.signing = signing
+	return nil
+}
+
+// Watch reloads the keys every interval until the context is done, so rotated keys are picked up.
+// A keyset without a directory or a non-positive interval has nothing to watch.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	if ks.dir == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				log.Println("error reloading the signing keys", err)
+			}
+		}
+	}
+}
+
+// Sign signs the claims with the current signing key and sets its ID in the "kid" header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	signing := ks.signing
+	ks.mu.RUnlock()
+	token := jwt.NewWithClaims(signing.method, claims)
+	if signing.id != "" {
+		token.Header["kid"] = signing.id
+	}
+	return token.SignedString(signing.private)
+}
+
+// Keyfunc returns the verification key of the token by its "kid" header.
+// The algorithm of the token has to be the algorithm of the key, so a public key is never used as an HMAC secret.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return k.public, nil
+}
+
+// readKey reads a private or public key from the PEM file.
+func readKey(path string) (key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return key{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key{}, errors.New("no PEM data")
+	}
+	k := key{id: strings.TrimSuffix(filepath.Base(path), keyExt)}
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return key{}, err
+	}
+	switch typed := parsed.(type) {
+	case ed25519.PrivateKey:
+		k.method, k.private, k.public = SigningMethodEdDSA, typed, typed.Public()
+	case *rsa.PrivateKey:
+		k.method, k.private, k.public = jwt.SigningMethodRS256, typed, &typed.PublicKey
+	case ed25519.PublicKey:
+		k.method, k.public = SigningMethodEdDSA, typed
+	case *rsa.PublicKey:
+		k.method, k.public = jwt.SigningMethodRS256, typed
+	default:
+		return key{}, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return k, nil
+}
+
+// GenerateEd25519 writes a new Ed25519 private key to the directory and returns its ID.
+// The ID is the current time, so the new key sorts after the old ones and becomes the signing key.
+func GenerateEd25519(dir string) (string, error) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format("20060102T150405Z")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return id, os.WriteFile(filepath.Join(dir, id+keyExt), data, 0o600)
+}
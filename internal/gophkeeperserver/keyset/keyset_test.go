@@ -0,0 +1,181 @@
+package keyset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, ks *KeySet, token string) (*jwt.StandardClaims, error) {
+	t.Helper()
+	claims := &jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, ks.Keyfunc)
+	return claims, err
+}
+
+func sign(t *testing.T, ks *KeySet, subject string) string {
+	t.Helper()
+	token, err := ks.Sign(&jwt.StandardClaims{Subject: subject, ExpiresAt: jwt.At(time.Now().Add(time.Minute))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2026-01.pem"), edKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := sign(t, ks, "old")
+	token, _, err := new(jwt.Parser).ParseUnverified(oldToken, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2026-01", token.Header["kid"])
+	assert.Equal(t, "EdDSA", token.Header["alg"])
+
+	// A newer key signs after the reload, the old one still verifies.
+	if err = os.WriteFile(filepath.Join(dir, "2026-02.pem"), rsaKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	newToken := sign(t, ks, "new")
+	claims, err := parse(t, ks, newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", claims.Subject)
+	token, _, err = new(jwt.Parser).ParseUnverified(newToken, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "RS256", token.Header["alg"])
+	_, err = parse(t, ks, oldToken)
+	assert.NoError(t, err)
+	jwks := ks.JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+	}
+
+	// The removed key does not verify any more.
+	if err = os.Remove(filepath.Join(dir, "2026-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = parse(t, ks, oldToken)
+	var unverifiable *jwt.UnverfiableTokenError
+	assert.True(t, errors.As(err, &unverifiable), "error %v", err)
+}
+
+func TestKeySet_RejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ed.pem"), edKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := Load(dir, "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{Subject: "forged"})
+	forged.Header["kid"] = "ed"
+	token, err := forged.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parse(t, ks, token)
+	assert.Error(t, err)
+
+	hmac := NewHMAC([]byte("secret"))
+	_, err = parse(t, hmac, sign(t, ks, "ed"))
+	assert.Error(t, err, "an HMAC keyset does not accept signed tokens")
+	assert.Empty(t, hmac.JWKS().Keys)
+}
+
+func TestLoad_NoPrivateKey(t *testing.T) {
+	_, err := Load(t.TempDir(), "")
+	assert.Error(t, err)
+}
+
+func TestGenerateEd25519(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	id, err := GenerateEd25519(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, ks.JWKS().Keys[0].KeyID)
+	info, err := os.Stat(filepath.Join(dir, id+keyExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func edKeyPEM(t *testing.T) []byte {
+	dir := t.TempDir()
+	id, err := GenerateEd25519(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id+keyExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func rsaKeyPEM(t *testing.T) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+}
+
+func TestKeySet_WatchNothing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2026-01.pem"), edKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, watch := range map[string]func(){
+		"HMAC secret":    func() { NewHMAC([]byte("secret")).Watch(context.Background(), time.Minute) },
+		"zero interval":  func() { loaded.Watch(context.Background(), 0) },
+		"negative value": func() { loaded.Watch(context.Background(), -time.Minute) },
+	} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			watch()
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("%s: Watch did not return", name)
+		}
+	}
+}
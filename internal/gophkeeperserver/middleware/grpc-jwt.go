@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+
+	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
+	pb "yudinsv/gophkeeper/internal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods are the gRPC methods called without a token.
+var publicMethods = map[string]bool{
+	pb.Keeper_Ping_FullMethodName:     true,
+	pb.Keeper_Register_FullMethodName: true,
+	pb.Keeper_Login_FullMethodName:    true,
+}
+
+// claimsKey is the context key of the claims of the authenticated gRPC call.
+type claimsKey struct{}
+
+// JwtUnaryInterceptor is JwtValid for the unary gRPC calls: the access token is taken from the "authorization"
+// metadata, any invalid or expired token and a revoked session fail the call with UNAUTHENTICATED.
+// The restricted token of the second factor is not accepted, the second factor is verified with the REST API.
+// The claims of the token are available to the handlers with ClaimsFromContext.
+func JwtUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// JwtStreamInterceptor is JwtUnaryInterceptor for the streaming gRPC calls.
+func JwtStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ClaimsFromContext returns the claims of the token of the authenticated gRPC call.
+func ClaimsFromContext(ctx context.Context) (*models.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*models.Claims)
+	return claims, ok
+}
+
+// PeerIP returns the IP address of the client of the gRPC call.
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// authenticatedStream replaces the context of the stream with the one carrying the claims.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate checks the access token of the call and returns the context with its claims.
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "no access token")
+	}
+	headerParts := strings.Split(values[0], " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+	}
+	claims, err := parseToken(headerParts[1], container.GetKeySet())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if claims.Scope == constans.ScopeMFA {
+		return nil, status.Error(codes.Unauthenticated, "the second factor is not verified")
+	}
+	valid, err := checkSession(ctx, claims, PeerIP(ctx))
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, constans.ErrorWorkDataBase)
+	}
+	if !valid {
+		return nil, status.Error(codes.Unauthenticated, "session revoked")
+	}
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
@@ -8,6 +8,8 @@ Function Signature:
 
 func JwtValid() gin.HandlerFunc
 
+The register, login and token/refresh endpoints are not checked.
+
 Parameters: None
 
 Return Values: gin.HandlerFunc - a middleware function that accepts the gin context and performs the JWT validation.
@@ -19,42 +21,53 @@ If the "Authorization" header is empty, aborts the request with HTTP status code
 Splits the header value into two parts using whitespace as the separator.
 If the header value does not contain two parts, aborts the request with HTTP status code 401 (Unauthorized).
 If the first part of the header value is not "Bearer", aborts the request with HTTP status code 401 (Unauthorized).
-Calls the "parseToken" function to validate the JWT token using the secret key.
+Calls the "parseToken" function to validate the JWT token with the keyset.
 If the token is invalid, aborts the request with HTTP status code 401 (Unauthorized) or 400 (Bad Request) based on the error.
-If the token is valid, adds the login user parameter to the context and calls the next middleware function.
+An expired token or a token signed with a key no longer in the keyset is answered with 401 (Unauthorized),
+so the client knows to refresh it.
+A restricted token issued before the second factor is verified is only accepted by constans.MFAVerifyPath,
+which accepts no other token; such a request gets only the login user parameter.
+Checks the session of the token: a revoked or unknown session aborts the request with HTTP status code 401 (Unauthorized),
+otherwise the last-seen time and IP address of the session are updated at most once per constans.SessionTouchInterval.
+If the token is valid, adds the login user and session ID parameters to the context and calls the next middleware function.
 Function Name: parseToken
 
-Description: parseToken is a helper function that validates the JWT token and returns the login user.
+Description: parseToken is a helper function that validates the JWT token and returns its claims.
 
 Function Signature:
 
-func parseToken(accessToken string, signingKey []byte) (string, error)
+func parseToken(accessToken string, keys *keyset.KeySet) (*models.Claims, error)
 
 Parameters:
 
 accessToken string: the JWT token to be validated.
-signingKey []byte: the secret key used for validating the token.
+keys *keyset.KeySet: the keyset holding the key selected by the "kid" header of the token.
 Return Values:
 
-string: the login user extracted from the token.
+*models.Claims: the login user and session ID extracted from the token.
 error: an error if the token is invalid.
 Functionality:
 
 Parses the JWT token using jwt.ParseWithClaims function.
-Validates the token using the signing key.
+Validates the token using the verification key of the keyset, the algorithm of the token has to match the key.
 Returns an error if the token is invalid.
-Returns the login user extracted from the token if the token is valid.
+Returns the claims extracted from the token if the token is valid.
 */
 
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/container"
+	"yudinsv/gophkeeper/internal/gophkeeperserver/keyset"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/models"
 
 	"github.com/dgrijalva/jwt-go/v4"
@@ -65,7 +78,8 @@ import (
 // JwtValid Validate the JWT token.
 func JwtValid() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if strings.Contains(c.Request.URL.Path, "register") || strings.Contains(c.Request.URL.Path, "login") {
+		if strings.Contains(c.Request.URL.Path, "register") || strings.Contains(c.Request.URL.Path, "login") ||
+			strings.Contains(c.Request.URL.Path, "token/refresh") {
 			return
 		}
 		authHeader := c.GetHeader("Authorization")
@@ -84,36 +98,81 @@ func JwtValid() gin.HandlerFunc {
 			return
 		}
 
-		login, err := parseToken(headerParts[1],
-			[]byte(container.GetConfig().SecretKey),
-		)
+		claims, err := parseToken(headerParts[1], container.GetKeySet())
 		if err != nil {
 			status := http.StatusBadRequest
-			if err == auth.ErrInvalidAccessToken {
+			var expired *jwt.TokenExpiredError
+			var unverifiable *jwt.UnverfiableTokenError
+			if err == auth.ErrInvalidAccessToken || errors.As(err, &expired) || errors.As(err, &unverifiable) {
 				status = http.StatusUnauthorized
 			}
 
 			c.AbortWithStatus(status)
 			return
 		}
-		c.AddParam(constans.CookeUserIDName, login)
+		if claims.Scope == constans.ScopeMFA || strings.HasSuffix(c.Request.URL.Path, constans.MFAVerifyPath) {
+			// The restricted token only completes the login with the second factor, and only it does.
+			if claims.Scope != constans.ScopeMFA || !strings.HasSuffix(c.Request.URL.Path, constans.MFAVerifyPath) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.AddParam(constans.CookeUserIDName, claims.Login)
+			return
+		}
+		if !validSession(c, claims) {
+			return
+		}
+		c.AddParam(constans.CookeUserIDName, claims.Login)
+		c.AddParam(constans.SessionIDName, claims.SessionID)
+	}
+}
+
+// validSession checks that the session of the token has not been revoked and updates its last-seen time.
+// It aborts the request and returns false if the session is revoked or unknown.
+func validSession(c *gin.Context, claims *models.Claims) bool {
+	valid, err := checkSession(c.Request.Context(), claims, c.ClientIP())
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return false
 	}
+	if !valid {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+	return true
 }
 
-// parseToken parses the JWT token and returns the login user.
-func parseToken(accessToken string, signingKey []byte) (string, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// checkSession reports whether the session of the token is still valid and updates its last-seen time and IP address.
+func checkSession(ctx context.Context, claims *models.Claims, ip string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, constans.TimeOutRequest)
+	defer cancel()
+	storage := container.GetUserStorage()
+	session, err := storage.GetSession(ctx, claims.SessionID)
+	if err != nil && !errors.Is(err, constants.ErrSessionNotFound) {
+		return false, err
+	}
+	if err != nil || session.Revoked || session.Login != claims.Login {
+		return false, nil
+	}
+	now := time.Now()
+	if now.Sub(session.LastSeen) >= constans.SessionTouchInterval || session.IP != ip {
+		if err = storage.TouchSession(ctx, session.ID, ip, now); err != nil {
+			log.Println(err)
 		}
-		return signingKey, nil
-	})
+	}
+	return true, nil
+}
+
+// parseToken parses the JWT token and returns its claims.
+func parseToken(accessToken string, keys *keyset.KeySet) (*models.Claims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &models.Claims{}, keys.Keyfunc)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if claims, ok := token.Claims.(*models.Claims); ok && token.Valid {
-		return claims.Login, nil
+		return claims, nil
 	}
 
-	return "", auth.ErrInvalidAccessToken
+	return nil, auth.ErrInvalidAccessToken
 }
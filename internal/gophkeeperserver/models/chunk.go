@@ -0,0 +1,8 @@
+package models
+
+// Chunk is a chunk of a blob, Hash is the hex SHA-256 digest of Data.
+type Chunk struct {
+	Index int
+	Data  []byte
+	Hash  string
+}
@@ -4,5 +4,8 @@ import "github.com/dgrijalva/jwt-go/v4"
 
 type Claims struct {
 	jwt.StandardClaims
-	Login string
+	Login     string
+	SessionID string `json:"sid"`
+	// Scope restricts the token, a token with constans.ScopeMFA only completes the login with the second factor.
+	Scope string `json:"scope,omitempty"`
 }
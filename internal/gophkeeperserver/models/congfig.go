@@ -1,8 +1,95 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
+// DefaultSecretKey is the default HMAC secret, it is public so the server only accepts it in the dev mode.
+const DefaultSecretKey = "secret-key"
+
 type Config struct {
 	Address     string `env:"RUN_ADDRESS" envDefault:"localhost:8080"`
 	DataBaseURI string `env:"DATABASE_URI"`
 	SecretKey   string `env:"SECRET_KEY" envDefault:"secret-key"`
 	DBPath      string `env:"DB_PATH"`
+	// GRPCAddress is the address of the gRPC API: the server listens on it if it is set,
+	// the client syncs over gRPC instead of the REST API.
+	GRPCAddress string `env:"GRPC_ADDRESS"`
+	// SecretVersions is the number of the last revisions kept in the history of every secret.
+	SecretVersions int `env:"SECRET_VERSIONS" envDefault:"10"`
+	// AccessTokenTTL and RefreshTokenTTL are the lifetimes of the issued tokens.
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	// JWTKeysDir is the directory with the keys signing the tokens, without it the tokens are signed with SecretKey.
+	// JWTSigningKeyID selects the signing key, by default the private key with the greatest ID signs.
+	// The keys are read again every JWTKeysReload, zero disables the reload.
+	JWTKeysDir      string        `env:"JWT_KEYS_DIR"`
+	JWTSigningKeyID string        `env:"JWT_SIGNING_KEY_ID"`
+	JWTKeysReload   time.Duration `env:"JWT_KEYS_RELOAD" envDefault:"1m"`
+	// TLSCertFile and TLSKeyFile enable HTTPS, the files are read again on SIGHUP.
+	// TLSClientCAFile requires the clients to present a certificate signed by one of its CAs.
+	// TLSSelfSigned generates a self-signed certificate into the files on the first start, only in the dev mode.
+	TLSCertFile     string `env:"TLS_CERT_FILE"`
+	TLSKeyFile      string `env:"TLS_KEY_FILE"`
+	TLSClientCAFile string `env:"TLS_CLIENT_CA_FILE"`
+	TLSSelfSigned   bool   `env:"TLS_SELF_SIGNED"`
+	// TLSCAFile, TLSClientCertFile and TLSClientKeyFile are the client options: the CA bundle trusted
+	// in addition to the system roots and the certificate presented to a server requiring mutual TLS.
+	TLSCAFile         string `env:"TLS_CA_FILE"`
+	TLSClientCertFile string `env:"TLS_CLIENT_CERT_FILE"`
+	TLSClientKeyFile  string `env:"TLS_CLIENT_KEY_FILE"`
+	// SessionFile keeps the session of the client commands between the runs, by default gophkeeper/session.json
+	// in the user config directory. MasterPassword unlocks the vault for the commands without asking on the terminal.
+	SessionFile    string `env:"SESSION_FILE"`
+	MasterPassword string `env:"MASTER_PASSWORD"`
+	// AgentSocket is the Unix socket of the agent keeping the vault unlocked between the client commands,
+	// by default gophkeeper/agent.sock in XDG_RUNTIME_DIR. The agent locks after AgentIdleTimeout without requests.
+	AgentSocket      string        `env:"AGENT_SOCKET"`
+	AgentIdleTimeout time.Duration `env:"AGENT_IDLE_TIMEOUT" envDefault:"15m"`
+	// BlobStoreDir keeps the secret values and file chunks larger than BlobThreshold bytes in files
+	// under the directory instead of the database, S3Endpoint and S3Bucket keep them in an S3-compatible bucket.
+	// The payloads no longer referenced are deleted every BlobGCInterval.
+	BlobStoreDir   string        `env:"BLOB_STORE_DIR"`
+	BlobThreshold  int           `env:"BLOB_THRESHOLD" envDefault:"65536"`
+	BlobGCInterval time.Duration `env:"BLOB_GC_INTERVAL" envDefault:"1h"`
+	S3Endpoint     string        `env:"S3_ENDPOINT"`
+	S3Bucket       string        `env:"S3_BUCKET"`
+	S3Region       string        `env:"S3_REGION" envDefault:"us-east-1"`
+	S3AccessKey    string        `env:"S3_ACCESS_KEY"`
+	S3SecretKey    string        `env:"S3_SECRET_KEY"`
+	// TrustedProxies are the IPs and CIDRs of the reverse proxies whose X-Forwarded-For header gives the client IP,
+	// by default the IP of the connection is used.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
+	// DevMode allows insecure settings for local development.
+	DevMode bool `env:"DEV_MODE"`
+}
+
+// Validate refuses the settings unsafe outside the dev mode.
+func (cfg Config) Validate() error {
+	if cfg.JWTKeysDir == "" && (cfg.SecretKey == DefaultSecretKey || cfg.SecretKey == "") && !cfg.DevMode {
+		return errors.New("the tokens would be signed with the default secret: set JWT_KEYS_DIR or SECRET_KEY, or DEV_MODE for development")
+	}
+	if cfg.JWTKeysReload < 0 {
+		return errors.New("JWT_KEYS_RELOAD cannot be negative")
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE have to be set together")
+	}
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		return errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if cfg.TLSSelfSigned && (!cfg.DevMode || cfg.TLSCertFile == "") {
+		return errors.New("TLS_SELF_SIGNED requires DEV_MODE and the TLS_CERT_FILE and TLS_KEY_FILE to write")
+	}
+	if cfg.BlobStoreDir != "" && cfg.S3Endpoint != "" {
+		return errors.New("BLOB_STORE_DIR and S3_ENDPOINT cannot be set together")
+	}
+	if (cfg.BlobStoreDir != "" || cfg.S3Endpoint != "") && cfg.DataBaseURI == "" {
+		return errors.New("BLOB_STORE_DIR and S3_ENDPOINT require DATABASE_URI")
+	}
+	if cfg.S3Endpoint != "" && cfg.S3Bucket == "" {
+		return errors.New("S3_ENDPOINT requires S3_BUCKET")
+	}
+	return nil
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default secret", Config{SecretKey: DefaultSecretKey}, true},
+		{"empty secret", Config{}, true},
+		{"default secret in dev mode", Config{SecretKey: DefaultSecretKey, DevMode: true}, false},
+		{"own secret", Config{SecretKey: "a long random secret"}, false},
+		{"key directory", Config{SecretKey: DefaultSecretKey, JWTKeysDir: "/etc/gophkeeper/keys"}, false},
+		{"negative key reload", Config{JWTKeysDir: "/etc/gophkeeper/keys", JWTKeysReload: -time.Minute}, true},
+		{"certificate without key", Config{SecretKey: "secret", TLSCertFile: "cert.pem"}, true},
+		{"client CA without certificate", Config{SecretKey: "secret", TLSClientCAFile: "ca.pem"}, true},
+		{"self-signed outside dev mode", Config{SecretKey: "secret", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSSelfSigned: true}, true},
+		{"two blob stores", Config{SecretKey: "secret", DataBaseURI: "postgres://", BlobStoreDir: "/var/lib/gophkeeper", S3Endpoint: "http://localhost:9000", S3Bucket: "blobs"}, true},
+		{"blob store without database", Config{SecretKey: "secret", BlobStoreDir: "/var/lib/gophkeeper"}, true},
+		{"S3 without bucket", Config{SecretKey: "secret", DataBaseURI: "postgres://", S3Endpoint: "http://localhost:9000"}, true},
+		{"S3", Config{SecretKey: "secret", DataBaseURI: "postgres://", S3Endpoint: "http://localhost:9000", S3Bucket: "blobs"}, false},
+		{"self-signed in dev mode", Config{DevMode: true, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSSelfSigned: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
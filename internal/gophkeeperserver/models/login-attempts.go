@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempts are the recent failed logins with one login or from one IP address.
+// PreviousFailure is the time of the failure before the last one, zero if there was none.
+type LoginAttempts struct {
+	Failures        int
+	LastFailure     time.Time
+	PreviousFailure time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RefreshToken is a refresh token stored on the server, only the SHA-256 hash of the token is kept.
+// All the tokens rotated from one login form a family, a reused token revokes the whole family.
+type RefreshToken struct {
+	Hash      string
+	Login     string
+	FamilyID  string
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
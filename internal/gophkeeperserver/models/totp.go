@@ -0,0 +1,9 @@
+package models
+
+// TOTP is the second factor of a user. The secret is pending until the enrollment is confirmed with a code.
+// LastStep is the time step of the last accepted code, a code is never accepted twice.
+type TOTP struct {
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	// Test vectors of RFC 6238 for SHA1, truncated to 6 digits.
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tt.want, got, "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, err := Code(secret, Step(now.Add(-Period)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok, "the previous step is accepted")
+	assert.Equal(t, Step(now)-1, step)
+
+	_, ok = Validate(secret, code, now.Add(2*Period))
+	assert.False(t, ok, "an old code is rejected")
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("GophKeeper", "user@example.com", "ABC")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/GophKeeper:user@example.com?"), uri)
+	assert.Contains(t, uri, "secret=ABC")
+	assert.Contains(t, uri, "issuer=GophKeeper")
+}
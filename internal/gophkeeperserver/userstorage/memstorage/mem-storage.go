@@ -2,23 +2,38 @@ package memstorage
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
 
 	"github.com/google/uuid"
 )
 
 type MemStorage struct {
-	userCash map[uuid.UUID]models.User
-	mu       *sync.RWMutex
+	userCash     map[uuid.UUID]models.User
+	kdfCash      map[string]models.KDFParams
+	tokenCash    map[string]servermodels.RefreshToken
+	sessionCash  map[string]models.Session
+	totpCash     map[string]servermodels.TOTP
+	recoveryCash map[string]map[string]bool
+	mu           *sync.RWMutex
 }
 
 func New() (*MemStorage, error) {
 	return &MemStorage{
-		userCash: make(map[uuid.UUID]models.User),
-		mu:       new(sync.RWMutex),
+		userCash:     make(map[uuid.UUID]models.User),
+		kdfCash:      make(map[string]models.KDFParams),
+		tokenCash:    make(map[string]servermodels.RefreshToken),
+		sessionCash:  make(map[string]models.Session),
+		totpCash:     make(map[string]servermodels.TOTP),
+		recoveryCash: make(map[string]map[string]bool),
+		mu:           new(sync.RWMutex),
 	}, nil
 }
 
@@ -30,9 +45,15 @@ func (MS *MemStorage) Close() error {
 	return nil
 }
 
+// AddUser stores a new user with the Argon2id hash of the password.
 func (MS *MemStorage) AddUser(ctx context.Context, user models.User) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	passwordHash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = passwordHash
 	MS.mu.Lock()
 	defer MS.mu.Unlock()
 	for _, v := range MS.userCash {
@@ -49,13 +70,220 @@ func (MS *MemStorage) AddUser(ctx context.Context, user models.User) error {
 	return nil
 }
 
+// AuthenticationUser checks the login/password pair against the stored hash.
 func (MS *MemStorage) AuthenticationUser(_ context.Context, user models.User) (bool, error) {
 	MS.mu.RLock()
 	defer MS.mu.RUnlock()
 	for _, v := range MS.userCash {
-		if v.Login == user.Login && v.Password == user.Password {
-			return true, nil
+		if v.Login == user.Login {
+			return utils.ComparePassword(v.Password, user.Password)
+		}
+	}
+	// spend the same time as for an existing user
+	_, err := utils.HashPassword(user.Password)
+	return false, err
+}
+
+// PutKDFParams stores the vault key derivation params of the user.
+func (MS *MemStorage) PutKDFParams(_ context.Context, login string, params models.KDFParams) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if _, ok := MS.kdfCash[login]; ok {
+		return constans.ErrorNoUNIQUE
+	}
+	MS.kdfCash[login] = params
+	return nil
+}
+
+// GetKDFParams returns the vault key derivation params of the user.
+func (MS *MemStorage) GetKDFParams(_ context.Context, login string) (models.KDFParams, error) {
+	MS.mu.RLock()
+	defer MS.mu.RUnlock()
+	params, ok := MS.kdfCash[login]
+	if !ok {
+		return models.KDFParams{}, constants.ErrKDFParamsNotFound
+	}
+	return params, nil
+}
+
+// AddRefreshToken stores a new refresh token.
+func (MS *MemStorage) AddRefreshToken(_ context.Context, token servermodels.RefreshToken) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if _, ok := MS.tokenCash[token.Hash]; ok {
+		return constans.ErrorNoUNIQUE
+	}
+	MS.tokenCash[token.Hash] = token
+	return nil
+}
+
+// UseRefreshToken marks the refresh token with the given hash as used and returns it.
+func (MS *MemStorage) UseRefreshToken(_ context.Context, hash string) (servermodels.RefreshToken, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	token, ok := MS.tokenCash[hash]
+	if !ok {
+		return servermodels.RefreshToken{}, constans.ErrTokenNotFound
+	}
+	if token.Used {
+		return token, constans.ErrTokenReused
+	}
+	token.Used = true
+	MS.tokenCash[hash] = token
+	return token, nil
+}
+
+// AddSession stores a new session.
+func (MS *MemStorage) AddSession(_ context.Context, session models.Session) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if _, ok := MS.sessionCash[session.ID]; ok {
+		return constans.ErrorNoUNIQUE
+	}
+	MS.sessionCash[session.ID] = session
+	return nil
+}
+
+// GetSession returns the session with the given ID.
+func (MS *MemStorage) GetSession(_ context.Context, sessionID string) (models.Session, error) {
+	MS.mu.RLock()
+	defer MS.mu.RUnlock()
+	session, ok := MS.sessionCash[sessionID]
+	if !ok {
+		return models.Session{}, constants.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// ListSessions returns the sessions of the user, the most recently seen first.
+func (MS *MemStorage) ListSessions(_ context.Context, login string) ([]models.Session, error) {
+	MS.mu.RLock()
+	defer MS.mu.RUnlock()
+	var sessions []models.Session
+	for _, session := range MS.sessionCash {
+		if session.Login == login {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+// TouchSession updates the last-seen time and the IP address of the session.
+func (MS *MemStorage) TouchSession(_ context.Context, sessionID string, ip string, seen time.Time) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	session, ok := MS.sessionCash[sessionID]
+	if !ok {
+		return constants.ErrSessionNotFound
+	}
+	session.IP = ip
+	session.LastSeen = seen
+	MS.sessionCash[sessionID] = session
+	return nil
+}
+
+// RevokeSession revokes the session of the user and its refresh tokens.
+func (MS *MemStorage) RevokeSession(_ context.Context, login string, sessionID string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	session, ok := MS.sessionCash[sessionID]
+	if !ok || session.Login != login {
+		return constants.ErrSessionNotFound
+	}
+	MS.revokeSession(session)
+	return nil
+}
+
+// RevokeOtherSessions revokes all the sessions of the user except the given one.
+func (MS *MemStorage) RevokeOtherSessions(_ context.Context, login string, keepSessionID string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	for _, session := range MS.sessionCash {
+		if session.Login == login && session.ID != keepSessionID {
+			MS.revokeSession(session)
+		}
+	}
+	return nil
+}
+
+// revokeSession marks the session and its refresh tokens as revoked, the caller holds the lock.
+func (MS *MemStorage) revokeSession(session models.Session) {
+	session.Revoked = true
+	MS.sessionCash[session.ID] = session
+	for hash, token := range MS.tokenCash {
+		if token.FamilyID == session.ID {
+			token.Revoked = true
+			MS.tokenCash[hash] = token
 		}
 	}
-	return false, nil
+}
+
+// SetTOTPSecret stores a pending second factor of the user.
+func (MS *MemStorage) SetTOTPSecret(_ context.Context, login string, secret string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if MS.totpCash[login].Enabled {
+		return constans.ErrorNoUNIQUE
+	}
+	MS.totpCash[login] = servermodels.TOTP{Secret: secret}
+	return nil
+}
+
+// GetTOTP returns the second factor of the user.
+func (MS *MemStorage) GetTOTP(_ context.Context, login string) (servermodels.TOTP, error) {
+	MS.mu.RLock()
+	defer MS.mu.RUnlock()
+	totp, ok := MS.totpCash[login]
+	if !ok {
+		return servermodels.TOTP{}, constans.ErrTOTPNotFound
+	}
+	return totp, nil
+}
+
+// EnableTOTP enables the pending second factor of the user and replaces the recovery codes.
+func (MS *MemStorage) EnableTOTP(_ context.Context, login string, recoveryCodeHashes []string) error {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	totp, ok := MS.totpCash[login]
+	if !ok {
+		return constans.ErrTOTPNotFound
+	}
+	totp.Enabled = true
+	MS.totpCash[login] = totp
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = true
+	}
+	MS.recoveryCash[login] = codes
+	return nil
+}
+
+// UseTOTPStep records the step of an accepted code.
+func (MS *MemStorage) UseTOTPStep(_ context.Context, login string, step int64) (bool, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	totp, ok := MS.totpCash[login]
+	if !ok {
+		return false, constans.ErrTOTPNotFound
+	}
+	if step <= totp.LastStep {
+		return false, nil
+	}
+	totp.LastStep = step
+	MS.totpCash[login] = totp
+	return true, nil
+}
+
+// UseRecoveryCode removes the recovery code of the user.
+func (MS *MemStorage) UseRecoveryCode(_ context.Context, login string, codeHash string) (bool, error) {
+	MS.mu.Lock()
+	defer MS.mu.Unlock()
+	if !MS.recoveryCash[login][codeHash] {
+		return false, nil
+	}
+	delete(MS.recoveryCash[login], codeHash)
+	return true, nil
 }
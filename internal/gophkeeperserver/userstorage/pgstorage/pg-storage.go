@@ -3,10 +3,16 @@ package pgstorage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"log"
+	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/constans"
+	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/models"
+	"yudinsv/gophkeeper/internal/utils"
 
 	_ "github.com/lib/pq"
 )
@@ -48,6 +54,43 @@ func createTables(connect *sql.DB) error {
 		password_user text,
 		create_user timestamp default now()
 	);
+
+	alter table public.users add column if not exists kdf_params jsonb;
+
+	create table if not exists public.refresh_tokens(
+		token_hash text primary key,
+		login_user text not null,
+		family_id text not null,
+		expires_at timestamptz not null,
+		used boolean not null default false,
+		revoked boolean not null default false
+	);
+	create index if not exists refresh_tokens_family_id_idx on public.refresh_tokens (family_id);
+
+	create table if not exists public.sessions(
+		session_id text primary key,
+		login_user text not null,
+		device text not null default '',
+		ip text not null default '',
+		user_agent text not null default '',
+		created_at timestamptz not null default now(),
+		last_seen timestamptz not null default now(),
+		revoked boolean not null default false
+	);
+	create index if not exists sessions_login_user_idx on public.sessions (login_user);
+
+	create table if not exists public.totp(
+		login_user text primary key,
+		secret text not null,
+		enabled boolean not null default false,
+		last_step bigint not null default 0
+	);
+
+	create table if not exists public.recovery_codes(
+		login_user text not null,
+		code_hash text not null,
+		primary key (login_user, code_hash)
+	);
 	
 	create table if not exists public.orders(
 		 number_order text primary key,
@@ -72,10 +115,15 @@ func createTables(connect *sql.DB) error {
 	return nil
 }
 
+// AddUser stores a new user with the Argon2id hash of the password.
 func (PS *PgStorage) AddUser(ctx context.Context, user models.User) error {
+	passwordHash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
 	result, err := PS.connect.ExecContext(ctx,
 		`insert into public.users (login_user, password_user) values ($1, $2) on conflict do nothing`,
-		user.Login, user.Password)
+		user.Login, passwordHash)
 	if err != nil {
 		return err
 	}
@@ -89,15 +137,320 @@ func (PS *PgStorage) AddUser(ctx context.Context, user models.User) error {
 	return nil
 }
 
+// AuthenticationUser checks the login/password pair.
+// A password still stored in plaintext is replaced with its Argon2id hash after a successful check.
 func (PS *PgStorage) AuthenticationUser(ctx context.Context, user models.User) (bool, error) {
-	var done int
-	err := PS.connect.QueryRowContext(ctx, `select count(1) from public.users where login_user=$1 and password_user=$2`,
-		user.Login, user.Password).Scan(&done)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	var stored string
+	err := PS.connect.QueryRowContext(ctx, `select password_user from public.users where login_user=$1`,
+		user.Login).Scan(&stored)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// spend the same time as for an existing user
+			_, err = utils.HashPassword(user.Password)
+			return false, err
+		}
 		return false, err
 	}
-	if done == 0 {
-		return false, nil
+	ok, err := utils.ComparePassword(stored, user.Password)
+	if err != nil || !ok {
+		return false, err
+	}
+	if !utils.IsPasswordHash(stored) {
+		passwordHash, err := utils.HashPassword(user.Password)
+		if err != nil {
+			return false, err
+		}
+		_, err = PS.connect.ExecContext(ctx,
+			`update public.users set password_user=$1 where login_user=$2 and password_user=$3`,
+			passwordHash, user.Login, stored)
+		if err != nil {
+			return false, err
+		}
 	}
 	return true, nil
 }
+
+// PutKDFParams stores the vault key derivation params of the user.
+func (PS *PgStorage) PutKDFParams(ctx context.Context, login string, params models.KDFParams) error {
+	marshal, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	result, err := PS.connect.ExecContext(ctx,
+		`update public.users set kdf_params=$1 where login_user=$2 and kdf_params is null`,
+		marshal, login)
+	if err != nil {
+		return err
+	}
+	row, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if row == 0 {
+		return constans.ErrorNoUNIQUE
+	}
+	return nil
+}
+
+// GetKDFParams returns the vault key derivation params of the user.
+func (PS *PgStorage) GetKDFParams(ctx context.Context, login string) (models.KDFParams, error) {
+	var marshal []byte
+	err := PS.connect.QueryRowContext(ctx,
+		`select kdf_params from public.users where login_user=$1 and kdf_params is not null`,
+		login).Scan(&marshal)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.KDFParams{}, constants.ErrKDFParamsNotFound
+		}
+		return models.KDFParams{}, err
+	}
+	var params models.KDFParams
+	if err = json.Unmarshal(marshal, &params); err != nil {
+		return models.KDFParams{}, err
+	}
+	return params, nil
+}
+
+// AddRefreshToken stores a new refresh token.
+func (PS *PgStorage) AddRefreshToken(ctx context.Context, token servermodels.RefreshToken) error {
+	_, err := PS.connect.ExecContext(ctx,
+		`insert into public.refresh_tokens (token_hash, login_user, family_id, expires_at) values ($1, $2, $3, $4)`,
+		token.Hash, token.Login, token.FamilyID, token.ExpiresAt)
+	return err
+}
+
+// UseRefreshToken marks the refresh token with the given hash as used and returns it.
+// The token is marked by a single conditional update, so two concurrent refreshes cannot both use it.
+func (PS *PgStorage) UseRefreshToken(ctx context.Context, hash string) (servermodels.RefreshToken, error) {
+	token := servermodels.RefreshToken{Hash: hash}
+	err := PS.connect.QueryRowContext(ctx,
+		`update public.refresh_tokens set used = true where token_hash = $1 and not used
+		returning login_user, family_id, expires_at, revoked`,
+		hash).Scan(&token.Login, &token.FamilyID, &token.ExpiresAt, &token.Revoked)
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return servermodels.RefreshToken{}, err
+	}
+	err = PS.connect.QueryRowContext(ctx,
+		`select login_user, family_id, expires_at, revoked from public.refresh_tokens where token_hash = $1`,
+		hash).Scan(&token.Login, &token.FamilyID, &token.ExpiresAt, &token.Revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return servermodels.RefreshToken{}, constans.ErrTokenNotFound
+		}
+		return servermodels.RefreshToken{}, err
+	}
+	token.Used = true
+	return token, constans.ErrTokenReused
+}
+
+// AddSession stores a new session.
+func (PS *PgStorage) AddSession(ctx context.Context, session models.Session) error {
+	_, err := PS.connect.ExecContext(ctx,
+		`insert into public.sessions (session_id, login_user, device, ip, user_agent, created_at, last_seen)
+		values ($1, $2, $3, $4, $5, $6, $7)`,
+		session.ID, session.Login, session.Device, session.IP, session.UserAgent, session.CreatedAt, session.LastSeen)
+	return err
+}
+
+// GetSession returns the session with the given ID.
+func (PS *PgStorage) GetSession(ctx context.Context, sessionID string) (models.Session, error) {
+	session := models.Session{ID: sessionID}
+	err := PS.connect.QueryRowContext(ctx,
+		`select login_user, device, ip, user_agent, created_at, last_seen, revoked from public.sessions where session_id = $1`,
+		sessionID).Scan(&session.Login, &session.Device, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastSeen, &session.Revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, constants.ErrSessionNotFound
+		}
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// ListSessions returns the sessions of the user, the most recently seen first.
+func (PS *PgStorage) ListSessions(ctx context.Context, login string) ([]models.Session, error) {
+	rows, err := PS.connect.QueryContext(ctx,
+		`select session_id, login_user, device, ip, user_agent, created_at, last_seen, revoked
+		from public.sessions where login_user = $1 order by last_seen desc`,
+		login)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err = rows.Scan(&session.ID, &session.Login, &session.Device, &session.IP, &session.UserAgent,
+			&session.CreatedAt, &session.LastSeen, &session.Revoked); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchSession updates the last-seen time and the IP address of the session.
+func (PS *PgStorage) TouchSession(ctx context.Context, sessionID string, ip string, seen time.Time) error {
+	_, err := PS.connect.ExecContext(ctx,
+		`update public.sessions set ip = $2, last_seen = $3 where session_id = $1`,
+		sessionID, ip, seen)
+	return err
+}
+
+// RevokeSession revokes the session of the user and its refresh tokens.
+func (PS *PgStorage) RevokeSession(ctx context.Context, login string, sessionID string) error {
+	tx, err := PS.connect.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	result, err := tx.ExecContext(ctx,
+		`update public.sessions set revoked = true where session_id = $1 and login_user = $2`,
+		sessionID, login)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return constants.ErrSessionNotFound
+	}
+	if _, err = tx.ExecContext(ctx,
+		`update public.refresh_tokens set revoked = true where family_id = $1`, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeOtherSessions revokes all the sessions of the user except the given one.
+func (PS *PgStorage) RevokeOtherSessions(ctx context.Context, login string, keepSessionID string) error {
+	tx, err := PS.connect.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	if _, err = tx.ExecContext(ctx,
+		`update public.sessions set revoked = true where login_user = $1 and session_id <> $2`,
+		login, keepSessionID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx,
+		`update public.refresh_tokens set revoked = true where login_user = $1 and family_id <> $2`,
+		login, keepSessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetTOTPSecret stores a pending second factor of the user.
+func (PS *PgStorage) SetTOTPSecret(ctx context.Context, login string, secret string) error {
+	result, err := PS.connect.ExecContext(ctx,
+		`insert into public.totp (login_user, secret) values ($1, $2)
+		on conflict (login_user) do update set secret = excluded.secret, last_step = 0 where not public.totp.enabled`,
+		login, secret)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return constans.ErrorNoUNIQUE
+	}
+	return nil
+}
+
+// GetTOTP returns the second factor of the user.
+func (PS *PgStorage) GetTOTP(ctx context.Context, login string) (servermodels.TOTP, error) {
+	var totp servermodels.TOTP
+	err := PS.connect.QueryRowContext(ctx,
+		`select secret, enabled, last_step from public.totp where login_user = $1`,
+		login).Scan(&totp.Secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return servermodels.TOTP{}, constans.ErrTOTPNotFound
+		}
+		return servermodels.TOTP{}, err
+	}
+	return totp, nil
+}
+
+// EnableTOTP enables the pending second factor of the user and replaces the recovery codes.
+func (PS *PgStorage) EnableTOTP(ctx context.Context, login string, recoveryCodeHashes []string) error {
+	tx, err := PS.connect.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	result, err := tx.ExecContext(ctx, `update public.totp set enabled = true where login_user = $1`, login)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return constans.ErrTOTPNotFound
+	}
+	if _, err = tx.ExecContext(ctx, `delete from public.recovery_codes where login_user = $1`, login); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err = tx.ExecContext(ctx,
+			`insert into public.recovery_codes (login_user, code_hash) values ($1, $2)`, login, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records the step of an accepted code.
+func (PS *PgStorage) UseTOTPStep(ctx context.Context, login string, step int64) (bool, error) {
+	result, err := PS.connect.ExecContext(ctx,
+		`update public.totp set last_step = $2 where login_user = $1 and last_step < $2`, login, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// UseRecoveryCode removes the recovery code of the user.
+func (PS *PgStorage) UseRecoveryCode(ctx context.Context, login string, codeHash string) (bool, error) {
+	result, err := PS.connect.ExecContext(ctx,
+		`delete from public.recovery_codes where login_user = $1 and code_hash = $2`, login, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// rollback rolls back the transaction unless it has been committed.
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Println(err)
+	}
+}
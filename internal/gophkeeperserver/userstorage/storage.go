@@ -2,6 +2,7 @@ package userstorage
 
 import (
 	"context"
+	"time"
 
 	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/gophkeeperserver/userstorage/memstorage"
@@ -9,11 +10,44 @@ import (
 	"yudinsv/gophkeeper/internal/models"
 )
 
+// UserStorage stores user accounts and the params used to derive their vault keys.
+// PutKDFParams only stores the params once per user: replacing them would make
+// the secrets encrypted with the old key unreadable, so a second call returns constans.ErrorNoUNIQUE.
+//
+// Refresh tokens are stored by hash. UseRefreshToken marks the token as used and returns it;
+// an unknown token returns constans.ErrTokenNotFound and a token used before returns constans.ErrTokenReused
+// together with the token, so the caller can revoke its session.
+//
+// Every login is recorded as a session, the ID of the session is also the family ID of its refresh tokens.
+// RevokeSession and RevokeOtherSessions revoke the refresh tokens of the sessions too.
+// GetSession and RevokeSession return constants.ErrSessionNotFound for an unknown session
+// or a session of another user.
+//
+// SetTOTPSecret stores a pending second factor, replacing a pending one; it returns constans.ErrorNoUNIQUE
+// once the second factor is enabled. GetTOTP returns constans.ErrTOTPNotFound if nothing is stored.
+// EnableTOTP enables the pending second factor and replaces the recovery codes, which are stored by hash.
+// UseTOTPStep records the step of an accepted code and returns false if the step or a later one was used before,
+// UseRecoveryCode removes the code and returns false if it is unknown.
 type UserStorage interface {
 	Ping() error
 	Close() error
 	AddUser(ctx context.Context, user models.User) error
 	AuthenticationUser(ctx context.Context, user models.User) (bool, error)
+	PutKDFParams(ctx context.Context, login string, params models.KDFParams) error
+	GetKDFParams(ctx context.Context, login string) (models.KDFParams, error)
+	AddRefreshToken(ctx context.Context, token servermodels.RefreshToken) error
+	UseRefreshToken(ctx context.Context, hash string) (servermodels.RefreshToken, error)
+	AddSession(ctx context.Context, session models.Session) error
+	GetSession(ctx context.Context, sessionID string) (models.Session, error)
+	ListSessions(ctx context.Context, login string) ([]models.Session, error)
+	TouchSession(ctx context.Context, sessionID string, ip string, seen time.Time) error
+	RevokeSession(ctx context.Context, login string, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, login string, keepSessionID string) error
+	SetTOTPSecret(ctx context.Context, login string, secret string) error
+	GetTOTP(ctx context.Context, login string) (servermodels.TOTP, error)
+	EnableTOTP(ctx context.Context, login string, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, login string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, login string, codeHash string) (bool, error)
 }
 
 func NewUserStorage(cfg servermodels.Config) (UserStorage, error) {
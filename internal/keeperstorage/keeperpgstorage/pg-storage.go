@@ -6,25 +6,42 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"yudinsv/gophkeeper/internal/blobstore"
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
+// valueDigest is the SQL expression of the value digest of a secret, a value kept in the blob store
+// is named by the same digest.
+const valueDigest = `CASE WHEN value_hash <> '' THEN value_hash ELSE encode(sha256(value), 'hex') END`
+
 // PostgresStorage represents a PostgreSQL database connection.
+// The values larger than threshold bytes are kept in the blob store, if it is set,
+// the rows keep only their hash (value_hash) and size (value_size) then.
 type PostgresStorage struct {
-	db *sql.DB
+	db           *sql.DB
+	historyLimit int
+	blobs        blobstore.BlobStore
+	threshold    int
 }
 
-// NewPostgresStorage creates a new instance of PostgresStorage.
-func NewPostgresStorage(uri string) (*PostgresStorage, error) {
+// NewPostgresStorage creates a new instance of PostgresStorage keeping the given number of the last revisions
+// of every secret, a limit below 1 keeps constants.DefaultSecretVersions revisions.
+// The values larger than threshold bytes are kept in blobs, a nil blobs keeps all values in the database.
+func NewPostgresStorage(uri string, versions int, blobs blobstore.BlobStore, threshold int) (*PostgresStorage, error) {
+	if versions < 1 {
+		versions = constants.DefaultSecretVersions
+	}
 	db, err := sql.Open("postgres", uri)
 	if err != nil {
 		return nil, err
 	}
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{db: db, historyLimit: versions, blobs: blobs, threshold: threshold}, nil
 }
 func (s *PostgresStorage) Ping() error {
 	if err := s.db.Ping(); err != nil {
@@ -32,17 +49,63 @@ func (s *PostgresStorage) Ping() error {
 	}
 	// Create the secrets table if it does not already exist
 	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS public.secrets (
-		id SERIAL PRIMARY KEY,
+		id UUID PRIMARY KEY,
 		owner_id TEXT NOT NULL,
 		value BYTEA NOT NULL,
 		secret_type TEXT NOT NULL,
 		description TEXT NOT NULL,
-		is_deleted BOOLEAN NOT NULL
+		is_deleted BOOLEAN NOT NULL,
+		ver TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS secrets_owner_id_idx ON public.secrets (owner_id);
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS conflict_of UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+	CREATE INDEX IF NOT EXISTS secrets_owner_id_seq_idx ON public.secrets (owner_id, seq);
+	CREATE TABLE IF NOT EXISTS public.sync_seq (
+		owner_id TEXT PRIMARY KEY,
+		seq BIGINT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS public.sync_cursors (
+		name TEXT PRIMARY KEY,
+		cursor BIGINT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS public.secret_versions (
+		secret_id UUID NOT NULL,
+		owner_id TEXT NOT NULL,
+		revision BIGINT NOT NULL,
+		value BYTEA NOT NULL,
+		secret_type TEXT NOT NULL,
+		description TEXT NOT NULL,
+		is_deleted BOOLEAN NOT NULL,
 		ver TIMESTAMP NOT NULL,
-	)`)
+		conflict_of UUID NOT NULL,
+		PRIMARY KEY (secret_id, revision)
+	);
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS blob_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+	ALTER TABLE public.secret_versions ADD COLUMN IF NOT EXISTS blob_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS value_hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE public.secrets ADD COLUMN IF NOT EXISTS value_size BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE public.secret_versions ADD COLUMN IF NOT EXISTS value_hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE public.secret_versions ADD COLUMN IF NOT EXISTS value_size BIGINT NOT NULL DEFAULT 0`)
 	if err != nil {
 		return fmt.Errorf("unable to create secrets table: %v", err)
 	}
+	// The secrets stored before the seq column was added got the default sequence 0
+	// and would never be listed by Changes, number them after the current sequence of their owner.
+	_, err = s.db.Exec(`WITH legacy AS (
+			SELECT s.id, COALESCE(q.seq, 0) + ROW_NUMBER() OVER (PARTITION BY s.owner_id ORDER BY s.ver, s.id) AS seq
+			FROM public.secrets s LEFT JOIN public.sync_seq q ON q.owner_id = s.owner_id
+			WHERE s.seq = 0
+		), updated AS (
+			UPDATE public.secrets s SET seq = legacy.seq FROM legacy WHERE s.id = legacy.id
+			RETURNING s.owner_id, s.seq
+		)
+		INSERT INTO public.sync_seq (owner_id, seq) SELECT owner_id, MAX(seq) FROM updated GROUP BY owner_id
+		ON CONFLICT (owner_id) DO UPDATE SET seq = GREATEST(public.sync_seq.seq, EXCLUDED.seq)`)
+	if err != nil {
+		return fmt.Errorf("unable to number the secrets: %v", err)
+	}
 	return nil
 }
 
@@ -53,35 +116,121 @@ func (s *PostgresStorage) Close() error {
 	return nil
 }
 
-// PutSecret adds a new secret to the database.
+// PutSecret adds a new secret to the database or updates an existing one owned by secret.OwnerID.
 func (s *PostgresStorage) PutSecret(ctx context.Context, secret models.Secret) error {
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO public.secrets (id, owner_id, value, secret_type, description, is_deleted, ver)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	value, valueHash, err := blobstore.Offload(ctx, s.blobs, s.threshold, secret.Value)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, secret.OwnerID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO public.secrets (id, owner_id, value, secret_type, description, is_deleted, ver, seq, revision, conflict_of, blob_id, value_hash, value_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT (id) DO UPDATE SET
-			owner_id = EXCLUDED.owner_id,
 			value = EXCLUDED.value,
+			secret_type = EXCLUDED.secret_type,
 			description = EXCLUDED.description,
 			is_deleted = EXCLUDED.is_deleted,
-			ver = EXCLUDED.ver
-	`, secret.ID, secret.OwnerID, secret.Value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver)
-
+			ver = EXCLUDED.ver,
+			seq = EXCLUDED.seq,
+			revision = EXCLUDED.revision,
+			conflict_of = EXCLUDED.conflict_of,
+			blob_id = EXCLUDED.blob_id,
+			value_hash = EXCLUDED.value_hash,
+			value_size = EXCLUDED.value_size
+		WHERE public.secrets.owner_id = EXCLUDED.owner_id
+	`, secret.ID, secret.OwnerID, value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver, seq, secret.Revision, secret.ConflictOf, secret.BlobID, valueHash, len(secret.Value))
 	if err != nil {
 		return err
 	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return constants.ErrSecretNotFound
+	}
 
-	return nil
+	return tx.Commit()
 }
 
-// GetSecret retrieves the first secret found in the store for a given secret ID.
-func (s *PostgresStorage) GetSecret(ctx context.Context, secretID uuid.UUID) (models.Secret, error) {
+// CompareAndPutSecret stores the secret if the stored revision equals baseRevision and returns the new revision.
+// The revision is checked by the upsert itself on the locked row, so concurrent updates of the same base revision
+// cannot both succeed.
+func (s *PostgresStorage) CompareAndPutSecret(ctx context.Context, secret models.Secret, baseRevision int64) (int64, error) {
+	value, valueHash, err := blobstore.Offload(ctx, s.blobs, s.threshold, secret.Value)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, secret.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	revision := baseRevision + 1
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO public.secrets (id, owner_id, value, secret_type, description, is_deleted, ver, seq, revision, conflict_of, blob_id, value_hash, value_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO UPDATE SET
+			value = EXCLUDED.value,
+			secret_type = EXCLUDED.secret_type,
+			description = EXCLUDED.description,
+			is_deleted = EXCLUDED.is_deleted,
+			ver = EXCLUDED.ver,
+			seq = EXCLUDED.seq,
+			revision = EXCLUDED.revision,
+			conflict_of = EXCLUDED.conflict_of,
+			blob_id = EXCLUDED.blob_id,
+			value_hash = EXCLUDED.value_hash,
+			value_size = EXCLUDED.value_size
+		WHERE public.secrets.owner_id = EXCLUDED.owner_id AND public.secrets.revision = $14
+	`, secret.ID, secret.OwnerID, value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver, seq, revision, secret.ConflictOf, secret.BlobID, valueHash, len(secret.Value), baseRevision)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		var ownerID string
+		err = tx.QueryRowContext(ctx, `SELECT owner_id FROM public.secrets WHERE id = $1`, secret.ID).Scan(&ownerID)
+		if err != nil {
+			return 0, err
+		}
+		if ownerID != secret.OwnerID {
+			return 0, constants.ErrSecretNotFound
+		}
+		return 0, constants.ErrRevisionConflict
+	}
+	if err = recordVersion(ctx, tx, secret.ID, s.historyLimit); err != nil {
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+// GetSecret retrieves the secret with the given ID owned by userID.
+func (s *PostgresStorage) GetSecret(ctx context.Context, userID string, secretID uuid.UUID) (models.Secret, error) {
 	var secret models.Secret
+	var valueHash string
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, owner_id, value, secret_type, description, is_deleted, ver
+		SELECT id, owner_id, value, secret_type, description, is_deleted, ver, seq, revision, conflict_of, blob_id, value_hash
 		FROM public.secrets
-		WHERE id = $1 AND is_deleted = false
-	`, secretID).Scan(
+		WHERE id = $1 AND owner_id = $2
+	`, secretID, userID).Scan(
 		&secret.ID,
 		&secret.OwnerID,
 		&secret.Value,
@@ -89,38 +238,186 @@ func (s *PostgresStorage) GetSecret(ctx context.Context, secretID uuid.UUID) (mo
 		&secret.Description,
 		&secret.IsDeleted,
 		&secret.Ver,
+		&secret.Seq,
+		&secret.Revision,
+		&secret.ConflictOf,
+		&secret.BlobID,
+		&valueHash,
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Secret{}, errors.New("secret not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Secret{}, constants.ErrSecretNotFound
 		}
 		return models.Secret{}, err
 	}
-
+	if secret.Value, err = blobstore.Load(ctx, s.blobs, secret.Value, valueHash); err != nil {
+		return models.Secret{}, err
+	}
 	return secret, nil
 }
 
-// DeleteSecret removes the first secret found in the database for a given owner ID.
-func (s *PostgresStorage) DeleteSecret(ctx context.Context, secretID uuid.UUID) error {
-	err := s.db.QueryRowContext(ctx, `
+// DeleteSecret marks the secret with the given ID owned by userID as deleted.
+func (s *PostgresStorage) DeleteSecret(ctx context.Context, userID string, secretID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
 		UPDATE public.secrets
-		set is_deleted = true
-		where id = $1
-	`, secretID).Err()
+		SET is_deleted = true, ver = $3, seq = $4
+		WHERE id = $1 AND owner_id = $2 AND is_deleted = false
+	`, secretID, userID, time.Now(), seq)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return constants.ErrSecretNotFound
+	}
+	return tx.Commit()
+}
 
+// CompareAndDeleteSecret marks the secret as deleted if the stored revision equals baseRevision
+// and returns the revision of the tombstone. The revision is checked by the update itself on the locked row,
+// so a concurrent update of the same base revision cannot succeed as well.
+func (s *PostgresStorage) CompareAndDeleteSecret(ctx context.Context, userID string, secretID uuid.UUID, baseRevision int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errors.New("secret not found")
+		return 0, err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	revision := baseRevision + 1
+	res, err := tx.ExecContext(ctx, `
+		UPDATE public.secrets
+		SET is_deleted = true, ver = $3, seq = $4, revision = $5
+		WHERE id = $1 AND owner_id = $2 AND is_deleted = false AND revision = $6
+	`, secretID, userID, time.Now(), seq, revision, baseRevision)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		var isDeleted bool
+		err = tx.QueryRowContext(ctx, `SELECT is_deleted FROM public.secrets WHERE id = $1 AND owner_id = $2`, secretID, userID).Scan(&isDeleted)
+		if errors.Is(err, sql.ErrNoRows) || err == nil && isDeleted {
+			return 0, constants.ErrSecretNotFound
 		}
-		return err
+		if err != nil {
+			return 0, err
+		}
+		return 0, constants.ErrRevisionConflict
 	}
-	return nil
+	if err = recordVersion(ctx, tx, secretID, s.historyLimit); err != nil {
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+// GetVersions returns the history of the secret with the given ID owned by userID, newest first.
+func (s *PostgresStorage) GetVersions(ctx context.Context, userID string, secretID uuid.UUID) ([]models.Secret, error) {
+	if _, err := s.GetSecret(ctx, userID, secretID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT secret_id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id, value_hash
+		FROM public.secret_versions
+		WHERE secret_id = $1 AND owner_id = $2
+		ORDER BY revision DESC
+	`, secretID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	versions := []models.Secret{}
+	for rows.Next() {
+		var secret models.Secret
+		var valueHash string
+		err := rows.Scan(&secret.ID, &secret.OwnerID, &secret.Revision, &secret.Value, &secret.Type, &secret.Description, &secret.IsDeleted, &secret.Ver, &secret.ConflictOf, &secret.BlobID, &valueHash)
+		if err != nil {
+			return nil, err
+		}
+		if secret.Value, err = blobstore.Load(ctx, s.blobs, secret.Value, valueHash); err != nil {
+			return nil, err
+		}
+		versions = append(versions, secret)
+	}
+	return versions, rows.Err()
 }
 
 func (s *PostgresStorage) SyncSecret(ctx context.Context, userID string) ([]models.LiteSecret, error) {
-	var liteSecrets []models.LiteSecret
-	rows, err := s.db.QueryContext(ctx, "SELECT id, md5(value) , md5(description), is_deleted, ver FROM public.secrets WHERE owner_id = $1", userID)
+	rows, err := s.db.QueryContext(ctx, `SELECT id, `+valueDigest+`, encode(sha256(convert_to(description, 'UTF8')), 'hex'), is_deleted, ver, seq, revision
+		FROM public.secrets WHERE owner_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanLiteSecrets(rows)
+}
+
+// Changes returns at most limit secrets of userID changed after the since cursor ordered by Seq, and the new cursor.
+func (s *PostgresStorage) Changes(ctx context.Context, userID string, since int64, limit int) ([]models.LiteSecret, int64, error) {
+	// LIMIT NULL is no limit
+	var rowLimit sql.NullInt64
+	if limit > 0 {
+		rowLimit = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, `+valueDigest+`, encode(sha256(convert_to(description, 'UTF8')), 'hex'), is_deleted, ver, seq, revision
+		FROM public.secrets WHERE owner_id = $1 AND seq > $2 ORDER BY seq LIMIT $3`, userID, since, rowLimit)
+	if err != nil {
+		return nil, 0, err
+	}
+	liteSecrets, err := scanLiteSecrets(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor := since
+	if len(liteSecrets) != 0 {
+		cursor = liteSecrets[len(liteSecrets)-1].Seq
+	}
+	return liteSecrets, cursor, nil
+}
+
+// GetCursor returns the sync cursor with the given name.
+func (s *PostgresStorage) GetCursor(ctx context.Context, name string) (int64, error) {
+	var cursor int64
+	err := s.db.QueryRowContext(ctx, `SELECT cursor FROM public.sync_cursors WHERE name = $1`, name).Scan(&cursor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return cursor, nil
+}
+
+// PutCursor stores the sync cursor with the given name.
+func (s *PostgresStorage) PutCursor(ctx context.Context, name string, cursor int64) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO public.sync_cursors (name, cursor) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET cursor = EXCLUDED.cursor`, name, cursor)
+	return err
+}
+
+// Referenced returns the hashes of the values kept in the blob store by the secrets and their history.
+func (s *PostgresStorage) Referenced(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT value_hash FROM public.secrets WHERE value_hash <> ''
+		UNION SELECT value_hash FROM public.secret_versions WHERE value_hash <> ''`)
 	if err != nil {
 		return nil, err
 	}
@@ -130,12 +427,78 @@ func (s *PostgresStorage) SyncSecret(ctx context.Context, userID string) ([]mode
 			log.Println(err)
 		}
 	}(rows)
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		referenced[hash] = true
+	}
+	return referenced, rows.Err()
+}
+
+// recordVersion copies the current state of the secret into its history within the transaction
+// and drops the revisions over the limit.
+func recordVersion(ctx context.Context, tx *sql.Tx, secretID uuid.UUID, limit int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO public.secret_versions (secret_id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id, value_hash, value_size)
+		SELECT id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id, value_hash, value_size
+		FROM public.secrets WHERE id = $1
+		ON CONFLICT (secret_id, revision) DO UPDATE SET
+			value = EXCLUDED.value,
+			secret_type = EXCLUDED.secret_type,
+			description = EXCLUDED.description,
+			is_deleted = EXCLUDED.is_deleted,
+			ver = EXCLUDED.ver,
+			conflict_of = EXCLUDED.conflict_of,
+			blob_id = EXCLUDED.blob_id,
+			value_hash = EXCLUDED.value_hash,
+			value_size = EXCLUDED.value_size
+	`, secretID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM public.secret_versions WHERE secret_id = $1 AND revision NOT IN
+			(SELECT revision FROM public.secret_versions WHERE secret_id = $1 ORDER BY revision DESC LIMIT $2)
+	`, secretID, limit)
+	return err
+}
+
+// nextSeq increments the change sequence of the owner within the transaction and returns the new value.
+// The row lock on the counter serializes the changes of one owner, so the sequence follows the commit order.
+func nextSeq(ctx context.Context, tx *sql.Tx, ownerID string) (int64, error) {
+	var seq int64
+	err := tx.QueryRowContext(ctx, `INSERT INTO public.sync_seq (owner_id, seq) VALUES ($1, 1)
+		ON CONFLICT (owner_id) DO UPDATE SET seq = public.sync_seq.seq + 1
+		RETURNING seq`, ownerID).Scan(&seq)
+	return seq, err
+}
+
+// scanLiteSecrets reads the rows of id, value digest, description digest, is_deleted, ver, seq and revision into models.LiteSecret.
+func scanLiteSecrets(rows *sql.Rows) ([]models.LiteSecret, error) {
+	var err error
+	defer func(rows *sql.Rows) {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	var liteSecrets []models.LiteSecret
 	for rows.Next() {
 		var liteSecret models.LiteSecret
-		if err := rows.Scan(&liteSecret.ID, &liteSecret.ValueHash, &liteSecret.DescriptionHash, &liteSecret.IsDeleted, &liteSecret.Ver); err != nil {
+		if err := rows.Scan(&liteSecret.ID, &liteSecret.ValueHash, &liteSecret.DescriptionHash, &liteSecret.IsDeleted, &liteSecret.Ver, &liteSecret.Seq, &liteSecret.Revision); err != nil {
 			return nil, err
 		}
 		liteSecrets = append(liteSecrets, liteSecret)
 	}
 	return liteSecrets, rows.Err()
 }
+
+// rollback rolls back the transaction unless it has been committed.
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Println(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"time"
 
 	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
@@ -14,10 +15,16 @@ import (
 )
 
 type SqliteStorage struct {
-	db *sql.DB
+	db           *sql.DB
+	historyLimit int
 }
 
-func NewSqliteStorage(dbPath string) (*SqliteStorage, error) {
+// NewSqliteStorage opens the database keeping the given number of the last revisions of every secret,
+// a limit below 1 keeps constants.DefaultSecretVersions revisions.
+func NewSqliteStorage(dbPath string, versions int) (*SqliteStorage, error) {
+	if versions < 1 {
+		versions = constants.DefaultSecretVersions
+	}
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, err
@@ -31,11 +38,124 @@ func NewSqliteStorage(dbPath string) (*SqliteStorage, error) {
 			is_deleted INTEGER DEFAULT 0,
 			ver TIMESTAMP ,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS sync_seq (
+			owner_id TEXT PRIMARY KEY,
+			seq INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS sync_cursors (
+			name TEXT PRIMARY KEY,
+			cursor INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS secret_versions (
+			secret_id UUID NOT NULL,
+			owner_id TEXT NOT NULL,
+			revision INTEGER NOT NULL,
+			value BLOB,
+			secret_type TEXT,
+			description TEXT,
+			is_deleted INTEGER,
+			ver TIMESTAMP,
+			conflict_of TEXT NOT NULL,
+			PRIMARY KEY (secret_id, revision)
 		)`)
 	if err != nil {
 		return nil, err
 	}
-	return &SqliteStorage{db: db}, nil
+	if err = addColumn(db, "secrets", "seq", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+	if err = addColumn(db, "secrets", "revision", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+	if err = addColumn(db, "secrets", "conflict_of", "TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000'"); err != nil {
+		return nil, err
+	}
+	if err = addColumn(db, "secrets", "blob_id", "TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000'"); err != nil {
+		return nil, err
+	}
+	if err = addColumn(db, "secret_versions", "blob_id", "TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000'"); err != nil {
+		return nil, err
+	}
+	if err = backfillSeq(context.Background(), db); err != nil {
+		return nil, err
+	}
+	return &SqliteStorage{db: db, historyLimit: versions}, nil
+}
+
+// backfillSeq assigns a change sequence to the secrets stored by an older version of the client.
+// They got the default sequence 0 when the column was added and would never be listed by Changes.
+func backfillSeq(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	rows, err := tx.QueryContext(ctx, `SELECT id, owner_id FROM secrets WHERE seq = 0 AND owner_id IS NOT NULL ORDER BY ver`)
+	if err != nil {
+		return err
+	}
+	type legacySecret struct {
+		id      string
+		ownerID string
+	}
+	var legacy []legacySecret
+	for rows.Next() {
+		var secret legacySecret
+		if err = rows.Scan(&secret.id, &secret.ownerID); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy = append(legacy, secret)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, secret := range legacy {
+		seq, err := nextSeq(ctx, tx, secret.ownerID)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, `UPDATE secrets SET seq = ? WHERE id = ?`, seq, secret.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumn adds the column to a table created by an older version of the client.
+func addColumn(db *sql.DB, table string, column string, definition string) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil || count != 0 {
+		return err
+	}
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+// recordVersion copies the current state of the secret into its history within the transaction
+// and drops the revisions over the limit.
+func recordVersion(ctx context.Context, tx *sql.Tx, secretID uuid.UUID, limit int) error {
+	_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO secret_versions
+		(secret_id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id)
+		SELECT id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id FROM secrets WHERE id = ?`, secretID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM secret_versions WHERE secret_id = ? AND revision NOT IN
+		(SELECT revision FROM secret_versions WHERE secret_id = ? ORDER BY revision DESC LIMIT ?)`, secretID, secretID, limit)
+	return err
+}
+
+// nextSeq increments the change sequence of the owner within the transaction and returns the new value.
+func nextSeq(ctx context.Context, tx *sql.Tx, ownerID string) (int64, error) {
+	var seq int64
+	err := tx.QueryRowContext(ctx, `INSERT INTO sync_seq (owner_id, seq) VALUES (?, 1)
+		ON CONFLICT (owner_id) DO UPDATE SET seq = seq + 1
+		RETURNING seq`, ownerID).Scan(&seq)
+	return seq, err
 }
 
 func (s *SqliteStorage) Ping() error {
@@ -46,26 +166,104 @@ func (s *SqliteStorage) Close() error {
 	return s.db.Close()
 }
 
+// PutSecret adds a new secret or updates an existing one owned by secret.OwnerID.
 func (s *SqliteStorage) PutSecret(ctx context.Context, secret models.Secret) error {
-	_, err := s.db.ExecContext(ctx, `INSERT INTO secrets (id, owner_id, value, secret_type, description, is_deleted, ver)
-		VALUES (?, ?,?, ?, ?, ?, ?)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, secret.OwnerID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO secrets (id, owner_id, value, secret_type, description, is_deleted, ver, seq, revision, conflict_of, blob_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (id) DO UPDATE SET
-			owner_id = ?,
 			value = ?,
+			secret_type = ?,
 			description = ?,
 			is_deleted = ?,
-			ver = ?`,
-		secret.ID, secret.OwnerID, secret.Value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver,
-		secret.OwnerID, secret.Value, secret.Description, secret.IsDeleted, secret.Ver,
+			ver = ?,
+			seq = ?,
+			revision = ?,
+			conflict_of = ?,
+			blob_id = ?
+		WHERE secrets.owner_id = excluded.owner_id`,
+		secret.ID, secret.OwnerID, secret.Value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver, seq, secret.Revision, secret.ConflictOf, secret.BlobID,
+		secret.Value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver, seq, secret.Revision, secret.ConflictOf, secret.BlobID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return constants.ErrSecretNotFound
+	}
+	return tx.Commit()
 }
 
-// GetSecret retrieves the first secret found in the store for a given secret ID.
-func (s *SqliteStorage) GetSecret(ctx context.Context, secretID uuid.UUID) (models.Secret, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT id, value, secret_type, description, owner_id, is_deleted, ver FROM secrets WHERE id = ? ORDER BY created_at DESC`, secretID)
+// CompareAndPutSecret stores the secret if the stored revision equals baseRevision and returns the new revision.
+// The revision is checked by the upsert itself, so concurrent updates of the same base revision cannot both succeed.
+func (s *SqliteStorage) CompareAndPutSecret(ctx context.Context, secret models.Secret, baseRevision int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, secret.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	revision := baseRevision + 1
+	res, err := tx.ExecContext(ctx, `INSERT INTO secrets (id, owner_id, value, secret_type, description, is_deleted, ver, seq, revision, conflict_of, blob_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			value = excluded.value,
+			secret_type = excluded.secret_type,
+			description = excluded.description,
+			is_deleted = excluded.is_deleted,
+			ver = excluded.ver,
+			seq = excluded.seq,
+			revision = excluded.revision,
+			conflict_of = excluded.conflict_of,
+			blob_id = excluded.blob_id
+		WHERE secrets.owner_id = excluded.owner_id AND secrets.revision = ?`,
+		secret.ID, secret.OwnerID, secret.Value, secret.Type, secret.Description, secret.IsDeleted, secret.Ver, seq, revision, secret.ConflictOf, secret.BlobID,
+		baseRevision,
+	)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		var ownerID string
+		err = tx.QueryRowContext(ctx, `SELECT owner_id FROM secrets WHERE id = ?`, secret.ID).Scan(&ownerID)
+		if err != nil {
+			return 0, err
+		}
+		if ownerID != secret.OwnerID {
+			return 0, constants.ErrSecretNotFound
+		}
+		return 0, constants.ErrRevisionConflict
+	}
+	if err = recordVersion(ctx, tx, secret.ID, s.historyLimit); err != nil {
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+// GetSecret retrieves the secret with the given ID owned by userID.
+func (s *SqliteStorage) GetSecret(ctx context.Context, userID string, secretID uuid.UUID) (models.Secret, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, value, secret_type, description, owner_id, is_deleted, ver, seq, revision, conflict_of, blob_id FROM secrets WHERE id = ? AND owner_id = ? ORDER BY created_at DESC`, secretID, userID)
 	var secret models.Secret
-	err := row.Scan(&secret.ID, &secret.Value, &secret.Type, &secret.Description, &secret.OwnerID, &secret.IsDeleted, &secret.Ver)
+	err := row.Scan(&secret.ID, &secret.Value, &secret.Type, &secret.Description, &secret.OwnerID, &secret.IsDeleted, &secret.Ver, &secret.Seq, &secret.Revision, &secret.ConflictOf, &secret.BlobID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Secret{}, constants.ErrSecretNotFound
@@ -75,8 +273,19 @@ func (s *SqliteStorage) GetSecret(ctx context.Context, secretID uuid.UUID) (mode
 	return secret, nil
 }
 
-func (s *SqliteStorage) DeleteSecret(ctx context.Context, secretID uuid.UUID) error {
-	res, err := s.db.ExecContext(ctx, `UPDATE secrets SET is_deleted = 1 WHERE id = ? AND is_deleted = 0`, secretID)
+// DeleteSecret marks the secret with the given ID owned by userID as deleted.
+func (s *SqliteStorage) DeleteSecret(ctx context.Context, userID string, secretID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE secrets SET is_deleted = 1, ver = ?, seq = ? WHERE id = ? AND owner_id = ? AND is_deleted = 0`,
+		time.Now(), seq, secretID, userID)
 	if err != nil {
 		return err
 	}
@@ -87,14 +296,126 @@ func (s *SqliteStorage) DeleteSecret(ctx context.Context, secretID uuid.UUID) er
 	if rowsAffected == 0 {
 		return constants.ErrSecretNotFound
 	}
-	return nil
+	return tx.Commit()
+}
+
+// CompareAndDeleteSecret marks the secret as deleted if the stored revision equals baseRevision
+// and returns the revision of the tombstone. The revision is checked by the update itself.
+func (s *SqliteStorage) CompareAndDeleteSecret(ctx context.Context, userID string, secretID uuid.UUID, baseRevision int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(tx)
+	seq, err := nextSeq(ctx, tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	revision := baseRevision + 1
+	res, err := tx.ExecContext(ctx, `UPDATE secrets SET is_deleted = 1, ver = ?, seq = ?, revision = ? WHERE id = ? AND owner_id = ? AND is_deleted = 0 AND revision = ?`,
+		time.Now(), seq, revision, secretID, userID, baseRevision)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		var isDeleted bool
+		err = tx.QueryRowContext(ctx, `SELECT is_deleted FROM secrets WHERE id = ? AND owner_id = ?`, secretID, userID).Scan(&isDeleted)
+		if err == sql.ErrNoRows || err == nil && isDeleted {
+			return 0, constants.ErrSecretNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return 0, constants.ErrRevisionConflict
+	}
+	if err = recordVersion(ctx, tx, secretID, s.historyLimit); err != nil {
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+// GetVersions returns the history of the secret with the given ID owned by userID, newest first.
+func (s *SqliteStorage) GetVersions(ctx context.Context, userID string, secretID uuid.UUID) ([]models.Secret, error) {
+	if _, err := s.GetSecret(ctx, userID, secretID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT secret_id, owner_id, revision, value, secret_type, description, is_deleted, ver, conflict_of, blob_id
+		FROM secret_versions WHERE secret_id = ? AND owner_id = ? ORDER BY revision DESC`, secretID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	versions := []models.Secret{}
+	for rows.Next() {
+		var secret models.Secret
+		err := rows.Scan(&secret.ID, &secret.OwnerID, &secret.Revision, &secret.Value, &secret.Type, &secret.Description, &secret.IsDeleted, &secret.Ver, &secret.ConflictOf, &secret.BlobID)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, secret)
+	}
+	return versions, rows.Err()
 }
 
 func (s *SqliteStorage) SyncSecret(ctx context.Context, userID string) ([]models.LiteSecret, error) {
-	rows, err := s.db.QueryContext(ctx, `SELECT id, value, description, is_deleted, ver FROM secrets WHERE owner_id = ? ORDER BY created_at DESC`, userID)
+	rows, err := s.db.QueryContext(ctx, `SELECT id, value, description, is_deleted, ver, seq, revision FROM secrets WHERE owner_id = ? ORDER BY created_at DESC`, userID)
 	if err != nil {
 		return nil, err
 	}
+	return scanLiteSecrets(rows)
+}
+
+// Changes returns at most limit secrets of userID changed after the since cursor ordered by Seq, and the new cursor.
+func (s *SqliteStorage) Changes(ctx context.Context, userID string, since int64, limit int) ([]models.LiteSecret, int64, error) {
+	// A negative LIMIT is no limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, value, description, is_deleted, ver, seq, revision FROM secrets WHERE owner_id = ? AND seq > ? ORDER BY seq LIMIT ?`, userID, since, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	liteSecrets, err := scanLiteSecrets(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor := since
+	if len(liteSecrets) != 0 {
+		cursor = liteSecrets[len(liteSecrets)-1].Seq
+	}
+	return liteSecrets, cursor, nil
+}
+
+// GetCursor returns the sync cursor with the given name.
+func (s *SqliteStorage) GetCursor(ctx context.Context, name string) (int64, error) {
+	var cursor int64
+	err := s.db.QueryRowContext(ctx, `SELECT cursor FROM sync_cursors WHERE name = ?`, name).Scan(&cursor)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return cursor, nil
+}
+
+// PutCursor stores the sync cursor with the given name.
+func (s *SqliteStorage) PutCursor(ctx context.Context, name string, cursor int64) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO sync_cursors (name, cursor) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET cursor = excluded.cursor`, name, cursor)
+	return err
+}
+
+// scanLiteSecrets reads the rows of id, value, description, is_deleted, ver, seq and revision
+// into models.LiteSecret with the digests of the value and the description.
+func scanLiteSecrets(rows *sql.Rows) ([]models.LiteSecret, error) {
+	var err error
 	defer func(rows *sql.Rows) {
 		err = rows.Close()
 		if err != nil {
@@ -105,14 +426,21 @@ func (s *SqliteStorage) SyncSecret(ctx context.Context, userID string) ([]models
 	var liteSecrets []models.LiteSecret
 	for rows.Next() {
 		var liteSecret models.LiteSecret
-		err := rows.Scan(&liteSecret.ID, &liteSecret.ValueHash, &liteSecret.DescriptionHash, &liteSecret.IsDeleted, &liteSecret.Ver)
+		err := rows.Scan(&liteSecret.ID, &liteSecret.ValueHash, &liteSecret.DescriptionHash, &liteSecret.IsDeleted, &liteSecret.Ver, &liteSecret.Seq, &liteSecret.Revision)
 		if err != nil {
 			return nil, err
 		}
-		liteSecret.ValueHash = utils.GetMD5Hash([]byte(liteSecret.ValueHash))
-		liteSecret.DescriptionHash = utils.GetMD5Hash([]byte(liteSecret.DescriptionHash))
+		liteSecret.ValueHash = utils.GetSHA256Hash([]byte(liteSecret.ValueHash))
+		liteSecret.DescriptionHash = utils.GetSHA256Hash([]byte(liteSecret.DescriptionHash))
 		liteSecrets = append(liteSecrets, liteSecret)
 	}
 
 	return liteSecrets, rows.Err()
 }
+
+// rollback rolls back the transaction unless it has been committed.
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,74 @@
+package keepsqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yudinsv/gophkeeper/internal/constants"
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSqliteStorage_BackfillsSeq(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "keeper.db")
+	// A database written by the client before the sync sequence was added
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE secrets (
+			id UUID PRIMARY KEY,
+			value BLOB,
+			secret_type TEXT,
+			description TEXT,
+			owner_id TEXT,
+			is_deleted INTEGER DEFAULT 0,
+			ver TIMESTAMP ,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+	first, second := uuid.New(), uuid.New()
+	for i, id := range []uuid.UUID{first, second} {
+		_, err = db.Exec(`INSERT INTO secrets (id, value, secret_type, description, owner_id, is_deleted, ver) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, []byte("value"), "text", "description", "client", 0, time.Now().Add(time.Duration(i-2)*time.Hour))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	s, err := NewSqliteStorage(dbPath, constants.DefaultSecretVersions)
+	require.NoError(t, err)
+	changes, cursor, err := s.Changes(ctx, "client", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 2, "the secrets stored before the migration are listed")
+	assert.Equal(t, first, changes[0].ID)
+	assert.Equal(t, second, changes[1].ID)
+	assert.Equal(t, int64(2), cursor)
+
+	// A limited page ends at the last secret returned
+	changes, cursor, err = s.Changes(ctx, "client", 0, 1)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, first, changes[0].ID)
+	assert.Equal(t, int64(1), cursor)
+
+	// The new changes are numbered after the backfilled ones
+	require.NoError(t, s.PutSecret(ctx, models.Secret{ID: uuid.New(), OwnerID: "client", Value: []byte("value"), Type: "text"}))
+	changes, cursor, err = s.Changes(ctx, "client", 2, 0)
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, int64(3), cursor)
+	require.NoError(t, s.Close())
+
+	// Reopening the database numbers nothing again
+	s, err = NewSqliteStorage(dbPath, constants.DefaultSecretVersions)
+	require.NoError(t, err)
+	defer s.Close()
+	changes, _, err = s.Changes(ctx, "client", 3, 0)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -3,7 +3,9 @@ package keepermemstorage
 import (
 	"context"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
@@ -13,13 +15,26 @@ import (
 )
 
 type MemoryStorage struct {
-	mu      sync.RWMutex
-	secrets map[uuid.UUID]models.Secret
+	mu           sync.RWMutex
+	secrets      map[uuid.UUID]models.Secret
+	seqs         map[string]int64
+	cursors      map[string]int64
+	history      map[uuid.UUID][]models.Secret
+	historyLimit int
 }
 
-func NewMemoryStorage() *MemoryStorage {
+// NewMemoryStorage creates a storage keeping the given number of the last revisions of every secret,
+// a limit below 1 keeps constants.DefaultSecretVersions revisions.
+func NewMemoryStorage(versions int) *MemoryStorage {
+	if versions < 1 {
+		versions = constants.DefaultSecretVersions
+	}
 	return &MemoryStorage{
-		secrets: make(map[uuid.UUID]models.Secret),
+		secrets:      make(map[uuid.UUID]models.Secret),
+		seqs:         make(map[string]int64),
+		cursors:      make(map[string]int64),
+		history:      make(map[uuid.UUID][]models.Secret),
+		historyLimit: versions,
 	}
 }
 func (s *MemoryStorage) Ping() error {
@@ -36,43 +51,121 @@ func (s *MemoryStorage) PutSecret(_ context.Context, secret models.Secret) error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A secret owned by another user must not be overwritten
+	if stored, ok := s.secrets[secret.ID]; ok && stored.OwnerID != secret.OwnerID {
+		return constants.ErrSecretNotFound
+	}
+
 	// Add the secret to the map
+	s.seqs[secret.OwnerID]++
+	secret.Seq = s.seqs[secret.OwnerID]
 	s.secrets[secret.ID] = secret
 
 	return nil
 }
 
-// GetSecret retrieves the first secret found in the store for a given secret ID.
-func (s *MemoryStorage) GetSecret(_ context.Context, secretID uuid.UUID) (models.Secret, error) {
+// CompareAndPutSecret stores the secret if the stored revision equals baseRevision and returns the new revision.
+func (s *MemoryStorage) CompareAndPutSecret(_ context.Context, secret models.Secret, baseRevision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.secrets[secret.ID]
+	if ok && stored.OwnerID != secret.OwnerID {
+		return 0, constants.ErrSecretNotFound
+	}
+	if ok && stored.Revision != baseRevision {
+		return 0, constants.ErrRevisionConflict
+	}
+
+	s.seqs[secret.OwnerID]++
+	secret.Seq = s.seqs[secret.OwnerID]
+	secret.Revision = baseRevision + 1
+	s.secrets[secret.ID] = secret
+	s.recordVersion(secret)
+
+	return secret.Revision, nil
+}
+
+// GetSecret retrieves the secret with the given ID owned by userID.
+func (s *MemoryStorage) GetSecret(_ context.Context, userID string, secretID uuid.UUID) (models.Secret, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Find the secret with the specified owner ID
-	for _, secret := range s.secrets {
-		if secret.ID == secretID {
-			return secret, nil
-		}
+	secret, ok := s.secrets[secretID]
+	if !ok || secret.OwnerID != userID {
+		return models.Secret{}, constants.ErrSecretNotFound
 	}
 
-	return models.Secret{}, constants.ErrSecretNotFound
+	return secret, nil
 }
 
-// DeleteSecret removes the first secret found in the store for a given owner ID.
-func (s *MemoryStorage) DeleteSecret(_ context.Context, secretID uuid.UUID) error {
+// DeleteSecret marks the secret with the given ID owned by userID as deleted.
+func (s *MemoryStorage) DeleteSecret(_ context.Context, userID string, secretID uuid.UUID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Find the secret with the specified owner ID
-	for id, secret := range s.secrets {
-		if secret.ID == secretID && !secret.IsDeleted {
-			// Delete the secret from the map
-			secret.IsDeleted = true
-			s.secrets[id] = secret
-			return nil
-		}
+	secret, ok := s.secrets[secretID]
+	if !ok || secret.OwnerID != userID || secret.IsDeleted {
+		return constants.ErrSecretNotFound
 	}
+	secret.IsDeleted = true
+	secret.Ver = time.Now()
+	s.seqs[userID]++
+	secret.Seq = s.seqs[userID]
+	s.secrets[secretID] = secret
 
-	return constants.ErrSecretNotFound
+	return nil
+}
+
+// CompareAndDeleteSecret marks the secret as deleted if the stored revision equals baseRevision
+// and returns the revision of the tombstone.
+func (s *MemoryStorage) CompareAndDeleteSecret(_ context.Context, userID string, secretID uuid.UUID, baseRevision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[secretID]
+	if !ok || secret.OwnerID != userID || secret.IsDeleted {
+		return 0, constants.ErrSecretNotFound
+	}
+	if secret.Revision != baseRevision {
+		return 0, constants.ErrRevisionConflict
+	}
+	secret.IsDeleted = true
+	secret.Ver = time.Now()
+	secret.Revision = baseRevision + 1
+	s.seqs[userID]++
+	secret.Seq = s.seqs[userID]
+	s.secrets[secretID] = secret
+	s.recordVersion(secret)
+
+	return secret.Revision, nil
+}
+
+// GetVersions returns the history of the secret with the given ID owned by userID, newest first.
+func (s *MemoryStorage) GetVersions(_ context.Context, userID string, secretID uuid.UUID) ([]models.Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secret, ok := s.secrets[secretID]
+	if !ok || secret.OwnerID != userID {
+		return nil, constants.ErrSecretNotFound
+	}
+	history := s.history[secretID]
+	versions := make([]models.Secret, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		versions = append(versions, history[i])
+	}
+	return versions, nil
+}
+
+// recordVersion appends the secret to its history and drops the revisions over the limit.
+// The caller must hold the lock.
+func (s *MemoryStorage) recordVersion(secret models.Secret) {
+	history := append(s.history[secret.ID], secret)
+	if len(history) > s.historyLimit {
+		history = append([]models.Secret(nil), history[len(history)-s.historyLimit:]...)
+	}
+	s.history[secret.ID] = history
 }
 
 func (s *MemoryStorage) SyncSecret(_ context.Context, userID string) ([]models.LiteSecret, error) {
@@ -84,13 +177,60 @@ func (s *MemoryStorage) SyncSecret(_ context.Context, userID string) ([]models.L
 
 			liteSecrets = append(liteSecrets, models.LiteSecret{
 				ID:              secret.ID,
-				ValueHash:       utils.GetMD5Hash(secret.Value),
-				DescriptionHash: utils.GetMD5Hash([]byte(secret.Description)),
+				ValueHash:       utils.GetSHA256Hash(secret.Value),
+				DescriptionHash: utils.GetSHA256Hash([]byte(secret.Description)),
 				IsDeleted:       secret.IsDeleted,
 				Ver:             secret.Ver,
+				Seq:             secret.Seq,
+				Revision:        secret.Revision,
 			})
 		}
 	}
 
 	return liteSecrets, nil
 }
+
+// Changes returns at most limit secrets of userID changed after the since cursor ordered by Seq, and the new cursor.
+func (s *MemoryStorage) Changes(_ context.Context, userID string, since int64, limit int) ([]models.LiteSecret, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var liteSecrets []models.LiteSecret
+	for _, secret := range s.secrets {
+		if secret.OwnerID != userID || secret.Seq <= since {
+			continue
+		}
+		liteSecrets = append(liteSecrets, models.LiteSecret{
+			ID:              secret.ID,
+			ValueHash:       utils.GetSHA256Hash(secret.Value),
+			DescriptionHash: utils.GetSHA256Hash([]byte(secret.Description)),
+			IsDeleted:       secret.IsDeleted,
+			Ver:             secret.Ver,
+			Seq:             secret.Seq,
+			Revision:        secret.Revision,
+		})
+	}
+	sort.Slice(liteSecrets, func(i, j int) bool { return liteSecrets[i].Seq < liteSecrets[j].Seq })
+	if limit > 0 && len(liteSecrets) > limit {
+		liteSecrets = liteSecrets[:limit]
+	}
+	cursor := since
+	if len(liteSecrets) != 0 {
+		cursor = liteSecrets[len(liteSecrets)-1].Seq
+	}
+	return liteSecrets, cursor, nil
+}
+
+// GetCursor returns the sync cursor with the given name.
+func (s *MemoryStorage) GetCursor(_ context.Context, name string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cursors[name], nil
+}
+
+// PutCursor stores the sync cursor with the given name.
+func (s *MemoryStorage) PutCursor(_ context.Context, name string, cursor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[name] = cursor
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"yudinsv/gophkeeper/internal/constants"
 	"yudinsv/gophkeeper/internal/models"
 	"yudinsv/gophkeeper/internal/utils"
 
@@ -13,7 +14,7 @@ import (
 )
 
 func TestMemoryStorage_PutSecret(t *testing.T) {
-	s := NewMemoryStorage()
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
 
 	err := s.PutSecret(context.Background(), models.Secret{
 		ID:          uuid.New(),
@@ -28,7 +29,7 @@ func TestMemoryStorage_PutSecret(t *testing.T) {
 }
 
 func TestMemoryStorage_GetSecret(t *testing.T) {
-	s := NewMemoryStorage()
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
 	key := uuid.New()
 	err := s.PutSecret(context.Background(), models.Secret{
 		ID:          key,
@@ -41,7 +42,7 @@ func TestMemoryStorage_GetSecret(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	secret, err := s.GetSecret(context.Background(), key)
+	secret, err := s.GetSecret(context.Background(), "user1", key)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "user1", secret.OwnerID)
@@ -52,7 +53,7 @@ func TestMemoryStorage_GetSecret(t *testing.T) {
 }
 
 func TestMemoryStorage_DeleteSecret(t *testing.T) {
-	s := NewMemoryStorage()
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
 	key := uuid.New()
 	err := s.PutSecret(context.Background(), models.Secret{
 		ID:          key,
@@ -65,13 +66,52 @@ func TestMemoryStorage_DeleteSecret(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	err = s.DeleteSecret(context.Background(), key)
+	err = s.DeleteSecret(context.Background(), "user1", key)
 	assert.NoError(t, err)
+
+	secret, err := s.GetSecret(context.Background(), "user1", key)
+	assert.NoError(t, err)
+	assert.True(t, secret.IsDeleted)
+	assert.False(t, secret.Ver.IsZero())
+
+	err = s.DeleteSecret(context.Background(), "user1", key)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+}
+
+func TestMemoryStorage_OtherOwner(t *testing.T) {
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
+	key := uuid.New()
+	err := s.PutSecret(context.Background(), models.Secret{
+		ID:          key,
+		OwnerID:     "user1",
+		Value:       []byte("secret1"),
+		Type:        "type1",
+		Description: "description1",
+	})
+	assert.NoError(t, err)
+
+	_, err = s.GetSecret(context.Background(), "user2", key)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+
+	err = s.PutSecret(context.Background(), models.Secret{
+		ID:      key,
+		OwnerID: "user2",
+		Value:   []byte("stolen"),
+	})
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+
+	err = s.DeleteSecret(context.Background(), "user2", key)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+
+	secret, err := s.GetSecret(context.Background(), "user1", key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret1"), secret.Value)
+	assert.False(t, secret.IsDeleted)
 }
 
 func TestSyncSecret(t *testing.T) {
 	// Create a new MemoryStorage instance
-	s := NewMemoryStorage()
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
 
 	// Add some secrets to the storage
 	secret1 := models.Secret{ID: uuid.New(), OwnerID: "user1", Value: []byte("secret1"), Type: "type1", Description: "desc1", IsDeleted: false, Ver: time.Now()}
@@ -92,11 +132,11 @@ func TestSyncSecret(t *testing.T) {
 		want   []models.LiteSecret
 	}{
 		{"user1", []models.LiteSecret{
-			{ID: secret2.ID, ValueHash: utils.GetMD5Hash([]byte("secret2")), DescriptionHash: utils.GetMD5Hash([]byte("desc2")), IsDeleted: true, Ver: secret2.Ver},
-			{ID: secret1.ID, ValueHash: utils.GetMD5Hash([]byte("secret1")), DescriptionHash: utils.GetMD5Hash([]byte("desc1")), IsDeleted: false, Ver: secret1.Ver},
+			{ID: secret2.ID, ValueHash: utils.GetSHA256Hash([]byte("secret2")), DescriptionHash: utils.GetSHA256Hash([]byte("desc2")), IsDeleted: true, Ver: secret2.Ver},
+			{ID: secret1.ID, ValueHash: utils.GetSHA256Hash([]byte("secret1")), DescriptionHash: utils.GetSHA256Hash([]byte("desc1")), IsDeleted: false, Ver: secret1.Ver},
 		}},
 		{"user2", []models.LiteSecret{
-			{ID: secret3.ID, ValueHash: utils.GetMD5Hash([]byte("secret3")), DescriptionHash: utils.GetMD5Hash([]byte("desc3")), IsDeleted: false, Ver: secret3.Ver},
+			{ID: secret3.ID, ValueHash: utils.GetSHA256Hash([]byte("secret3")), DescriptionHash: utils.GetSHA256Hash([]byte("desc3")), IsDeleted: false, Ver: secret3.Ver},
 		}},
 	}
 	for _, tt := range tests {
@@ -109,6 +149,144 @@ func TestSyncSecret(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_CompareAndPutSecret(t *testing.T) {
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+	secret := models.Secret{ID: uuid.New(), OwnerID: "user1", Value: []byte("secret1"), Ver: time.Now()}
+
+	revision, err := s.CompareAndPutSecret(ctx, secret, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), revision)
+
+	// A concurrent update from the same base revision is rejected
+	_, err = s.CompareAndPutSecret(ctx, secret, 0)
+	assert.ErrorIs(t, err, constants.ErrRevisionConflict)
+
+	secret.Value = []byte("secret2")
+	revision, err = s.CompareAndPutSecret(ctx, secret, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), revision)
+
+	// Another owner cannot overwrite the secret
+	_, err = s.CompareAndPutSecret(ctx, models.Secret{ID: secret.ID, OwnerID: "user2"}, 2)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+
+	// A local tombstone keeps the revision it was deleted from
+	assert.NoError(t, s.DeleteSecret(ctx, "user1", secret.ID))
+	stored, err := s.GetSecret(ctx, "user1", secret.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret2"), stored.Value)
+	assert.Equal(t, int64(2), stored.Revision)
+}
+
+func TestMemoryStorage_CompareAndDeleteSecret(t *testing.T) {
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+	secret := models.Secret{ID: uuid.New(), OwnerID: "user1", Value: []byte("secret1"), Ver: time.Now()}
+	for i := 0; i < 2; i++ {
+		_, err := s.CompareAndPutSecret(ctx, secret, int64(i))
+		assert.NoError(t, err)
+	}
+
+	// A delete from a stale revision must not remove the edit made meanwhile
+	_, err := s.CompareAndDeleteSecret(ctx, "user1", secret.ID, 1)
+	assert.ErrorIs(t, err, constants.ErrRevisionConflict)
+	_, err = s.CompareAndDeleteSecret(ctx, "user2", secret.ID, 2)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+
+	revision, err := s.CompareAndDeleteSecret(ctx, "user1", secret.ID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), revision)
+	stored, err := s.GetSecret(ctx, "user1", secret.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.IsDeleted)
+	assert.Equal(t, int64(3), stored.Revision)
+
+	_, err = s.CompareAndDeleteSecret(ctx, "user1", secret.ID, 3)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+}
+
+func TestMemoryStorage_GetVersions(t *testing.T) {
+	s := NewMemoryStorage(2)
+	ctx := context.Background()
+	secret := models.Secret{ID: uuid.New(), OwnerID: "user1", Ver: time.Now()}
+
+	for i, value := range []string{"first", "second", "third"} {
+		secret.Value = []byte(value)
+		_, err := s.CompareAndPutSecret(ctx, secret, int64(i))
+		assert.NoError(t, err)
+	}
+	_, err := s.CompareAndDeleteSecret(ctx, "user1", secret.ID, 3)
+	assert.NoError(t, err)
+
+	// Only the last two revisions are kept, newest first
+	versions, err := s.GetVersions(ctx, "user1", secret.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, int64(4), versions[0].Revision)
+		assert.True(t, versions[0].IsDeleted)
+		assert.Equal(t, int64(3), versions[1].Revision)
+		assert.Equal(t, []byte("third"), versions[1].Value)
+	}
+
+	_, err = s.GetVersions(ctx, "user2", secret.ID)
+	assert.ErrorIs(t, err, constants.ErrSecretNotFound)
+}
+
+func TestMemoryStorage_Changes(t *testing.T) {
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+
+	secret1 := models.Secret{ID: uuid.New(), OwnerID: "user1", Value: []byte("secret1"), Ver: time.Now()}
+	secret2 := models.Secret{ID: uuid.New(), OwnerID: "user1", Value: []byte("secret2"), Ver: time.Now()}
+	other := models.Secret{ID: uuid.New(), OwnerID: "user2", Value: []byte("other"), Ver: time.Now()}
+	for _, secret := range []models.Secret{secret1, secret2, other} {
+		assert.NoError(t, s.PutSecret(ctx, secret))
+	}
+	assert.NoError(t, s.DeleteSecret(ctx, "user1", secret1.ID))
+
+	changes, cursor, err := s.Changes(ctx, "user1", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), cursor)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, secret2.ID, changes[0].ID)
+		assert.Equal(t, int64(2), changes[0].Seq)
+		assert.Equal(t, secret1.ID, changes[1].ID)
+		assert.True(t, changes[1].IsDeleted)
+	}
+
+	changes, cursor, err = s.Changes(ctx, "user1", 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cursor)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, secret2.ID, changes[0].ID)
+	}
+
+	changes, cursor, err = s.Changes(ctx, "user1", 3, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, int64(3), cursor)
+
+	changes, cursor, err = s.Changes(ctx, "user2", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, int64(1), cursor)
+}
+
+func TestMemoryStorage_Cursor(t *testing.T) {
+	s := NewMemoryStorage(constants.DefaultSecretVersions)
+	ctx := context.Background()
+
+	cursor, err := s.GetCursor(ctx, "server:user1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), cursor)
+
+	assert.NoError(t, s.PutCursor(ctx, "server:user1", 42))
+	cursor, err = s.GetCursor(ctx, "server:user1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), cursor)
+}
+
 func TestMemoryStorage_Ping(t *testing.T) {
 	// Create a new instance of the MemoryStorage struct
 	storage := &MemoryStorage{}
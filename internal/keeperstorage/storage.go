@@ -3,6 +3,7 @@ package keeperstorage
 import (
 	"context"
 
+	"yudinsv/gophkeeper/internal/blobstore"
 	servermodels "yudinsv/gophkeeper/internal/gophkeeperserver/models"
 	"yudinsv/gophkeeper/internal/keeperstorage/keeperpgstorage"
 	"yudinsv/gophkeeper/internal/keeperstorage/keepsqlstorage"
@@ -13,30 +14,63 @@ import (
 	"github.com/google/uuid"
 )
 
+// KeeperStorage stores user secrets. Every secret belongs to exactly one owner:
+// PutSecret only overwrites secrets owned by secret.OwnerID, and GetSecret/DeleteSecret
+// only see secrets owned by userID. Secrets of other owners are reported as
+// constants.ErrSecretNotFound.
+// DeleteSecret does not remove the secret but turns it into a tombstone: IsDeleted is set
+// and Ver is moved to the deletion time, so the deletion can be propagated by sync.
+//
+// Every PutSecret and DeleteSecret assigns the secret the next number of the owner's change sequence (Seq),
+// the sequence is monotonically increasing per owner. Changes returns at most limit secrets changed after a cursor,
+// all of them for a limit of 0, and the new cursor, the Seq of the last secret returned. GetCursor and PutCursor persist named sync cursors, a missing cursor is 0.
+//
+// Revision is the server revision of a secret. PutSecret stores the revision as given, so a client keeps
+// the revision its copy was edited from. CompareAndPutSecret atomically stores the secret only if the stored
+// revision (0 for a missing secret) equals baseRevision, assigns it baseRevision+1 and returns the new revision,
+// otherwise it returns constants.ErrRevisionConflict. DeleteSecret keeps the revision, so a local tombstone
+// keeps the revision it was deleted from, while CompareAndDeleteSecret deletes the secret only if the stored
+// revision equals baseRevision and assigns the tombstone baseRevision+1 like CompareAndPutSecret.
+//
+// CompareAndPutSecret and CompareAndDeleteSecret also record the new revision in the history of the secret,
+// the history keeps the given number of the last revisions. GetVersions returns the history newest first.
+//
+// ConflictOf links a conflicted copy to the original secret, it is uuid.Nil for other secrets.
+// BlobID refers to the chunks of a file secret in the blob storage of the server, it is uuid.Nil for other secrets.
 type KeeperStorage interface {
 	Ping() error
 	Close() error
 	PutSecret(ctx context.Context, secret models.Secret) error
-	GetSecret(ctx context.Context, secretID uuid.UUID) (models.Secret, error)
-	DeleteSecret(ctx context.Context, secretID uuid.UUID) error
+	CompareAndPutSecret(ctx context.Context, secret models.Secret, baseRevision int64) (int64, error)
+	GetSecret(ctx context.Context, userID string, secretID uuid.UUID) (models.Secret, error)
+	DeleteSecret(ctx context.Context, userID string, secretID uuid.UUID) error
+	CompareAndDeleteSecret(ctx context.Context, userID string, secretID uuid.UUID, baseRevision int64) (int64, error)
+	GetVersions(ctx context.Context, userID string, secretID uuid.UUID) ([]models.Secret, error)
 	SyncSecret(ctx context.Context, userID string) ([]models.LiteSecret, error)
+	Changes(ctx context.Context, userID string, since int64, limit int) ([]models.LiteSecret, int64, error)
+	GetCursor(ctx context.Context, name string) (int64, error)
+	PutCursor(ctx context.Context, name string, cursor int64) error
 }
 
 func NewKeeperStorage(cfg servermodels.Config) (KeeperStorage, error) {
 	var goferStorage KeeperStorage
 	var err error
 	if cfg.DataBaseURI != "" {
-		goferStorage, err = keeperpgstorage.NewPostgresStorage(cfg.DataBaseURI)
+		blobs, err := blobstore.NewBlobStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		goferStorage, err = keeperpgstorage.NewPostgresStorage(cfg.DataBaseURI, cfg.SecretVersions, blobs, cfg.BlobThreshold)
 		if err != nil {
 			return nil, err
 		}
 	} else if cfg.DBPath != "" {
-		goferStorage, err = keepsqlstorage.NewSqliteStorage(cfg.DBPath)
+		goferStorage, err = keepsqlstorage.NewSqliteStorage(cfg.DBPath, cfg.SecretVersions)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		goferStorage = keepermemstorage.NewMemoryStorage()
+		goferStorage = keepermemstorage.NewMemoryStorage(cfg.SecretVersions)
 	}
 	//goferStorage = keepermemstorage.NewMemoryStorage()
 	return goferStorage, nil
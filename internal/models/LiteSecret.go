@@ -12,4 +12,6 @@ type LiteSecret struct {
 	DescriptionHash string    `json:"description_hash"`
 	IsDeleted       bool      `json:"is_deleted"`
 	Ver             time.Time `json:"ver"`
+	Seq             int64     `json:"seq"`
+	Revision        int64     `json:"revision"`
 }
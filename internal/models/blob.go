@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blob describes the chunks of a file secret stored on the server, the secret refers to it by BlobID.
+// The chunks are encrypted by the client, the server only keeps them with their digests.
+// Uploaded lists the indexes of the chunks the server has, so an interrupted upload sends only the missing ones.
+// A complete blob has all its chunks and cannot be changed.
+type Blob struct {
+	ID       uuid.UUID `json:"id"`
+	OwnerID  string    `json:"-"`
+	Chunks   int       `json:"chunks"`
+	Size     int64     `json:"size"`
+	Complete bool      `json:"complete"`
+	Uploaded []int     `json:"uploaded"`
+}
+
+// BlobObject is a payload kept in a blob store, it is named by Hash, the hex SHA-256 digest of its content.
+type BlobObject struct {
+	Hash     string
+	Size     int64
+	Modified time.Time
+}
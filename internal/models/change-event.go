@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+// ChangeEvent notifies the devices of the owner that a secret was changed, the devices pull the changes then.
+// Revision is the new revision of a stored secret, Deleted is set for a deleted one.
+type ChangeEvent struct {
+	OwnerID  string    `json:"-"`
+	SecretID uuid.UUID `json:"secret_id"`
+	Revision int64     `json:"revision,omitempty"`
+	Deleted  bool      `json:"deleted,omitempty"`
+}
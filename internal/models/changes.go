@@ -0,0 +1,10 @@
+package models
+
+// Changes is the answer to a delta sync request: the secrets changed or deleted
+// after the requested cursor and the cursor to ask with next time.
+// HasMore is set when the changes are paged and more changes follow the cursor.
+type Changes struct {
+	Secrets []LiteSecret `json:"secrets"`
+	Cursor  int64        `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// KDFParams describes how the vault encryption key is derived from the master password with Argon2id.
+// The params are stored on the server, so every device of the user re-derives the same key.
+// Verifier lets the client check the entered master password without decrypting any secret.
+type KDFParams struct {
+	Salt     []byte `json:"salt"`
+	Time     uint32 `json:"time"`
+	Memory   uint32 `json:"memory"`
+	Threads  uint8  `json:"threads"`
+	KeyLen   uint32 `json:"key_len"`
+	Verifier []byte `json:"verifier"`
+}
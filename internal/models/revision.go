@@ -0,0 +1,6 @@
+package models
+
+// Revision is the revision assigned by the server to a stored secret.
+type Revision struct {
+	Revision int64 `json:"revision"`
+}
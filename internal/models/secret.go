@@ -14,4 +14,8 @@ type Secret struct {
 	Description string    `json:"description"`
 	IsDeleted   bool      `json:"is_deleted"`
 	Ver         time.Time `json:"ver"`
+	Seq         int64     `json:"seq"`
+	Revision    int64     `json:"revision"`
+	ConflictOf  uuid.UUID `json:"conflict_of"`
+	BlobID      uuid.UUID `json:"blob_id"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one login of a user. The access and refresh tokens issued on the login
+// and rotated from it belong to the session and stop working when it is revoked.
+type Session struct {
+	ID        string    `json:"id"`
+	Login     string    `json:"-"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Revoked   bool      `json:"revoked"`
+	Current   bool      `json:"current"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Tokens is the pair of tokens issued on login and on refresh.
+// The access token authorizes the requests until it expires in ExpiresIn seconds,
+// the refresh token is exchanged for a new pair once and then becomes invalid.
+//
+// If the user has enabled the second factor, the login returns MFARequired and a restricted access token
+// without a refresh token, it is only accepted by /api/v1/2fa/verify.
+type Tokens struct {
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+}
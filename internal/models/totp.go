@@ -0,0 +1,18 @@
+package models
+
+// TOTPEnrollment is the secret of a new second factor, the user adds it to an authenticator app
+// from the otpauth URI and confirms the enrollment with a code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TOTPCode is a code from the authenticator app or a recovery code.
+type TOTPCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodes are the one-time codes replacing the authenticator app when it is lost.
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
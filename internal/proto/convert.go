@@ -0,0 +1,120 @@
+package proto
+
+import (
+	"yudinsv/gophkeeper/internal/models"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromSecret converts the secret to its message. The owner is not sent, it is always the authenticated user.
+func FromSecret(secret models.Secret) *Secret {
+	message := &Secret{
+		Id:          secret.ID.String(),
+		Value:       secret.Value,
+		Type:        secret.Type,
+		Description: secret.Description,
+		IsDeleted:   secret.IsDeleted,
+		Ver:         timestamppb.New(secret.Ver),
+		Seq:         secret.Seq,
+		Revision:    secret.Revision,
+	}
+	if secret.ConflictOf != uuid.Nil {
+		message.ConflictOf = secret.ConflictOf.String()
+	}
+	if secret.BlobID != uuid.Nil {
+		message.BlobId = secret.BlobID.String()
+	}
+	return message
+}
+
+// ToSecret converts the message to the secret of the owner.
+func ToSecret(message *Secret, ownerID string) (models.Secret, error) {
+	id, err := uuid.Parse(message.GetId())
+	if err != nil {
+		return models.Secret{}, err
+	}
+	var conflictOf uuid.UUID
+	if message.GetConflictOf() != "" {
+		if conflictOf, err = uuid.Parse(message.GetConflictOf()); err != nil {
+			return models.Secret{}, err
+		}
+	}
+	var blobID uuid.UUID
+	if message.GetBlobId() != "" {
+		if blobID, err = uuid.Parse(message.GetBlobId()); err != nil {
+			return models.Secret{}, err
+		}
+	}
+	return models.Secret{
+		ID:          id,
+		OwnerID:     ownerID,
+		Value:       message.GetValue(),
+		Type:        message.GetType(),
+		Description: message.GetDescription(),
+		IsDeleted:   message.GetIsDeleted(),
+		Ver:         message.GetVer().AsTime(),
+		Seq:         message.GetSeq(),
+		Revision:    message.GetRevision(),
+		ConflictOf:  conflictOf,
+		BlobID:      blobID,
+	}, nil
+}
+
+// FromChanges converts the changes to their message.
+func FromChanges(changes models.Changes) *ChangeBatch {
+	batch := &ChangeBatch{Cursor: changes.Cursor, Secrets: make([]*LiteSecret, 0, len(changes.Secrets))}
+	for _, secret := range changes.Secrets {
+		batch.Secrets = append(batch.Secrets, &LiteSecret{
+			Id:              secret.ID.String(),
+			ValueHash:       secret.ValueHash,
+			DescriptionHash: secret.DescriptionHash,
+			IsDeleted:       secret.IsDeleted,
+			Ver:             timestamppb.New(secret.Ver),
+			Seq:             secret.Seq,
+			Revision:        secret.Revision,
+		})
+	}
+	return batch
+}
+
+// ToChanges converts the message to the changes.
+func ToChanges(batch *ChangeBatch) (models.Changes, error) {
+	changes := models.Changes{Cursor: batch.GetCursor(), Secrets: make([]models.LiteSecret, 0, len(batch.GetSecrets()))}
+	for _, secret := range batch.GetSecrets() {
+		id, err := uuid.Parse(secret.GetId())
+		if err != nil {
+			return models.Changes{}, err
+		}
+		changes.Secrets = append(changes.Secrets, models.LiteSecret{
+			ID:              id,
+			ValueHash:       secret.GetValueHash(),
+			DescriptionHash: secret.GetDescriptionHash(),
+			IsDeleted:       secret.GetIsDeleted(),
+			Ver:             secret.GetVer().AsTime(),
+			Seq:             secret.GetSeq(),
+			Revision:        secret.GetRevision(),
+		})
+	}
+	return changes, nil
+}
+
+// FromTokens converts the tokens to their message.
+func FromTokens(tokens models.Tokens) *Tokens {
+	return &Tokens{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		MfaRequired:  tokens.MFARequired,
+	}
+}
+
+// ToTokens converts the message to the tokens.
+func ToTokens(message *Tokens) models.Tokens {
+	return models.Tokens{
+		AccessToken:  message.GetAccessToken(),
+		RefreshToken: message.GetRefreshToken(),
+		ExpiresIn:    message.GetExpiresIn(),
+		MFARequired:  message.GetMfaRequired(),
+	}
+}
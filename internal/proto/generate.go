@@ -0,0 +1,5 @@
+// Package proto contains the gRPC API of the server generated from keeper.proto
+// and the conversions between its messages and the models.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative keeper.proto
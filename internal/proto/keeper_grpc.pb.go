@@ -0,0 +1,380 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: keeper.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Keeper_Ping_FullMethodName         = "/gophkeeper.Keeper/Ping"
+	Keeper_Register_FullMethodName     = "/gophkeeper.Keeper/Register"
+	Keeper_Login_FullMethodName        = "/gophkeeper.Keeper/Login"
+	Keeper_PutSecret_FullMethodName    = "/gophkeeper.Keeper/PutSecret"
+	Keeper_GetSecret_FullMethodName    = "/gophkeeper.Keeper/GetSecret"
+	Keeper_DeleteSecret_FullMethodName = "/gophkeeper.Keeper/DeleteSecret"
+	Keeper_Changes_FullMethodName      = "/gophkeeper.Keeper/Changes"
+)
+
+// KeeperClient is the client API for Keeper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type KeeperClient interface {
+	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Register creates the user and starts a session like Login.
+	Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Tokens, error)
+	// Login starts a session, the device name is taken from the "x-device-name" metadata.
+	// If the user has enabled the second factor, only a restricted token is returned with mfa_required,
+	// the code is verified with the REST API.
+	Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Tokens, error)
+	// PutSecret stores the secret if the server copy is still at the revision of the secret,
+	// otherwise it fails with ABORTED.
+	PutSecret(ctx context.Context, in *Secret, opts ...grpc.CallOption) (*Revision, error)
+	GetSecret(ctx context.Context, in *SecretID, opts ...grpc.CallOption) (*Secret, error)
+	// DeleteSecret turns the secret into a tombstone if the server copy is still at the revision of the request,
+	// otherwise it fails with ABORTED.
+	DeleteSecret(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Revision, error)
+	// Changes streams the secrets changed after the cursor. Without follow the stream ends after the first batch,
+	// with follow the next batches are sent as the secrets change.
+	Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (Keeper_ChangesClient, error)
+}
+
+type keeperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeeperClient(cc grpc.ClientConnInterface) KeeperClient {
+	return &keeperClient{cc}
+}
+
+func (c *keeperClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Keeper_Ping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Tokens, error) {
+	out := new(Tokens)
+	err := c.cc.Invoke(ctx, Keeper_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Tokens, error) {
+	out := new(Tokens)
+	err := c.cc.Invoke(ctx, Keeper_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) PutSecret(ctx context.Context, in *Secret, opts ...grpc.CallOption) (*Revision, error) {
+	out := new(Revision)
+	err := c.cc.Invoke(ctx, Keeper_PutSecret_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetSecret(ctx context.Context, in *SecretID, opts ...grpc.CallOption) (*Secret, error) {
+	out := new(Secret)
+	err := c.cc.Invoke(ctx, Keeper_GetSecret_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) DeleteSecret(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Revision, error) {
+	out := new(Revision)
+	err := c.cc.Invoke(ctx, Keeper_DeleteSecret_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (Keeper_ChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Keeper_ServiceDesc.Streams[0], Keeper_Changes_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keeperChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Keeper_ChangesClient interface {
+	Recv() (*ChangeBatch, error)
+	grpc.ClientStream
+}
+
+type keeperChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *keeperChangesClient) Recv() (*ChangeBatch, error) {
+	m := new(ChangeBatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// KeeperServer is the server API for Keeper service.
+// All implementations must embed UnimplementedKeeperServer
+// for forward compatibility
+type KeeperServer interface {
+	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// Register creates the user and starts a session like Login.
+	Register(context.Context, *Credentials) (*Tokens, error)
+	// Login starts a session, the device name is taken from the "x-device-name" metadata.
+	// If the user has enabled the second factor, only a restricted token is returned with mfa_required,
+	// the code is verified with the REST API.
+	Login(context.Context, *Credentials) (*Tokens, error)
+	// PutSecret stores the secret if the server copy is still at the revision of the secret,
+	// otherwise it fails with ABORTED.
+	PutSecret(context.Context, *Secret) (*Revision, error)
+	GetSecret(context.Context, *SecretID) (*Secret, error)
+	// DeleteSecret turns the secret into a tombstone if the server copy is still at the revision of the request,
+	// otherwise it fails with ABORTED.
+	DeleteSecret(context.Context, *DeleteRequest) (*Revision, error)
+	// Changes streams the secrets changed after the cursor. Without follow the stream ends after the first batch,
+	// with follow the next batches are sent as the secrets change.
+	Changes(*ChangesRequest, Keeper_ChangesServer) error
+	mustEmbedUnimplementedKeeperServer()
+}
+
+// UnimplementedKeeperServer must be embedded to have forward compatible implementations.
+type UnimplementedKeeperServer struct {
+}
+
+func (UnimplementedKeeperServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedKeeperServer) Register(context.Context, *Credentials) (*Tokens, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedKeeperServer) Login(context.Context, *Credentials) (*Tokens, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedKeeperServer) PutSecret(context.Context, *Secret) (*Revision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutSecret not implemented")
+}
+func (UnimplementedKeeperServer) GetSecret(context.Context, *SecretID) (*Secret, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSecret not implemented")
+}
+func (UnimplementedKeeperServer) DeleteSecret(context.Context, *DeleteRequest) (*Revision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSecret not implemented")
+}
+func (UnimplementedKeeperServer) Changes(*ChangesRequest, Keeper_ChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method Changes not implemented")
+}
+func (UnimplementedKeeperServer) mustEmbedUnimplementedKeeperServer() {}
+
+// UnsafeKeeperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeeperServer will
+// result in compilation errors.
+type UnsafeKeeperServer interface {
+	mustEmbedUnimplementedKeeperServer()
+}
+
+func RegisterKeeperServer(s grpc.ServiceRegistrar, srv KeeperServer) {
+	s.RegisterService(&Keeper_ServiceDesc, srv)
+}
+
+func _Keeper_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Ping(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Register(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Login(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_PutSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Secret)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).PutSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_PutSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).PutSecret(ctx, req.(*Secret))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetSecret(ctx, req.(*SecretID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_DeleteSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).DeleteSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_DeleteSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).DeleteSecret(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Changes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeeperServer).Changes(m, &keeperChangesServer{stream})
+}
+
+type Keeper_ChangesServer interface {
+	Send(*ChangeBatch) error
+	grpc.ServerStream
+}
+
+type keeperChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *keeperChangesServer) Send(m *ChangeBatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Keeper_ServiceDesc is the grpc.ServiceDesc for Keeper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Keeper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gophkeeper.Keeper",
+	HandlerType: (*KeeperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _Keeper_Ping_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _Keeper_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Keeper_Login_Handler,
+		},
+		{
+			MethodName: "PutSecret",
+			Handler:    _Keeper_PutSecret_Handler,
+		},
+		{
+			MethodName: "GetSecret",
+			Handler:    _Keeper_GetSecret_Handler,
+		},
+		{
+			MethodName: "DeleteSecret",
+			Handler:    _Keeper_DeleteSecret_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Changes",
+			Handler:       _Keeper_Changes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "keeper.proto",
+}
@@ -0,0 +1,86 @@
+// Package utils provides functions for deriving the vault encryption key from the master password with Argon2id.
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	"yudinsv/gophkeeper/internal/models"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters used for new vault keys.
+const (
+	vaultKeyTime    uint32 = 3
+	vaultKeyMemory  uint32 = 64 * 1024
+	vaultKeyThreads uint8  = 4
+	vaultKeyLen     uint32 = 32
+	vaultSaltLen           = 16
+)
+
+// The bounds of the accepted Argon2id parameters. The minimums keep the registered params from being trivially weak,
+// the maximums keep the params served to the clients from making them run out of memory.
+const (
+	vaultKeyMinTime    uint32 = 2
+	vaultKeyMinMemory  uint32 = 19 * 1024
+	vaultKeyMaxMemory  uint32 = 1024 * 1024
+	vaultKeyMaxThreads uint8  = 64
+)
+
+// verifierLabel is the message authenticated with the vault key to build the KDFParams verifier.
+const verifierLabel = "gophkeeper vault key verifier"
+
+// ErrInvalidKDFParams the key derivation params are missing or too weak.
+var ErrInvalidKDFParams = errors.New("invalid kdf params")
+
+// NewKDFParams generates key derivation params with a random per-user salt.
+// The verifier is not set, it is filled in by SetKeyVerifier once the master password is known.
+func NewKDFParams() (models.KDFParams, error) {
+	salt := make([]byte, vaultSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return models.KDFParams{}, err
+	}
+	return models.KDFParams{
+		Salt:    salt,
+		Time:    vaultKeyTime,
+		Memory:  vaultKeyMemory,
+		Threads: vaultKeyThreads,
+		KeyLen:  vaultKeyLen,
+	}, nil
+}
+
+// ValidKDFParams checks that the params are complete, within the bounds and produce an AES-256 key.
+func ValidKDFParams(params models.KDFParams) error {
+	if len(params.Salt) < vaultSaltLen || params.Time < vaultKeyMinTime ||
+		params.Memory < vaultKeyMinMemory || params.Memory > vaultKeyMaxMemory ||
+		params.Threads == 0 || params.Threads > vaultKeyMaxThreads ||
+		params.KeyLen != vaultKeyLen || len(params.Verifier) == 0 {
+		return ErrInvalidKDFParams
+	}
+	return nil
+}
+
+// DeriveKey derives the vault encryption key from the master password.
+func DeriveKey(masterPassword string, params models.KDFParams) []byte {
+	return argon2.IDKey([]byte(masterPassword), params.Salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+}
+
+// SetKeyVerifier stores the verifier of the key in the params.
+func SetKeyVerifier(params *models.KDFParams, key []byte) {
+	params.Verifier = keyVerifier(key)
+}
+
+// CheckKey reports whether the key was derived from the same master password as the params verifier.
+func CheckKey(params models.KDFParams, key []byte) bool {
+	return hmac.Equal(params.Verifier, keyVerifier(key))
+}
+
+// keyVerifier authenticates a fixed label with the key.
+func keyVerifier(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(verifierLabel))
+	return mac.Sum(nil)
+}
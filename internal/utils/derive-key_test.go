@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"yudinsv/gophkeeper/internal/models"
+)
+
+func TestDeriveKey(t *testing.T) {
+	params, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := DeriveKey("master", params)
+	if len(key) != 32 {
+		t.Fatalf("DeriveKey() key length = %d, want 32", len(key))
+	}
+	if !bytes.Equal(key, DeriveKey("master", params)) {
+		t.Errorf("DeriveKey() must be deterministic for the same params")
+	}
+	if bytes.Equal(key, DeriveKey("other", params)) {
+		t.Errorf("DeriveKey() must depend on the master password")
+	}
+	other, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(key, DeriveKey("master", other)) {
+		t.Errorf("DeriveKey() must depend on the salt")
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	params, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ValidKDFParams(params); err == nil {
+		t.Errorf("ValidKDFParams() must reject params without verifier")
+	}
+	SetKeyVerifier(&params, DeriveKey("master", params))
+	if err = ValidKDFParams(params); err != nil {
+		t.Errorf("ValidKDFParams() error = %v", err)
+	}
+	if !CheckKey(params, DeriveKey("master", params)) {
+		t.Errorf("CheckKey() = false for the right master password")
+	}
+	if CheckKey(params, DeriveKey("wrong", params)) {
+		t.Errorf("CheckKey() = true for a wrong master password")
+	}
+}
+
+func TestValidKDFParams_Bounds(t *testing.T) {
+	params, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyVerifier(&params, []byte("key"))
+	tests := []struct {
+		name   string
+		change func(params *models.KDFParams)
+	}{
+		{"one pass", func(params *models.KDFParams) { params.Time = 1 }},
+		{"8 KiB", func(params *models.KDFParams) { params.Memory = 8 }},
+		{"4 TiB", func(params *models.KDFParams) { params.Memory = 0xFFFFFFFF }},
+		{"no threads", func(params *models.KDFParams) { params.Threads = 0 }},
+		{"255 threads", func(params *models.KDFParams) { params.Threads = 255 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weak := params
+			tt.change(&weak)
+			if err := ValidKDFParams(weak); !errors.Is(err, ErrInvalidKDFParams) {
+				t.Errorf("ValidKDFParams() error = %v, want %v", err, ErrInvalidKDFParams)
+			}
+		})
+	}
+}
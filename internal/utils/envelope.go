@@ -0,0 +1,107 @@
+// Package utils provides functions for sealing secrets into authenticated, versioned envelopes.
+//
+// An envelope of version 1 has the layout
+//
+//	"GK" | version (1 byte) | nonce (12 bytes) | AES-256-GCM ciphertext and tag
+//
+// The nonce is random for every sealed value. The secret ID and type are bound
+// to the ciphertext as associated data, so a value cannot be moved to another secret unnoticed.
+// Values without the envelope header were stored before the vault key, see DecryptLegacyValue.
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// envelopeV1 is the version byte of the AES-256-GCM envelope.
+const envelopeV1 byte = 1
+
+// envelopeV1MinSize is the size of a version 1 envelope of an empty plaintext: the header, the nonce and the tag.
+const envelopeV1MinSize = 3 + 12 + 16
+
+// envelopeMagic marks a value as an envelope.
+var envelopeMagic = []byte("GK")
+
+// ErrUnsupportedEnvelope the envelope version is unknown to this client.
+var ErrUnsupportedEnvelope = errors.New("unsupported envelope version")
+
+// ErrNotEnvelope the value has no envelope header, it was stored before the vault key.
+var ErrNotEnvelope = errors.New("value is not an envelope")
+
+// ErrEnvelopeAuth the envelope was tampered with or sealed with another key or associated data.
+var ErrEnvelopeAuth = errors.New("envelope authentication failed")
+
+// SecretAssociatedData builds the associated data that binds an envelope to the secret ID and type.
+func SecretAssociatedData(secretID uuid.UUID, secretType string) []byte {
+	return append(secretID[:], secretType...)
+}
+
+// SealEnvelope encrypts the plaintext with AES-256-GCM and a random nonce into a version 1 envelope.
+func SealEnvelope(plaintext []byte, key []byte, associatedData []byte) ([]byte, error) {
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := append(append([]byte{}, envelopeMagic...), envelopeV1)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	additional := append(append([]byte{}, header...), associatedData...)
+	envelope := append(header, nonce...)
+	return aead.Seal(envelope, nonce, plaintext, additional), nil
+}
+
+// OpenEnvelope decrypts a value sealed by SealEnvelope.
+// Values without the envelope header are reported with ErrNotEnvelope.
+// The header is only two bytes of magic and a version, so a value stored before the vault key
+// may start with it by chance: the callers opening such values fall back to DecryptLegacyValue
+// when the envelope does not open.
+func OpenEnvelope(value []byte, key []byte, associatedData []byte) ([]byte, error) {
+	if len(value) <= len(envelopeMagic) || !bytes.HasPrefix(value, envelopeMagic) {
+		return nil, ErrNotEnvelope
+	}
+	header := value[:len(envelopeMagic)+1]
+	if header[len(envelopeMagic)] != envelopeV1 {
+		return nil, ErrUnsupportedEnvelope
+	}
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	body := value[len(header):]
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	additional := append(append([]byte{}, header...), associatedData...)
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrEnvelopeAuth
+	}
+	return plaintext, nil
+}
+
+// IsEnvelope reports whether the value looks like an envelope of a supported version:
+// it starts with the magic and the version and is long enough to hold the nonce and the tag.
+// Only opening the envelope proves it is one, see OpenEnvelope.
+func IsEnvelope(value []byte) bool {
+	return len(value) >= envelopeV1MinSize && bytes.HasPrefix(value, envelopeMagic) && value[len(envelopeMagic)] == envelopeV1
+}
+
+// newEnvelopeAEAD creates the AES-256-GCM cipher of the envelope.
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, aes.KeySizeError(len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSealOpenEnvelope(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	secretID := uuid.New()
+	ad := SecretAssociatedData(secretID, "text")
+	plaintext := []byte("hello world")
+
+	first, err := SealEnvelope(plaintext, key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := SealEnvelope(plaintext, key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Errorf("SealEnvelope must use a random nonce for every value")
+	}
+	if !IsEnvelope(first) || first[2] != envelopeV1 {
+		t.Errorf("SealEnvelope must produce a version 1 envelope")
+	}
+
+	decrypted, err := OpenEnvelope(first, key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("Sealing and opening of envelope failed")
+	}
+
+	// Empty plaintext
+	empty, err := SealEnvelope(nil, key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = OpenEnvelope(empty, key, ad)
+	if err != nil || len(decrypted) != 0 {
+		t.Errorf("Sealing and opening of empty envelope failed: %v", err)
+	}
+}
+
+func TestOpenEnvelopeTampered(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	otherKey := make([]byte, 32)
+	if _, err := rand.Read(otherKey); err != nil {
+		t.Fatal(err)
+	}
+	secretID := uuid.New()
+	ad := SecretAssociatedData(secretID, "text")
+	envelope, err := SealEnvelope([]byte("hello world"), key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte{}, envelope...)
+	flipped[len(flipped)-1] ^= 1
+	unknown := append([]byte{}, envelope...)
+	unknown[2] = 2
+
+	tests := []struct {
+		name    string
+		value   []byte
+		key     []byte
+		ad      []byte
+		wantErr error
+	}{
+		{"flipped bit", flipped, key, ad, ErrEnvelopeAuth},
+		{"other key", envelope, otherKey, ad, ErrEnvelopeAuth},
+		{"other secret ID", envelope, key, SecretAssociatedData(uuid.New(), "text"), ErrEnvelopeAuth},
+		{"other secret type", envelope, key, SecretAssociatedData(secretID, "binary"), ErrEnvelopeAuth},
+		{"truncated", envelope[:10], key, ad, ErrInvalidCiphertext},
+		{"unknown version", unknown, key, ad, ErrUnsupportedEnvelope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenEnvelope(tt.value, tt.key, tt.ad)
+			if err != tt.wantErr {
+				t.Errorf("OpenEnvelope() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenEnvelopeLegacy(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	// A value stored before the vault key
+	privateKey := uuid.NewString()
+	keyStr := []byte(privateKey)
+	plaintext := []byte("hello world")
+	ciphertext, err := Encrypt(plaintext, keyStr[:aes.BlockSize], keyStr[len(keyStr)-aes.BlockSize:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenEnvelope(ciphertext, key, nil); err != ErrNotEnvelope {
+		t.Errorf("OpenEnvelope() error = %v, want %v", err, ErrNotEnvelope)
+	}
+
+	decrypted, err := DecryptLegacyValue(ciphertext, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("Decryption of legacy value failed")
+	}
+	if _, err = DecryptLegacyValue(ciphertext, "not a private key"); err == nil {
+		t.Errorf("DecryptLegacyValue must reject a key that is not a private key")
+	}
+}
+
+// legacyValueWithHeader encrypts a plaintext the way the clients did before the vault key,
+// chosen so that the ciphertext starts with the envelope header.
+func legacyValueWithHeader(t *testing.T, privateKey string) ([]byte, []byte) {
+	keyStr := []byte(privateKey)
+	key, iv := keyStr[:aes.BlockSize], keyStr[len(keyStr)-aes.BlockSize:]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(first); err != nil {
+		t.Fatal(err)
+	}
+	copy(first, []byte{'G', 'K', envelopeV1})
+	plaintext := make([]byte, aes.BlockSize)
+	block.Decrypt(plaintext, first)
+	for i := range plaintext {
+		plaintext[i] ^= iv[i]
+	}
+	ciphertext, err := Encrypt(plaintext, key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(ciphertext, first) {
+		t.Fatal("the ciphertext does not start with the chosen block")
+	}
+	return ciphertext, plaintext
+}
+
+func TestOpenEnvelopeLegacyWithHeader(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	privateKey := uuid.NewString()
+	value, plaintext := legacyValueWithHeader(t, privateKey)
+	if !IsEnvelope(value) {
+		t.Fatal("the value must look like an envelope")
+	}
+	if _, err := OpenEnvelope(value, key, nil); err != ErrEnvelopeAuth {
+		t.Errorf("OpenEnvelope() error = %v, want %v", err, ErrEnvelopeAuth)
+	}
+	decrypted, err := DecryptLegacyValue(value, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("Decryption of legacy value starting with the envelope header failed")
+	}
+}
+
+func TestIsEnvelope(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := SealEnvelope(nil, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := append([]byte{}, envelope...)
+	unknown[2] = 2
+
+	tests := []struct {
+		name  string
+		value []byte
+		want  bool
+	}{
+		{"empty envelope", envelope, true},
+		{"magic only", []byte("GK"), false},
+		{"shorter than nonce and tag", envelope[:len(envelope)-1], false},
+		{"unknown version", unknown, false},
+		{"no magic", append([]byte("XX"), envelope[2:]...), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEnvelope(tt.value); got != tt.want {
+				t.Errorf("IsEnvelope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Package utils provides a collection of utility functions.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GetSHA256Hash computes the SHA-256 hash of a given byte slice and returns it as a hexadecimal string.
+// Sync uses it to detect changes of the encrypted values and metadata without decrypting them.
+//
+// Parameters:
+// - text: A byte slice containing the data to be hashed.
+//
+// Returns:
+// - A string representing the hexadecimal value of the SHA-256 hash of the input data.
+func GetSHA256Hash(text []byte) string {
+	hash := sha256.Sum256(text)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,94 @@
+// Package utils provides functions for hashing and verifying account passwords with Argon2id.
+// Hashes are stored in the PHC string format, so the parameters travel together with the hash:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters used for new password hashes.
+const (
+	passwordTime    uint32 = 1
+	passwordMemory  uint32 = 64 * 1024
+	passwordThreads uint8  = 4
+	passwordKeyLen  uint32 = 32
+	passwordSaltLen        = 16
+)
+
+// The bounds of the parameters of a stored hash, a few times those of the new hashes
+// so they can be tuned up. A damaged or injected hash cannot make a login allocate more.
+const (
+	passwordMaxTime    uint32 = 16
+	passwordMaxMemory  uint32 = 4 * passwordMemory
+	passwordMaxThreads uint8  = 4 * passwordThreads
+	passwordMaxKeyLen         = 2 * passwordKeyLen
+)
+
+const passwordHashPrefix = "$argon2id$"
+
+// ErrInvalidPasswordHash the stored password hash cannot be parsed.
+var ErrInvalidPasswordHash = errors.New("invalid password hash")
+
+// HashPassword hashes the password with Argon2id and a random salt.
+// It returns the hash encoded together with its parameters.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, passwordTime, passwordMemory, passwordThreads, passwordKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		passwordHashPrefix, argon2.Version, passwordMemory, passwordTime, passwordThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// IsPasswordHash reports whether the stored value is an Argon2id hash
+// rather than a legacy plaintext password.
+func IsPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, passwordHashPrefix)
+}
+
+// ComparePassword checks the password against the stored value in constant time.
+// The stored value is either an Argon2id hash produced by HashPassword
+// or a legacy plaintext password, which should be rehashed by the caller after a successful check.
+func ComparePassword(stored string, password string) (bool, error) {
+	if !IsPasswordHash(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, nil
+	}
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false, ErrInvalidPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrInvalidPasswordHash
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		time < 1 || time > passwordMaxTime || threads < 1 || threads > passwordMaxThreads ||
+		memory < 8*uint32(threads) || memory > passwordMaxMemory {
+		return false, ErrInvalidPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrInvalidPasswordHash
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 || len(hash) > int(passwordMaxKeyLen) {
+		return false, ErrInvalidPasswordHash
+	}
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(hash)))
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=1,p=4$") {
+		t.Errorf("HashPassword() = %q, want argon2id PHC string", hash)
+	}
+	other, err := HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == other {
+		t.Errorf("HashPassword() must use a random salt")
+	}
+}
+
+func TestComparePassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		stored   string
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{"hash match", hash, "password", true, false},
+		{"hash mismatch", hash, "wrong", false, false},
+		{"legacy plaintext match", "password", "password", true, false},
+		{"legacy plaintext mismatch", "password", "wrong", false, false},
+		{"broken hash", "$argon2id$v=19$m=65536", "password", false, true},
+		{"wrong version", strings.Replace(hash, "v=19", "v=16", 1), "password", false, true},
+		{"4 TiB memory", strings.Replace(hash, "m=65536", "m=4294967295", 1), "password", false, true},
+		{"no iterations", strings.Replace(hash, "t=1", "t=0", 1), "password", false, true},
+		{"too many iterations", strings.Replace(hash, "t=1", "t=1000000", 1), "password", false, true},
+		{"too many threads", strings.Replace(hash, "p=4", "p=255", 1), "password", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComparePassword(tt.stored, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ComparePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ComparePassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPasswordHash(t *testing.T) {
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPasswordHash(hash) {
+		t.Errorf("IsPasswordHash(%q) = false, want true", hash)
+	}
+	if IsPasswordHash("password") {
+		t.Errorf("IsPasswordHash(plaintext) = true, want false")
+	}
+}
@@ -1,12 +1,30 @@
 // Package utils provides functions for encrypting and decrypting data using the AES encryption algorithm with the CBC mode of operation.
+// The CBC mode is only kept to read the values stored before the vault key, new secrets are sealed into envelopes, see envelope.go.
 // The package imports the "crypto/aes" and "crypto/cipher" packages.
 package utils
 
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"errors"
+
+	"github.com/google/uuid"
 )
 
+// ErrInvalidCiphertext the ciphertext is too short, not aligned to the block size or badly padded.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// DecryptLegacyValue decrypts a value stored by the clients before the vault key.
+// Such a value is the AES-CBC ciphertext under the private key the user was given on registration:
+// the first 16 characters of the key are the AES key and the last 16 characters the IV.
+func DecryptLegacyValue(value []byte, privateKey string) ([]byte, error) {
+	if _, err := uuid.Parse(privateKey); err != nil {
+		return nil, err
+	}
+	keyStr := []byte(privateKey)
+	return Decrypt(value, keyStr[:aes.BlockSize], keyStr[len(keyStr)-aes.BlockSize:])
+}
+
 // Encrypt takes a plaintext byte array, a key byte array, and an initialization vector (IV) byte array as inputs.
 // It creates a new AES cipher using the key and pads the plaintext to a multiple of the block size.
 // It then creates a new CBC cipher using the AES cipher and IV and encrypts the plaintext using the CBC cipher.
@@ -41,6 +59,11 @@ func Decrypt(ciphertext []byte, key []byte, iv []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	// The ciphertext is never empty because of the padding
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 || len(iv) != aes.BlockSize {
+		return nil, ErrInvalidCiphertext
+	}
+
 	// Create a new CBC cipher using the AES cipher and IV
 	mode := cipher.NewCBCDecrypter(block, iv)
 
@@ -49,9 +72,7 @@ func Decrypt(ciphertext []byte, key []byte, iv []byte) ([]byte, error) {
 	mode.CryptBlocks(plaintext, ciphertext)
 
 	// Unpad the plaintext to remove any padding bytes added during encryption
-	plaintext = unpad(plaintext)
-
-	return plaintext, nil
+	return unpad(plaintext, aes.BlockSize)
 }
 
 // pad takes a data byte array and a block size as inputs and pads the data to a multiple of the block size using
@@ -65,9 +86,20 @@ func pad(data []byte, blockSize int) []byte {
 	return append(data, padBytes...)
 }
 
-// unpad takes a data byte array as input and removes PKCS7 padding from the end of the array.
-// The function returns the unpadded data byte array.
-func unpad(data []byte) []byte {
+// unpad takes a data byte array and a block size as inputs and removes PKCS7 padding from the end of the array.
+// The function returns the unpadded data byte array or ErrInvalidCiphertext if the padding is broken.
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidCiphertext
+	}
 	padding := int(data[len(data)-1])
-	return data[:len(data)-padding]
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, ErrInvalidCiphertext
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, ErrInvalidCiphertext
+		}
+	}
+	return data[:len(data)-padding], nil
 }
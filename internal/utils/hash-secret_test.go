@@ -5,6 +5,8 @@ import (
 	"crypto/aes"
 	"crypto/rand"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestEncryptDecrypt(t *testing.T) {
@@ -68,7 +70,10 @@ func TestPadUnpad(t *testing.T) {
 	// Test padding and unpadding of empty data
 	data := []byte{}
 	padded := pad(data, aes.BlockSize)
-	unpadded := unpad(padded)
+	unpadded, err := unpad(padded, aes.BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !bytes.Equal(data, unpadded) {
 		t.Errorf("Padding and unpadding of empty data failed")
 	}
@@ -76,7 +81,10 @@ func TestPadUnpad(t *testing.T) {
 	// Test padding and unpadding of data with length equal to block size
 	data = []byte("hello world")
 	padded = pad(data, aes.BlockSize)
-	unpadded = unpad(padded)
+	unpadded, err = unpad(padded, aes.BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !bytes.Equal(data, unpadded) {
 		t.Errorf("Padding and unpadding of data with length equal to block size failed")
 	}
@@ -84,7 +92,10 @@ func TestPadUnpad(t *testing.T) {
 	// Test padding and unpadding of data with length less than block size
 	data = []byte("hello")
 	padded = pad(data, aes.BlockSize)
-	unpadded = unpad(padded)
+	unpadded, err = unpad(padded, aes.BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !bytes.Equal(data, unpadded) {
 		t.Errorf("Padding and unpadding of data with length less than block size failed")
 	}
@@ -95,8 +106,45 @@ func TestPadUnpad(t *testing.T) {
 		t.Fatal(err)
 	}
 	padded = pad(data, aes.BlockSize)
-	unpadded = unpad(padded)
+	unpadded, err = unpad(padded, aes.BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !bytes.Equal(data, unpadded) {
 		t.Errorf("Padding and unpadding of data with length greater than block size failed")
 	}
 }
+
+func TestUnpadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"zero padding", []byte{1, 2, 3, 0}},
+		{"padding longer than block", append(make([]byte, 31), 17)},
+		{"padding longer than data", []byte{1, 5}},
+		{"inconsistent padding", []byte{1, 2, 3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := unpad(tt.data, aes.BlockSize); err != ErrInvalidCiphertext {
+				t.Errorf("unpad() error = %v, want %v", err, ErrInvalidCiphertext)
+			}
+		})
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := Decrypt([]byte{}, key, iv); err != ErrInvalidCiphertext {
+		t.Errorf("Decrypt(empty) error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+	if _, err := Decrypt([]byte("short"), key, iv); err != ErrInvalidCiphertext {
+		t.Errorf("Decrypt(unaligned) error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+	if _, err := DecryptLegacyValue([]byte{}, uuid.NewString()); err != ErrInvalidCiphertext {
+		t.Errorf("DecryptLegacyValue(empty) error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/gophkeeperclient/service/authorization.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+	models "yudinsv/gophkeeper/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAuthorizationer is a mock of Authorizationer interface.
+type MockAuthorizationer struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorizationerMockRecorder
+}
+
+// MockAuthorizationerMockRecorder is the mock recorder for MockAuthorizationer.
+type MockAuthorizationerMockRecorder struct {
+	mock *MockAuthorizationer
+}
+
+// NewMockAuthorizationer creates a new mock instance.
+func NewMockAuthorizationer(ctrl *gomock.Controller) *MockAuthorizationer {
+	mock := &MockAuthorizationer{ctrl: ctrl}
+	mock.recorder = &MockAuthorizationerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorizationer) EXPECT() *MockAuthorizationerMockRecorder {
+	return m.recorder
+}
+
+// Authorization mocks base method.
+func (m *MockAuthorizationer) Authorization(user models.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorization", user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Authorization indicates an expected call of Authorization.
+func (mr *MockAuthorizationerMockRecorder) Authorization(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorization", reflect.TypeOf((*MockAuthorizationer)(nil).Authorization), user)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockAuthorizationer) ConfirmTOTP(code string) (models.RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", code)
+	ret0, _ := ret[0].(models.RecoveryCodes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockAuthorizationerMockRecorder) ConfirmTOTP(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockAuthorizationer)(nil).ConfirmTOTP), code)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockAuthorizationer) EnrollTOTP() (models.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP")
+	ret0, _ := ret[0].(models.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockAuthorizationerMockRecorder) EnrollTOTP() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockAuthorizationer)(nil).EnrollTOTP))
+}
+
+// Ping mocks base method.
+func (m *MockAuthorizationer) Ping() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockAuthorizationerMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAuthorizationer)(nil).Ping))
+}
+
+// VerifyTOTP mocks base method.
+func (m *MockAuthorizationer) VerifyTOTP(code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTOTP", code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyTOTP indicates an expected call of VerifyTOTP.
+func (mr *MockAuthorizationerMockRecorder) VerifyTOTP(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTOTP", reflect.TypeOf((*MockAuthorizationer)(nil).VerifyTOTP), code)
+}
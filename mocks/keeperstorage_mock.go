@@ -36,6 +36,22 @@ func (m *MockKeeperStorage) EXPECT() *MockKeeperStorageMockRecorder {
 	return m.recorder
 }
 
+// Changes mocks base method.
+func (m *MockKeeperStorage) Changes(ctx context.Context, userID string, since int64, limit int) ([]models.LiteSecret, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", ctx, userID, since, limit)
+	ret0, _ := ret[0].([]models.LiteSecret)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockKeeperStorageMockRecorder) Changes(ctx, userID, since, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockKeeperStorage)(nil).Changes), ctx, userID, since, limit)
+}
+
 // Close mocks base method.
 func (m *MockKeeperStorage) Close() error {
 	m.ctrl.T.Helper()
@@ -50,33 +66,93 @@ func (mr *MockKeeperStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockKeeperStorage)(nil).Close))
 }
 
+// CompareAndDeleteSecret mocks base method.
+func (m *MockKeeperStorage) CompareAndDeleteSecret(ctx context.Context, userID string, secretID uuid.UUID, baseRevision int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndDeleteSecret", ctx, userID, secretID, baseRevision)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndDeleteSecret indicates an expected call of CompareAndDeleteSecret.
+func (mr *MockKeeperStorageMockRecorder) CompareAndDeleteSecret(ctx, userID, secretID, baseRevision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndDeleteSecret", reflect.TypeOf((*MockKeeperStorage)(nil).CompareAndDeleteSecret), ctx, userID, secretID, baseRevision)
+}
+
+// CompareAndPutSecret mocks base method.
+func (m *MockKeeperStorage) CompareAndPutSecret(ctx context.Context, secret models.Secret, baseRevision int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndPutSecret", ctx, secret, baseRevision)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndPutSecret indicates an expected call of CompareAndPutSecret.
+func (mr *MockKeeperStorageMockRecorder) CompareAndPutSecret(ctx, secret, baseRevision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndPutSecret", reflect.TypeOf((*MockKeeperStorage)(nil).CompareAndPutSecret), ctx, secret, baseRevision)
+}
+
 // DeleteSecret mocks base method.
-func (m *MockKeeperStorage) DeleteSecret(ctx context.Context, secretID uuid.UUID) error {
+func (m *MockKeeperStorage) DeleteSecret(ctx context.Context, userID string, secretID uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSecret", ctx, secretID)
+	ret := m.ctrl.Call(m, "DeleteSecret", ctx, userID, secretID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteSecret indicates an expected call of DeleteSecret.
-func (mr *MockKeeperStorageMockRecorder) DeleteSecret(ctx, secretID interface{}) *gomock.Call {
+func (mr *MockKeeperStorageMockRecorder) DeleteSecret(ctx, userID, secretID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecret", reflect.TypeOf((*MockKeeperStorage)(nil).DeleteSecret), ctx, secretID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecret", reflect.TypeOf((*MockKeeperStorage)(nil).DeleteSecret), ctx, userID, secretID)
+}
+
+// GetCursor mocks base method.
+func (m *MockKeeperStorage) GetCursor(ctx context.Context, name string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCursor", ctx, name)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCursor indicates an expected call of GetCursor.
+func (mr *MockKeeperStorageMockRecorder) GetCursor(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCursor", reflect.TypeOf((*MockKeeperStorage)(nil).GetCursor), ctx, name)
 }
 
 // GetSecret mocks base method.
-func (m *MockKeeperStorage) GetSecret(ctx context.Context, secretID uuid.UUID) (models.Secret, error) {
+func (m *MockKeeperStorage) GetSecret(ctx context.Context, userID string, secretID uuid.UUID) (models.Secret, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSecret", ctx, secretID)
+	ret := m.ctrl.Call(m, "GetSecret", ctx, userID, secretID)
 	ret0, _ := ret[0].(models.Secret)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSecret indicates an expected call of GetSecret.
-func (mr *MockKeeperStorageMockRecorder) GetSecret(ctx, secretID interface{}) *gomock.Call {
+func (mr *MockKeeperStorageMockRecorder) GetSecret(ctx, userID, secretID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockKeeperStorage)(nil).GetSecret), ctx, secretID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockKeeperStorage)(nil).GetSecret), ctx, userID, secretID)
+}
+
+// GetVersions mocks base method.
+func (m *MockKeeperStorage) GetVersions(ctx context.Context, userID string, secretID uuid.UUID) ([]models.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersions", ctx, userID, secretID)
+	ret0, _ := ret[0].([]models.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVersions indicates an expected call of GetVersions.
+func (mr *MockKeeperStorageMockRecorder) GetVersions(ctx, userID, secretID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersions", reflect.TypeOf((*MockKeeperStorage)(nil).GetVersions), ctx, userID, secretID)
 }
 
 // Ping mocks base method.
@@ -93,6 +169,20 @@ func (mr *MockKeeperStorageMockRecorder) Ping() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockKeeperStorage)(nil).Ping))
 }
 
+// PutCursor mocks base method.
+func (m *MockKeeperStorage) PutCursor(ctx context.Context, name string, cursor int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutCursor", ctx, name, cursor)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutCursor indicates an expected call of PutCursor.
+func (mr *MockKeeperStorageMockRecorder) PutCursor(ctx, name, cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutCursor", reflect.TypeOf((*MockKeeperStorage)(nil).PutCursor), ctx, name, cursor)
+}
+
 // PutSecret mocks base method.
 func (m *MockKeeperStorage) PutSecret(ctx context.Context, secret models.Secret) error {
 	m.ctrl.T.Helper()
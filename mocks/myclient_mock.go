@@ -8,6 +8,7 @@ import (
 	io "io"
 	http "net/http"
 	reflect "reflect"
+	models "yudinsv/gophkeeper/internal/models"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,36 @@ func (m *MockClienter) EXPECT() *MockClienterMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockClienter) Delete(url, contentType string, body io.Reader) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", url, contentType, body)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockClienterMockRecorder) Delete(url, contentType, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockClienter)(nil).Delete), url, contentType, body)
+}
+
+// DeleteWithHeader mocks base method.
+func (m *MockClienter) DeleteWithHeader(url string, header http.Header, body io.Reader) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWithHeader", url, header, body)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWithHeader indicates an expected call of DeleteWithHeader.
+func (mr *MockClienterMockRecorder) DeleteWithHeader(url, header, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWithHeader", reflect.TypeOf((*MockClienter)(nil).DeleteWithHeader), url, header, body)
+}
+
 // Get mocks base method.
 func (m *MockClienter) Get(url string) (*http.Response, error) {
 	m.ctrl.T.Helper()
@@ -79,3 +110,44 @@ func (mr *MockClienterMockRecorder) Put(url, contentType, body interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockClienter)(nil).Put), url, contentType, body)
 }
+
+// PutWithHeader mocks base method.
+func (m *MockClienter) PutWithHeader(url string, header http.Header, body io.Reader) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWithHeader", url, header, body)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutWithHeader indicates an expected call of PutWithHeader.
+func (mr *MockClienterMockRecorder) PutWithHeader(url, header, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithHeader", reflect.TypeOf((*MockClienter)(nil).PutWithHeader), url, header, body)
+}
+
+// SetTokens mocks base method.
+func (m *MockClienter) SetTokens(tokens models.Tokens) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTokens", tokens)
+}
+
+// SetTokens indicates an expected call of SetTokens.
+func (mr *MockClienterMockRecorder) SetTokens(tokens interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTokens", reflect.TypeOf((*MockClienter)(nil).SetTokens), tokens)
+}
+
+// Tokens mocks base method.
+func (m *MockClienter) Tokens() models.Tokens {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tokens")
+	ret0, _ := ret[0].(models.Tokens)
+	return ret0
+}
+
+// Tokens indicates an expected call of Tokens.
+func (mr *MockClienterMockRecorder) Tokens() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tokens", reflect.TypeOf((*MockClienter)(nil).Tokens))
+}
@@ -0,0 +1,92 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/gophkeeperclient/service/sessions.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+	models "yudinsv/gophkeeper/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSessioner is a mock of Sessioner interface.
+type MockSessioner struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionerMockRecorder
+}
+
+// MockSessionerMockRecorder is the mock recorder for MockSessioner.
+type MockSessionerMockRecorder struct {
+	mock *MockSessioner
+}
+
+// NewMockSessioner creates a new mock instance.
+func NewMockSessioner(ctrl *gomock.Controller) *MockSessioner {
+	mock := &MockSessioner{ctrl: ctrl}
+	mock.recorder = &MockSessionerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessioner) EXPECT() *MockSessionerMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockSessioner) List() ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSessionerMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSessioner)(nil).List))
+}
+
+// Logout mocks base method.
+func (m *MockSessioner) Logout() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockSessionerMockRecorder) Logout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockSessioner)(nil).Logout))
+}
+
+// Revoke mocks base method.
+func (m *MockSessioner) Revoke(sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockSessionerMockRecorder) Revoke(sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockSessioner)(nil).Revoke), sessionID)
+}
+
+// RevokeOthers mocks base method.
+func (m *MockSessioner) RevokeOthers() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOthers")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOthers indicates an expected call of RevokeOthers.
+func (mr *MockSessionerMockRecorder) RevokeOthers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOthers", reflect.TypeOf((*MockSessioner)(nil).RevokeOthers))
+}
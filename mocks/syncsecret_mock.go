@@ -7,6 +7,7 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	models "yudinsv/gophkeeper/internal/models"
 
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
@@ -35,6 +36,35 @@ func (m *MockSyncer) EXPECT() *MockSyncerMockRecorder {
 	return m.recorder
 }
 
+// ChangesService mocks base method.
+func (m *MockSyncer) ChangesService(cursor int64) (models.Changes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangesService", cursor)
+	ret0, _ := ret[0].(models.Changes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangesService indicates an expected call of ChangesService.
+func (mr *MockSyncerMockRecorder) ChangesService(cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangesService", reflect.TypeOf((*MockSyncer)(nil).ChangesService), cursor)
+}
+
+// DeleteService mocks base method.
+func (m *MockSyncer) DeleteService(ctx context.Context, secretID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteService", ctx, secretID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteService indicates an expected call of DeleteService.
+func (mr *MockSyncerMockRecorder) DeleteService(ctx, secretID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteService", reflect.TypeOf((*MockSyncer)(nil).DeleteService), ctx, secretID)
+}
+
 // GetService mocks base method.
 func (m *MockSyncer) GetService(ctx context.Context, secretID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -77,6 +107,32 @@ func (mr *MockSyncerMockRecorder) PutService(ctx, secretID interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutService", reflect.TypeOf((*MockSyncer)(nil).PutService), ctx, secretID)
 }
 
+// RestoreService mocks base method.
+func (m *MockSyncer) RestoreService(ctx context.Context, secretID uuid.UUID, revision int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreService", ctx, secretID, revision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreService indicates an expected call of RestoreService.
+func (mr *MockSyncerMockRecorder) RestoreService(ctx, secretID, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreService", reflect.TypeOf((*MockSyncer)(nil).RestoreService), ctx, secretID, revision)
+}
+
+// SetClientID mocks base method.
+func (m *MockSyncer) SetClientID(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetClientID", arg0)
+}
+
+// SetClientID indicates an expected call of SetClientID.
+func (mr *MockSyncerMockRecorder) SetClientID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetClientID", reflect.TypeOf((*MockSyncer)(nil).SetClientID), arg0)
+}
+
 // StartSync mocks base method.
 func (m *MockSyncer) StartSync(arg0 string) {
 	m.ctrl.T.Helper()
@@ -102,3 +158,18 @@ func (mr *MockSyncerMockRecorder) Sync() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockSyncer)(nil).Sync))
 }
+
+// VersionsService mocks base method.
+func (m *MockSyncer) VersionsService(secretID uuid.UUID) ([]models.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VersionsService", secretID)
+	ret0, _ := ret[0].([]models.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VersionsService indicates an expected call of VersionsService.
+func (mr *MockSyncerMockRecorder) VersionsService(secretID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VersionsService", reflect.TypeOf((*MockSyncer)(nil).VersionsService), secretID)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/gophkeeperclient/service/vault.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+	models "yudinsv/gophkeeper/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockVaulter is a mock of Vaulter interface.
+type MockVaulter struct {
+	ctrl     *gomock.Controller
+	recorder *MockVaulterMockRecorder
+}
+
+// MockVaulterMockRecorder is the mock recorder for MockVaulter.
+type MockVaulterMockRecorder struct {
+	mock *MockVaulter
+}
+
+// NewMockVaulter creates a new mock instance.
+func NewMockVaulter(ctrl *gomock.Controller) *MockVaulter {
+	mock := &MockVaulter{ctrl: ctrl}
+	mock.recorder = &MockVaulterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVaulter) EXPECT() *MockVaulterMockRecorder {
+	return m.recorder
+}
+
+// GetKDFParams mocks base method.
+func (m *MockVaulter) GetKDFParams() (models.KDFParams, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKDFParams")
+	ret0, _ := ret[0].(models.KDFParams)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKDFParams indicates an expected call of GetKDFParams.
+func (mr *MockVaulterMockRecorder) GetKDFParams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKDFParams", reflect.TypeOf((*MockVaulter)(nil).GetKDFParams))
+}
+
+// PutKDFParams mocks base method.
+func (m *MockVaulter) PutKDFParams(params models.KDFParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutKDFParams", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutKDFParams indicates an expected call of PutKDFParams.
+func (mr *MockVaulterMockRecorder) PutKDFParams(params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutKDFParams", reflect.TypeOf((*MockVaulter)(nil).PutKDFParams), params)
+}